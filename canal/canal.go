@@ -17,20 +17,25 @@ package canal
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/rueidis"
 	"github.com/trim21/errgo"
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/bangumi/server/config"
 	"github.com/bangumi/server/dal"
 	"github.com/bangumi/server/internal/character"
+	"github.com/bangumi/server/internal/index"
 	"github.com/bangumi/server/internal/person"
 	"github.com/bangumi/server/internal/pkg/cache"
 	"github.com/bangumi/server/internal/pkg/driver"
@@ -38,7 +43,8 @@ import (
 	"github.com/bangumi/server/internal/pkg/sys"
 	"github.com/bangumi/server/internal/search"
 	"github.com/bangumi/server/internal/subject"
-	"github.com/bangumi/server/internal/tag"
+	"github.com/bangumi/server/internal/user"
+	"github.com/bangumi/server/internal/webhook"
 	"github.com/bangumi/server/web/session"
 )
 
@@ -46,20 +52,9 @@ const groupID = "go-canal"
 
 var errNoTopic = fmt.Errorf("missing search events topic")
 
-// nolint: funlen
-func Main() error {
-	cfg, err := config.AppConfigReader(config.AppTypeCanal)()
-	if err != nil {
-		return errgo.Trace(err)
-	}
-
-	if len(cfg.Kafka.Topics) == 0 {
-		return errNoTopic
-	}
-
-	var h *eventHandler
-	di := fx.New(
-		fx.NopLogger,
+// modules 是 canal 处理消息需要的依赖，不包含 [Stream].
+func modules(cfg config.AppConfig) fx.Option {
+	return fx.Options(
 		dal.Module,
 
 		fx.Provide(func() config.AppConfig { return cfg }),
@@ -69,27 +64,107 @@ func Main() error {
 			driver.NewMysqlDriver,
 			driver.NewRueidisClient, logger.Copy, cache.NewRedisCache,
 			subject.NewMysqlRepo, character.NewMysqlRepo, person.NewMysqlRepo,
+			index.NewMysqlRepo, user.NewMysqlRepo,
 			search.New, session.NewMysqlRepo, session.New,
-			driver.NewS3,
-			tag.NewCachedRepo,
-			tag.NewMysqlRepo,
+			driver.NewS3, newImagePurger,
+
+			webhook.NewMysqlRepo, webhook.NewDispatcher,
+			func(d *webhook.Dispatcher) eventPublisher { return d },
 
 			newEventHandler,
 		),
 
-		fx.Provide(newKafkaStream),
+		// table handlers, see [Registry]
+		fx.Provide(
+			newSearchHandler,
+			asHandlers(newSubjectHandlers), asHandlers(newCharacterHandlers), asHandlers(newPersonHandlers),
+			asHandlers(newIndexHandlers), asHandlers(newCastHandlers), asHandlers(newUserHandlers),
+			asHandlers(newCacheHandlers), asHandlers(newWebhookHandlers), asHandlers(newImageHandlers),
+			asHandlers(newCollectionHandlers), asHandlers(newRedirectHandlers),
+			newRegistry,
+		),
+	)
+}
+
+// newStream 根据配置选择 kafka 或者 redis stream.
+func newStream(cfg config.AppConfig, redis rueidis.Client, log *zap.Logger) (Stream, error) {
+	switch cfg.Canal.Backend {
+	case "":
+		if len(cfg.Kafka.Topics) == 0 {
+			return nil, errNoTopic
+		}
+
+		return newKafkaStream(cfg), nil
+	case config.CanalBackendRedis:
+		if len(cfg.Canal.RedisStreams) == 0 {
+			return nil, errNoTopic
+		}
+
+		return newRedisStream(cfg, redis, log), nil
+	}
+
+	return nil, fmt.Errorf("unknown canal backend %q", cfg.Canal.Backend)
+}
+
+// Replay 使用 [NewFileStream] 处理 r 中的消息，用于在本地重现线上的问题，处理完所有消息后返回.
+// 不使用 [config.AppTypeCanal]，search 不会修改索引设置或者重建索引.
+func Replay(r io.Reader) error {
+	cfg, err := config.NewAppConfig()
+	if err != nil {
+		return errgo.Trace(err)
+	}
+
+	var h *eventHandler
+	var images *imagePurger
+	err = fx.New(
+		fx.NopLogger,
+		modules(cfg),
+		fx.Provide(func(log *zap.Logger) Stream { return NewFileStream(r, log) }),
+		// 重放的消息不应该再次发送 webhook
+		fx.Decorate(func(eventPublisher) eventPublisher { return noopPublisher{} }),
+		// 也不删除 s3 中缩放后的图片
+		fx.Decorate(func(*s3.Client) *s3.Client { return nil }),
+		// 不执行修改收藏数量这样会修改业务数据的 handler
+		fx.Decorate(func(r *Registry) *Registry { return r.withoutNoReplay() }),
+		fx.Populate(&h, &images),
+	).Err()
+	if err != nil {
+		return errgo.Wrap(err, "fx")
+	}
+
+	defer images.Close()
+	defer h.Close()
+
+	return h.start()
+}
 
-		fx.Populate(&h),
+func Main() error {
+	cfg, err := config.AppConfigReader(config.AppTypeCanal)()
+	if err != nil {
+		return errgo.Trace(err)
+	}
+
+	var h *eventHandler
+	var hc *health
+	var dispatcher *webhook.Dispatcher
+	var images *imagePurger
+	di := fx.New(
+		fx.NopLogger,
+		modules(cfg),
+		fx.Provide(newStream, newHealth),
+		fx.Populate(&h, &hc, &dispatcher, &images),
 	)
 
 	if err := di.Err(); err != nil {
 		return errgo.Wrap(err, "fx")
 	}
 
-	// metrics http reporter
+	// metrics and health check
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
-	srv := &http.Server{Addr: h.config.ListenAddr(), Handler: mux, ReadHeaderTimeout: time.Second}
+	mux.HandleFunc("/healthz", hc.healthz)
+	mux.HandleFunc("/readyz", hc.readyz)
+	srv := &http.Server{Addr: cfg.ListenAddr(), Handler: mux, ReadHeaderTimeout: time.Second}
 
 	var wg errgroup.Group
 
@@ -98,11 +173,19 @@ func Main() error {
 		return errgo.Wrap(srv.ListenAndServe(), "http")
 	})
 
+	// 先停止读取消息，再等待清除图片的队列
+	defer images.Close()
 	defer h.Close()
 	wg.Go(func() error {
 		return errgo.Wrap(h.start(), "start")
 	})
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wg.Go(func() error {
+		return errgo.Wrap(dispatcher.Run(ctx), "webhook")
+	})
+
 	wg.Go(func() error {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/trim21/errgo"
+	"gorm.io/gorm"
+
+	"github.com/bangumi/server/internal/collections/domain/collection"
+	"github.com/bangumi/server/internal/model"
+)
+
+const maxRate = 10
+
+// chii_subjects 中每种收藏类型对应的字段，下标是收藏类型.
+//
+//nolint:gochecknoglobals
+var collectionTypeColumns = [...]string{
+	collection.SubjectCollectionWish:    "subject_wish",
+	collection.SubjectCollectionDone:    "subject_collect",
+	collection.SubjectCollectionDoing:   "subject_doing",
+	collection.SubjectCollectionOnHold:  "subject_on_hold",
+	collection.SubjectCollectionDropped: "subject_dropped",
+}
+
+// subjectCounts 是一个条目的收藏数量和 chii_subject_fields 中的评分分布.
+// 和 web 中原来的统计方式一样，收藏数量包括私密收藏，评分只统计公开的收藏.
+type subjectCounts struct {
+	types [len(collectionTypeColumns)]int64 // 下标是收藏类型
+	rates [maxRate + 1]int64                // 下标是评分
+}
+
+type interestPayload struct {
+	SubjectID model.SubjectID `json:"interest_subject_id"`
+	Type      uint8           `json:"interest_type"`
+	Rate      uint8           `json:"interest_rate"`
+	Private   uint8           `json:"interest_private"`
+}
+
+// interestCounts 返回一条收藏记录对条目计数的贡献.
+func interestCounts(p interestPayload) subjectCounts {
+	var c subjectCounts
+	if collection.SubjectCollection(p.Type).IsValid() {
+		c.types[p.Type]++
+	}
+
+	if p.Rate > 0 && p.Rate <= maxRate && collection.CollectPrivacy(p.Private) == collection.CollectPrivacyNone {
+		c.rates[p.Rate]++
+	}
+
+	return c
+}
+
+func (c subjectCounts) isZero() bool {
+	return c == subjectCounts{}
+}
+
+// recountSubjectCounts 在同一条 update 语句中重新统计并写入条目的计数，重复执行的结果相同.
+func recountSubjectCounts(ctx context.Context, db *gorm.DB, id model.SubjectID) error {
+	var sets []string
+	var args []any
+	for t, column := range collectionTypeColumns {
+		if column == "" {
+			continue
+		}
+
+		sets = append(sets, "s."+column+` = (select count(*) from chii_subject_interests
+			where interest_subject_id = ? and interest_type = ?)`)
+		args = append(args, id, t)
+	}
+
+	for rate := 1; rate <= maxRate; rate++ {
+		sets = append(sets, fmt.Sprintf("f.field_rate_%d", rate)+` = (select count(*) from chii_subject_interests
+			where interest_subject_id = ? and interest_private = 0 and interest_rate = ?)`)
+		args = append(args, id, rate)
+	}
+
+	// 没有 chii_subject_fields 的条目只更新收藏数量
+	err := db.WithContext(ctx).Exec(`update chii_subjects s left join chii_subject_fields f on f.field_sid = s.subject_id
+		set `+strings.Join(sets, ", ")+` where s.subject_id = ?`, append(args, id)...).Error
+
+	return errgo.Wrap(err, "failed to recount subject")
+}
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/trim21/errgo"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/bangumi/server/config"
+	"github.com/bangumi/server/dal"
+	"github.com/bangumi/server/internal/character"
+	"github.com/bangumi/server/internal/index"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/person"
+	"github.com/bangumi/server/internal/pkg/cache"
+	"github.com/bangumi/server/internal/pkg/driver"
+	"github.com/bangumi/server/internal/pkg/logger"
+	"github.com/bangumi/server/internal/search"
+	"github.com/bangumi/server/internal/subject"
+	"github.com/bangumi/server/internal/user"
+)
+
+type ReconcileOptions struct {
+	FromID    model.SubjectID
+	BatchSize int
+	DryRun    bool
+}
+
+// ReconcileCollections 重新统计所有条目的收藏数量和评分分布，修正不一致的计数，返回修正的条目数量.
+// 修正后的条目会删除缓存并更新搜索索引.
+//
+// 只需要数据库、缓存和搜索，不使用 [config.AppTypeCanal]，search 不会修改索引设置或者重建索引.
+func ReconcileCollections(ctx context.Context, opt ReconcileOptions) (int, error) {
+	var r *collectionReconciler
+	err := fx.New(
+		fx.NopLogger,
+		dal.Module,
+
+		fx.Provide(
+			config.NewAppConfig, logger.Copy,
+			driver.NewMysqlDriver, driver.NewRueidisClient, cache.NewRedisCache,
+
+			subject.NewMysqlRepo, character.NewMysqlRepo, person.NewMysqlRepo,
+			index.NewMysqlRepo, user.NewMysqlRepo,
+			search.New,
+
+			newCollectionReconciler,
+		),
+
+		fx.Populate(&r),
+	).Err()
+	if err != nil {
+		return 0, errgo.Wrap(err, "fx")
+	}
+
+	return r.run(ctx, opt)
+}
+
+type collectionReconciler struct {
+	db     *gorm.DB
+	cache  cache.RedisCache
+	search search.Client
+	log    *zap.Logger
+}
+
+func newCollectionReconciler(
+	db *gorm.DB, c cache.RedisCache, search search.Client, log *zap.Logger,
+) *collectionReconciler {
+	return &collectionReconciler{db: db, cache: c, search: search, log: log.Named("canal.collection.reconcile")}
+}
+
+func (r *collectionReconciler) run(ctx context.Context, opt ReconcileOptions) (int, error) {
+	var fixed int
+	opt.BatchSize = max(opt.BatchSize, 1)
+	for from := opt.FromID; ; {
+		ids, current, err := r.current(ctx, from, opt.BatchSize)
+		if err != nil {
+			return fixed, err
+		}
+
+		if len(ids) == 0 {
+			return fixed, nil
+		}
+
+		actual, err := r.count(ctx, ids[0], ids[len(ids)-1])
+		if err != nil {
+			return fixed, err
+		}
+
+		var changed []model.SubjectID
+		for _, id := range ids {
+			if current[id] == actual[id] {
+				continue
+			}
+
+			r.log.Info("fix subject collection counts", zap.Uint32("subject_id", id),
+				zap.Any("before", current[id].types), zap.Any("after", actual[id].types))
+			changed = append(changed, id)
+
+			if opt.DryRun {
+				continue
+			}
+
+			// 批量统计只用于找出需要修正的条目，写入时在同一条语句中重新统计
+			if err := recountSubjectCounts(ctx, r.db, id); err != nil {
+				return fixed, err
+			}
+		}
+
+		fixed += len(changed)
+
+		if !opt.DryRun && len(changed) != 0 {
+			if err := r.refresh(ctx, changed); err != nil {
+				return fixed, err
+			}
+		}
+
+		from = ids[len(ids)-1] + 1
+	}
+}
+
+func (r *collectionReconciler) refresh(ctx context.Context, ids []model.SubjectID) error {
+	if err := subject.InvalidateCache(ctx, r.cache, ids...); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := r.search.EventUpdate(ctx, id, search.SearchTargetSubject); err != nil {
+			return errgo.Wrap(err, "search.EventUpdate")
+		}
+	}
+
+	return nil
+}
+
+// current 返回从 from 开始的 limit 个条目现在的计数.
+func (r *collectionReconciler) current(
+	ctx context.Context, from model.SubjectID, limit int,
+) ([]model.SubjectID, map[model.SubjectID]subjectCounts, error) {
+	var columns []string
+	for _, column := range collectionTypeColumns[1:] {
+		columns = append(columns, "s."+column)
+	}
+	for rate := 1; rate <= maxRate; rate++ {
+		columns = append(columns, fmt.Sprintf("coalesce(f.field_rate_%d, 0)", rate))
+	}
+
+	rows, err := r.db.WithContext(ctx).Raw(`select s.subject_id, `+strings.Join(columns, ", ")+`
+		from chii_subjects s left join chii_subject_fields f on f.field_sid = s.subject_id
+		where s.subject_id >= ? order by s.subject_id limit ?`, from, limit).Rows()
+	if err != nil {
+		return nil, nil, errgo.Wrap(err, "failed to query subject counts")
+	}
+	defer rows.Close()
+
+	var ids []model.SubjectID
+	counts := make(map[model.SubjectID]subjectCounts, limit)
+	for rows.Next() {
+		var id model.SubjectID
+		var c subjectCounts
+
+		dest := []any{&id}
+		for i := 1; i < len(c.types); i++ {
+			dest = append(dest, &c.types[i])
+		}
+		for i := 1; i <= maxRate; i++ {
+			dest = append(dest, &c.rates[i])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, errgo.Wrap(err, "rows.Scan")
+		}
+
+		ids = append(ids, id)
+		counts[id] = c
+	}
+
+	return ids, counts, errgo.Wrap(rows.Err(), "rows.Err")
+}
+
+// count 从 chii_subject_interests 重新统计 [from, to] 中所有条目的计数.
+func (r *collectionReconciler) count(
+	ctx context.Context, from, to model.SubjectID,
+) (map[model.SubjectID]subjectCounts, error) {
+	var rows []struct {
+		SubjectID model.SubjectID `gorm:"column:subject_id"`
+		Value     uint8           `gorm:"column:value"`
+		Total     int64           `gorm:"column:total"`
+	}
+
+	counts := make(map[model.SubjectID]subjectCounts)
+
+	err := r.db.WithContext(ctx).Raw(`
+		select interest_subject_id as subject_id, interest_type as value, count(*) as total
+		from chii_subject_interests
+		where interest_subject_id between ? and ? and interest_type between 1 and ?
+		group by interest_subject_id, interest_type`, from, to, len(collectionTypeColumns)-1).Scan(&rows).Error
+	if err != nil {
+		return nil, errgo.Wrap(err, "failed to count collections")
+	}
+
+	for _, row := range rows {
+		c := counts[row.SubjectID]
+		c.types[row.Value] = row.Total
+		counts[row.SubjectID] = c
+	}
+
+	rows = nil
+	err = r.db.WithContext(ctx).Raw(`
+		select interest_subject_id as subject_id, interest_rate as value, count(*) as total
+		from chii_subject_interests
+		where interest_subject_id between ? and ? and interest_private = 0 and interest_rate between 1 and ?
+		group by interest_subject_id, interest_rate`, from, to, maxRate).Scan(&rows).Error
+	if err != nil {
+		return nil, errgo.Wrap(err, "failed to count rates")
+	}
+
+	for _, row := range rows {
+		c := counts[row.SubjectID]
+		c.rates[row.Value] = row.Total
+		counts[row.SubjectID] = c
+	}
+
+	return counts, nil
+}
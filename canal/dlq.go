@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/config"
+)
+
+// DLQ 消息的 key 和 value 与原消息相同，错误信息保存在 header 中.
+const (
+	headerTopic     = "canal-topic"
+	headerPartition = "canal-partition"
+	headerOffset    = "canal-offset"
+	headerError     = "canal-error"
+	headerAttempts  = "canal-attempts"
+	headerFailedAt  = "canal-failed-at"
+	// headerHandlers 是失败的 handler，用逗号分隔，重放时只会交给这些 handler，为空时交给所有 handler
+	headerHandlers = "canal-handlers"
+)
+
+const dlqReplayGroupID = groupID + "-dlq-replay"
+
+//nolint:gochecknoglobals
+var dlqMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Subsystem: "chii",
+	Name:      "canal_dlq_messages_total",
+	Help:      "messages published to dead-letter queue after retries are exhausted",
+}, []string{"topic"})
+
+//nolint:gochecknoinits
+func init() {
+	prometheus.MustRegister(dlqMessages)
+}
+
+// messageWriter 是 [kafka.Writer] 中用到的方法.
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+func newDLQMessage(msg kafka.Message, err error, attempts int, now time.Time) kafka.Message {
+	headers := []kafka.Header{
+		{Key: headerTopic, Value: []byte(msg.Topic)},
+		{Key: headerPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		{Key: headerOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		{Key: headerError, Value: []byte(err.Error())},
+		{Key: headerAttempts, Value: []byte(strconv.Itoa(attempts))},
+		{Key: headerFailedAt, Value: []byte(now.UTC().Format(time.RFC3339))},
+	}
+
+	if failed := failedHandlers(err); len(failed) != 0 {
+		headers = append(headers, kafka.Header{Key: headerHandlers, Value: []byte(strings.Join(failed, ","))})
+	}
+
+	return kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers}
+}
+
+// messageHandlers 返回消息需要交给的 handler，为空时交给所有 handler.
+func messageHandlers(msg kafka.Message) []string {
+	if v := messageHeader(msg, headerHandlers); v != "" {
+		return strings.Split(v, ",")
+	}
+
+	return nil
+}
+
+func messageHeader(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+// backoff 返回第 attempt 次失败后等待的时间，从 base 开始翻倍，不超过 limit.
+func backoff(base, limit time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+
+	return min(d, limit)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// ReplayDLQ 把 DLQ 中的消息重新写入原来的 topic，由正常的 canal 进程重新处理.
+// 使用单独的 consumer group，已经重新写入的消息不会被再次写入.
+// 在 idle 时间内没有新消息时认为 DLQ 已经处理完毕.
+func ReplayDLQ(ctx context.Context, cfg config.AppConfig, log *zap.Logger, idle time.Duration) (int, error) {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: []string{cfg.Kafka.Broker},
+		GroupID: dlqReplayGroupID,
+		Topic:   cfg.Kafka.DLQTopic,
+	})
+	defer r.Close()
+
+	w := &kafka.Writer{Addr: kafka.TCP(cfg.Kafka.Broker), Balancer: &kafka.Hash{}}
+	defer w.Close()
+
+	return replayDLQ(ctx, r, w, log, idle)
+}
+
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+func replayDLQ(
+	ctx context.Context,
+	r messageReader,
+	w messageWriter,
+	log *zap.Logger,
+	idle time.Duration,
+) (int, error) {
+	var n int
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, idle)
+		msg, err := r.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return n, nil
+			}
+
+			return n, errgo.Wrap(err, "failed to fetch dlq message")
+		}
+
+		topic := messageHeader(msg, headerTopic)
+		if topic == "" {
+			log.Warn("dlq message without original topic, skip", zap.Int64("offset", msg.Offset))
+		} else {
+			log.Info("replay message", zap.String("topic", topic),
+				zap.String("offset", messageHeader(msg, headerOffset)),
+				zap.String("error", messageHeader(msg, headerError)))
+
+			replay := kafka.Message{Topic: topic, Key: msg.Key, Value: msg.Value}
+			// 只重新执行失败的 handler
+			if handlers := messageHeader(msg, headerHandlers); handlers != "" {
+				replay.Headers = []kafka.Header{{Key: headerHandlers, Value: []byte(handlers)}}
+			}
+
+			if err := w.WriteMessages(ctx, replay); err != nil {
+				return n, errgo.Wrap(err, "failed to write message")
+			}
+
+			n++
+		}
+
+		if err := r.CommitMessages(ctx, msg); err != nil {
+			return n, errgo.Wrap(err, "failed to commit dlq message")
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/pkg/logger"
+)
+
+type memoryWriter struct {
+	msgs []kafka.Message
+}
+
+func (w *memoryWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *memoryWriter) Close() error {
+	return nil
+}
+
+type memoryReader struct {
+	msgs      []kafka.Message
+	committed []kafka.Message
+}
+
+func (r *memoryReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(r.msgs) == 0 {
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+
+	msg := r.msgs[0]
+	r.msgs = r.msgs[1:]
+
+	return msg, nil
+}
+
+func (r *memoryReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, time.Second, backoff(time.Second, time.Minute, 1))
+	require.Equal(t, 4*time.Second, backoff(time.Second, time.Minute, 3))
+	require.Equal(t, time.Minute, backoff(time.Second, time.Minute, 20))
+}
+
+func TestKafkaStream_process(t *testing.T) {
+	t.Parallel()
+
+	w := &memoryWriter{}
+	s := &kafkaStream{log: logger.Copy(), dlq: w, maxAttempts: 3, backoff: time.Millisecond, maxBackoff: time.Millisecond}
+	msg := kafka.Message{Topic: "debezium.bangumi.chii_subjects", Partition: 2, Offset: 10, Key: []byte("k")}
+
+	// 重试后成功
+	var calls int
+	require.NoError(t, s.process(context.Background(), msg, func(Msg) error {
+		calls++
+		if calls < 2 {
+			return errors.New("temporary")
+		}
+		return nil
+	}))
+	require.Equal(t, 2, calls)
+	require.Empty(t, w.msgs)
+
+	// 超过重试次数后写入 DLQ
+	calls = 0
+	require.NoError(t, s.process(context.Background(), msg, func(Msg) error {
+		calls++
+		return errors.New("poison")
+	}))
+	require.Equal(t, 3, calls)
+	require.Len(t, w.msgs, 1)
+
+	dlq := w.msgs[0]
+	require.Equal(t, msg.Key, dlq.Key)
+	require.Equal(t, "debezium.bangumi.chii_subjects", messageHeader(dlq, headerTopic))
+	require.Equal(t, "2", messageHeader(dlq, headerPartition))
+	require.Equal(t, "10", messageHeader(dlq, headerOffset))
+	require.Equal(t, "poison", messageHeader(dlq, headerError))
+	require.Equal(t, "3", messageHeader(dlq, headerAttempts))
+}
+
+func TestKafkaStream_process_failedHandlers(t *testing.T) {
+	t.Parallel()
+
+	w := &memoryWriter{}
+	s := &kafkaStream{log: logger.Copy(), dlq: w, maxAttempts: 2, backoff: time.Millisecond, maxBackoff: time.Millisecond}
+	msg := kafka.Message{Topic: "debezium.bangumi.chii_subjects", Key: []byte("k")}
+
+	var handlers [][]string
+	require.NoError(t, s.process(context.Background(), msg, func(m Msg) error {
+		handlers = append(handlers, m.Handlers)
+		return &HandlerError{err: errors.New("fail"), Failed: []string{"search"}}
+	}))
+
+	// 第一次交给所有 handler，重试时只交给失败的 handler
+	require.Equal(t, [][]string{nil, {"search"}}, handlers)
+	require.Len(t, w.msgs, 1)
+	require.Equal(t, "search", messageHeader(w.msgs[0], headerHandlers))
+
+	// 从 DLQ 重放的消息只交给失败的 handler
+	handlers = nil
+	require.NoError(t, s.process(context.Background(), kafka.Message{
+		Headers: []kafka.Header{{Key: headerHandlers, Value: []byte("search,cache")}},
+	}, func(m Msg) error {
+		handlers = append(handlers, m.Handlers)
+		return nil
+	}))
+	require.Equal(t, [][]string{{"search", "cache"}}, handlers)
+}
+
+func TestReplayDLQ(t *testing.T) {
+	t.Parallel()
+
+	failed := newDLQMessage(kafka.Message{Topic: "debezium.bangumi.chii_subjects", Key: []byte("k"), Value: []byte("v")},
+		&HandlerError{err: errors.New("poison"), Failed: []string{"search"}}, 3, time.Now())
+	r := &memoryReader{msgs: []kafka.Message{failed, {Value: []byte("no header")}}}
+	w := &memoryWriter{}
+
+	n, err := replayDLQ(context.Background(), r, w, logger.Copy(), 10*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Len(t, r.committed, 2)
+	require.Equal(t, []kafka.Message{
+		{
+			Topic:   "debezium.bangumi.chii_subjects",
+			Key:     []byte("k"),
+			Value:   []byte("v"),
+			Headers: []kafka.Header{{Key: headerHandlers, Value: []byte("search")}},
+		},
+	}, w.msgs)
+}
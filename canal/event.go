@@ -18,59 +18,53 @@ import (
 	"context"
 	"encoding/json"
 	"sync/atomic"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/redis/rueidis"
 	"github.com/trim21/errgo"
 	"go.uber.org/zap"
 
-	"github.com/bangumi/server/config"
-	"github.com/bangumi/server/internal/model"
-	"github.com/bangumi/server/internal/pkg/logger/log"
 	"github.com/bangumi/server/internal/search"
-	"github.com/bangumi/server/internal/tag"
-	"github.com/bangumi/server/web/session"
 )
 
 func newEventHandler(
 	log *zap.Logger,
-	appConfig config.AppConfig,
-	session session.Manager,
-	redis rueidis.Client,
 	stream Stream,
-	tag tag.Repo,
+	registry *Registry,
 	search search.Client,
-	s3 *s3.Client,
 ) *eventHandler {
 	return &eventHandler{
-		redis:   redis,
-		config:  appConfig,
-		session: session,
-		search:  search,
-		s3:      s3,
-		stream:  stream,
-		log:     log.Named("eventHandler"),
-		tag:     tag,
+		search:   search,
+		stream:   stream,
+		registry: registry,
+		log:      log.Named("eventHandler"),
 	}
 }
 
 type eventHandler struct {
 	closed  atomic.Bool
-	config  config.AppConfig
-	session session.Manager
-	log     *zap.Logger
-	search  search.Client
-	stream  Stream
-	s3      *s3.Client // optional, check nil before use
-	redis   rueidis.Client
-	tag     tag.Repo
+	started atomic.Bool
+	// stopped 在 stream 的读取循环退出后设置
+	stopped atomic.Bool
+	// lastMessage 是最后一次处理完消息的 unix 纳秒时间，没有处理过消息时是开始读取的时间
+	lastMessage atomic.Int64
+
+	log      *zap.Logger
+	search   search.Client
+	stream   Stream
+	registry *Registry
 }
 
 func (e *eventHandler) start() error {
+	e.lastMessage.Store(time.Now().UnixNano())
+	e.started.Store(true)
+	defer e.stopped.Store(true)
+
 	ee := e.stream.Read(context.Background(), func(msg Msg) error {
+		defer func() { e.lastMessage.Store(time.Now().UnixNano()) }()
+
 		e.log.Debug("new message", zap.String("topic", msg.Topic), zap.String("id", msg.ID))
 
-		err := e.onMessage(msg.Key, msg.Value)
+		err := e.onMessage(msg.Key, msg.Value, msg.Handlers...)
 		if err != nil {
 			e.log.Error("failed to handle stream msg",
 				zap.Error(err), zap.String("stream", msg.Topic), zap.String("id", msg.ID))
@@ -83,22 +77,29 @@ func (e *eventHandler) start() error {
 	return errgo.Trace(ee)
 }
 
-func (e *eventHandler) Close() error {
-	e.closed.Store(true)
-	e.search.Close()
-	return nil
-}
+// status 返回 readyz 需要的读取状态.
+func (e *eventHandler) status() streamStatus {
+	s := streamStatus{
+		started:     e.started.Load(),
+		stopped:     e.stopped.Load(),
+		lastMessage: time.Unix(0, e.lastMessage.Load()),
+		lag:         -1,
+	}
 
-func (e *eventHandler) OnUserPasswordChange(ctx context.Context, id model.UserID) error {
-	if err := e.session.RevokeUser(ctx, id); err != nil {
-		e.log.Error("failed to revoke user", log.User(id), zap.Error(err))
-		return errgo.Wrap(err, "session.RevokeUser")
+	if r, ok := e.stream.(lagReporter); ok {
+		s.lag = r.Lag()
 	}
 
+	return s
+}
+
+func (e *eventHandler) Close() error {
+	e.closed.Store(true)
+	e.search.Close()
 	return nil
 }
 
-func (e *eventHandler) onMessage(key, value []byte) error {
+func (e *eventHandler) onMessage(key, value []byte, handlers ...string) error {
 	if len(value) == 0 {
 		// fake event, just ignore
 		// https://debezium.io/documentation/reference/stable/connectors/mysql.html#mysql-tombstone-events
@@ -116,21 +117,15 @@ func (e *eventHandler) onMessage(key, value []byte) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	var err error
-	switch p.Source.Table {
-	case "chii_subject_fields":
-		err = e.OnSubjectField(ctx, key, p)
-	case "chii_subjects":
-		err = e.OnSubject(ctx, key, p)
-	case "chii_characters":
-		err = e.OnCharacter(ctx, key, p)
-	case "chii_persons":
-		err = e.OnPerson(ctx, key, p)
-	case "chii_members":
-		err = e.OnUserChange(ctx, key, p)
+	err := e.registry.Dispatch(ctx, key, p, handlers...)
+	if err != nil {
+		events.WithLabelValues(p.Source.Table, p.Op, "error").Inc()
+		return err
 	}
 
-	return err
+	events.WithLabelValues(p.Source.Table, p.Op, "ok").Inc()
+
+	return nil
 }
 
 const (
@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/redis/rueidis"
+	"github.com/segmentio/kafka-go"
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/config"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// health 检查 canal 依赖的服务，用于 kubernetes 的 liveness 和 readiness probe.
+//
+//   - /healthz 检查 kafka、mysql、redis 和 meilisearch 的连接
+//   - /readyz 在 /healthz 的基础上还要求正在读取消息，并且没有停止处理，见 [streamStatus.check]
+type health struct {
+	checks []healthCheck
+	// status 返回读取消息的状态
+	status       func() streamStatus
+	stallTimeout time.Duration
+}
+
+// streamStatus 是读取消息的状态.
+type streamStatus struct {
+	started bool
+	// stopped 表示读取循环已经退出
+	stopped bool
+	// lastMessage 是最后一次处理完消息的时间
+	lastMessage time.Time
+	// lag 是还没有读取的消息数量，小于 0 表示 stream 不支持
+	lag int64
+}
+
+// check 在读取循环没有运行，或者还有没读取的消息但是超过 stallTimeout 没有处理完任何消息时返回错误.
+func (s streamStatus) check(now time.Time, stallTimeout time.Duration) error {
+	if !s.started {
+		return errors.New("not started")
+	}
+
+	if s.stopped {
+		return errors.New("stopped")
+	}
+
+	if idle := now.Sub(s.lastMessage); s.lag > 0 && idle > stallTimeout {
+		return fmt.Errorf("no message processed in %s with lag %d", idle.Truncate(time.Second), s.lag)
+	}
+
+	return nil
+}
+
+func newHealth(cfg config.AppConfig, db *sql.DB, redis rueidis.Client, e *eventHandler) *health {
+	h := &health{status: e.status, stallTimeout: cfg.Canal.StallTimeout}
+
+	h.add("mysql", func(ctx context.Context) error {
+		return errgo.Wrap(db.PingContext(ctx), "ping")
+	})
+
+	h.add("redis", func(ctx context.Context) error {
+		return errgo.Wrap(redis.Do(ctx, redis.B().Ping().Build()).Error(), "ping")
+	})
+
+	if cfg.Canal.Backend == "" {
+		h.add("kafka", func(ctx context.Context) error {
+			conn, err := kafka.DialContext(ctx, "tcp", cfg.Kafka.Broker)
+			if err != nil {
+				return errgo.Wrap(err, "dial")
+			}
+
+			return conn.Close()
+		})
+	}
+
+	// 使用进程内的索引或者没有配置 meilisearch 时不检查
+	if cfg.Search.Backend != config.SearchBackendEmbedded && cfg.Search.MeiliSearch.URL != "" {
+		h.add("meilisearch", func(ctx context.Context) error {
+			return checkMeiliSearch(ctx, cfg.Search.MeiliSearch.URL)
+		})
+	}
+
+	return h
+}
+
+func (h *health) add(name string, check func(ctx context.Context) error) {
+	h.checks = append(h.checks, healthCheck{name: name, check: check})
+}
+
+// checkMeiliSearch 请求不需要 api key 的 `/health`.
+func checkMeiliSearch(ctx context.Context, base string) error {
+	u, err := url.JoinPath(base, "health")
+	if err != nil {
+		return errgo.Wrap(err, "url")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return errgo.Wrap(err, "request")
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errgo.Wrap(err, "request")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// run 并行执行所有检查，返回每个检查的结果.
+func (h *health) run(ctx context.Context) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	ok := true
+	result := make(map[string]string, len(h.checks))
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			status := "ok"
+			if err := c.check(ctx); err != nil {
+				status = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			result[c.name] = status
+			if status != "ok" {
+				ok = false
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return result, ok
+}
+
+func (h *health) healthz(w http.ResponseWriter, r *http.Request) {
+	result, ok := h.run(r.Context())
+	writeHealth(w, result, ok)
+}
+
+func (h *health) readyz(w http.ResponseWriter, r *http.Request) {
+	result, ok := h.run(r.Context())
+	if err := h.status().check(time.Now(), h.stallTimeout); err != nil {
+		result["stream"] = err.Error()
+		ok = false
+	} else {
+		result["stream"] = "ok"
+	}
+
+	writeHealth(w, result, ok)
+}
+
+func writeHealth(w http.ResponseWriter, result map[string]string, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_ = json.NewEncoder(w).Encode(result)
+}
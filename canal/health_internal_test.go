@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+
+	var status streamStatus
+	h := &health{status: func() streamStatus { return status }, stallTimeout: time.Minute}
+	h.add("mysql", func(context.Context) error { return nil })
+
+	get := func(handler http.HandlerFunc) (int, map[string]string) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+		var result map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return w.Code, result
+	}
+
+	code, result := get(h.healthz)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, map[string]string{"mysql": "ok"}, result)
+
+	// 还没有开始读取消息
+	code, result = get(h.readyz)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "not started", result["stream"])
+
+	status = streamStatus{started: true, lastMessage: time.Now(), lag: -1}
+	code, _ = get(h.readyz)
+	require.Equal(t, http.StatusOK, code)
+
+	status.stopped = true
+	code, result = get(h.readyz)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "stopped", result["stream"])
+	status.stopped = false
+
+	h.add("redis", func(context.Context) error { return errors.New("connection refused") })
+	code, result = get(h.healthz)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "connection refused", result["redis"])
+}
+
+func TestStreamStatus_check(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	status := streamStatus{started: true, lastMessage: now.Add(-2 * time.Minute)}
+
+	// 没有落后的消息时不处理消息是正常的
+	require.NoError(t, status.check(now, time.Minute))
+
+	// 不支持 lag 的 stream 不检查
+	status.lag = -1
+	require.NoError(t, status.check(now, time.Minute))
+
+	status.lag = 10
+	require.EqualError(t, status.check(now, time.Minute), "no message processed in 2m0s with lag 10")
+	require.NoError(t, status.check(now, 5*time.Minute))
+
+	status.lastMessage = now
+	require.NoError(t, status.check(now, time.Minute))
+}
+
+func TestCheckMeiliSearch(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"available"}`))
+	}))
+	defer srv.Close()
+
+	require.NoError(t, checkMeiliSearch(context.Background(), srv.URL))
+	require.Error(t, checkMeiliSearch(context.Background(), srv.URL+"/prefix"))
+}
@@ -23,6 +23,8 @@ type Msg struct {
 	Topic string
 	Key   []byte
 	Value []byte
+	// Handlers 不为空时只交给这些 handler 处理，用于重试之前失败的 handler，见 [HandlerError]
+	Handlers []string
 }
 
 type Stream interface {
@@ -30,3 +32,8 @@ type Stream interface {
 	// Ack(ctx context.Context, msg Msg) error
 	Close() error
 }
+
+// lagReporter 是可以报告还没有读取的消息数量的 [Stream]，用于 readyz 判断是否停止了处理.
+type lagReporter interface {
+	Lag() int64
+}
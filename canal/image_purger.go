@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/config"
+)
+
+const (
+	imagePurgeWorkers   = 4
+	imagePurgeQueueSize = 256
+	imagePurgeTimeout   = 10 * time.Second
+)
+
+type objectStore interface {
+	s3.ListObjectsV2APIClient
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// imagePurger 删除 S3ImageResizeBucket 中缩放后的图片.
+// 固定数量的 worker 从队列中读取前缀，队列满时 purge 会阻塞，s3 变慢时不会堆积 goroutine.
+type imagePurger struct {
+	store  objectStore // nil 表示没有配置 s3
+	bucket string
+	log    *zap.Logger
+	queue  chan string
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newImagePurger(cfg config.AppConfig, client *s3.Client, log *zap.Logger) *imagePurger {
+	var store objectStore
+	if client != nil {
+		store = client
+	}
+
+	return newImagePurgerWithStore(cfg.S3ImageResizeBucket, store, log)
+}
+
+func newImagePurgerWithStore(bucket string, store objectStore, log *zap.Logger) *imagePurger {
+	p := &imagePurger{
+		store:  store,
+		bucket: bucket,
+		log:    log.Named("canal.image"),
+		queue:  make(chan string, imagePurgeQueueSize),
+	}
+
+	for range imagePurgeWorkers {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for prefix := range p.queue {
+				p.purgePrefix(prefix)
+			}
+		}()
+	}
+
+	return p
+}
+
+// purge 把前缀加入队列，空字符串会被忽略.
+func (p *imagePurger) purge(ctx context.Context, prefixes ...string) error {
+	if p.store == nil {
+		return nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil
+	}
+
+	for _, prefix := range lo.Uniq(prefixes) {
+		if prefix == "" {
+			continue
+		}
+
+		select {
+		case p.queue <- prefix:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Close 等待队列中的前缀处理完成.
+func (p *imagePurger) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *imagePurger) purgePrefix(prefix string) {
+	p.log.Debug("clear image for prefix", zap.String("prefix", prefix))
+
+	ctx, cancel := context.WithTimeout(context.Background(), imagePurgeTimeout)
+	defer cancel()
+
+	pages := s3.NewListObjectsV2Paginator(p.store, &s3.ListObjectsV2Input{Bucket: &p.bucket, Prefix: &prefix})
+
+	for pages.HasMorePages() {
+		output, err := pages.NextPage(ctx)
+		if err != nil {
+			p.log.Error("failed to list s3 cached image", zap.String("prefix", prefix), zap.Error(err))
+			return
+		}
+
+		if len(output.Contents) == 0 {
+			return
+		}
+
+		_, err = p.store.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: &p.bucket,
+			Delete: &types.Delete{
+				Objects: lo.Map(output.Contents, func(item types.Object, _ int) types.ObjectIdentifier {
+					return types.ObjectIdentifier{Key: item.Key}
+				}),
+			},
+		})
+		if err != nil {
+			p.log.Error("failed to clear s3 cached image", zap.String("prefix", prefix), zap.Error(err))
+		}
+	}
+}
+
+// avatarPrefix 返回头像缩放后的图片前缀，hd=1 的头像保存在 /hd 下.
+func avatarPrefix(avatar string) string {
+	if avatar == "" {
+		return ""
+	}
+
+	p, q, ok := strings.Cut(avatar, "?")
+	if !ok {
+		p = avatar
+	}
+
+	p = "/pic/user/l/" + p
+
+	if strings.Contains(q, "hd=1") {
+		p = "/hd" + p
+	}
+
+	return p
+}
+
+func coverPrefix(image string) string {
+	if image == "" {
+		return ""
+	}
+
+	return "/pic/cover/l/" + image
+}
+
+// monoPrefix 角色和人物的图片都保存在 /pic/crt 下.
+func monoPrefix(image string) string {
+	if image == "" {
+		return ""
+	}
+
+	return "/pic/crt/l/" + image
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	objects []string
+}
+
+func (m *memoryStore) ListObjectsV2(
+	_ context.Context, params *s3.ListObjectsV2Input, _ ...func(*s3.Options),
+) (*s3.ListObjectsV2Output, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var output s3.ListObjectsV2Output
+	for _, key := range m.objects {
+		if strings.HasPrefix(key, *params.Prefix) {
+			output.Contents = append(output.Contents, types.Object{Key: &key})
+		}
+	}
+
+	return &output, nil
+}
+
+func (m *memoryStore) DeleteObjects(
+	_ context.Context, params *s3.DeleteObjectsInput, _ ...func(*s3.Options),
+) (*s3.DeleteObjectsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, o := range params.Delete.Objects {
+		for i, key := range m.objects {
+			if key == *o.Key {
+				m.objects = append(m.objects[:i], m.objects[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func TestAvatarPrefix(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "/pic/user/l/000/00/00/1.jpg", avatarPrefix("000/00/00/1.jpg?r=1"))
+	require.Equal(t, "/hd/pic/user/l/000/00/00/1.jpg", avatarPrefix("000/00/00/1.jpg?r=1&hd=1"))
+	require.Empty(t, avatarPrefix(""))
+	require.Empty(t, monoPrefix(""))
+}
+
+func TestImageHandlers(t *testing.T) {
+	t.Parallel()
+
+	store := &memoryStore{objects: []string{
+		"/pic/crt/l/a/1.jpg/r/100",
+		"/pic/crt/l/a/1.jpg/r/200",
+		"/pic/crt/l/b/2.jpg/r/100",
+		"/pic/cover/l/c/3.jpg/r/100",
+	}}
+
+	p := newImagePurgerWithStore("img-resize", store, zap.NewNop())
+	handlers := newImageHandlers(p)
+
+	ctx := context.Background()
+	for _, h := range handlers {
+		switch h.Info().Name {
+		case "image.character":
+			require.NoError(t, h.Handle(ctx, json.RawMessage(`{"crt_id":1}`), Payload{
+				Op:     opUpdate,
+				Before: json.RawMessage(`{"crt_img":"a/1.jpg"}`),
+				After:  json.RawMessage(`{"crt_img":"a/4.jpg"}`),
+			}))
+		case "image.subject":
+			// 没有修改封面
+			require.NoError(t, h.Handle(ctx, json.RawMessage(`{"subject_id":3}`), Payload{
+				Op:     opUpdate,
+				Before: json.RawMessage(`{"subject_image":"c/3.jpg","subject_name":"a"}`),
+				After:  json.RawMessage(`{"subject_image":"c/3.jpg","subject_name":"b"}`),
+			}))
+		}
+	}
+
+	p.Close()
+
+	require.Equal(t, []string{"/pic/crt/l/b/2.jpg/r/100", "/pic/cover/l/c/3.jpg/r/100"}, store.objects)
+	require.NoError(t, p.purge(ctx, "/pic/crt/l/b/2.jpg"), "purge after close should be ignored")
+}
+
+func TestImagePurger_noS3(t *testing.T) {
+	t.Parallel()
+
+	p := newImagePurgerWithStore("img-resize", nil, zap.NewNop())
+	defer p.Close()
+
+	require.NoError(t, p.purge(context.Background(), monoPrefix("a/1.jpg")))
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+)
+
+//nolint:gochecknoglobals
+var (
+	events = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "chii",
+		Name:      "canal_events_total",
+		Help:      "binlog events processed by canal, result is ok or error",
+	}, []string{"table", "op", "result"})
+
+	handlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "chii",
+		Name:      "canal_handler_duration_seconds",
+		Help:      "time spent in each canal table handler",
+		Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10},
+	}, []string{"handler"})
+
+	partitionLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "chii",
+		Name:      "canal_kafka_partition_lag",
+		Help:      "messages behind the high water mark of each partition when last message is fetched",
+	}, []string{"topic", "partition"})
+)
+
+//nolint:gochecknoinits
+func init() {
+	prometheus.MustRegister(events, handlerDuration, partitionLag)
+}
+
+// observeLag 更新 partition 的 lag 并返回.
+func observeLag(msg kafka.Message) int64 {
+	lag := max(msg.HighWaterMark-msg.Offset-1, 0)
+	partitionLag.WithLabelValues(msg.Topic, strconv.Itoa(msg.Partition)).Set(float64(lag))
+
+	return lag
+}
+
+// readerCollector 在每次采集时读取 [kafka.Reader.Stats].
+// Stats 中的计数是距离上一次调用的增量，所以需要自己累加.
+// 使用 GroupTopics 时 Stats 中的 lag 没有 topic 和 partition，所以 lag 只使用 partitionLag.
+type readerCollector struct {
+	reader *kafka.Reader
+
+	mu         sync.Mutex
+	messages   float64
+	errors     float64
+	rebalances float64
+
+	messagesDesc   *prometheus.Desc
+	errorsDesc     *prometheus.Desc
+	rebalancesDesc *prometheus.Desc
+	queueDesc      *prometheus.Desc
+}
+
+func newReaderCollector(r *kafka.Reader) *readerCollector {
+	return &readerCollector{
+		reader:         r,
+		messagesDesc:   prometheus.NewDesc("chii_canal_kafka_messages_total", "messages fetched from kafka", nil, nil),
+		errorsDesc:     prometheus.NewDesc("chii_canal_kafka_errors_total", "kafka reader errors", nil, nil),
+		rebalancesDesc: prometheus.NewDesc("chii_canal_kafka_rebalances_total", "consumer group rebalances", nil, nil),
+		queueDesc:      prometheus.NewDesc("chii_canal_kafka_queue_length", "fetched messages waiting in queue", nil, nil),
+	}
+}
+
+func (c *readerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.messagesDesc
+	ch <- c.errorsDesc
+	ch <- c.rebalancesDesc
+	ch <- c.queueDesc
+}
+
+func (c *readerCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.reader.Stats()
+
+	c.mu.Lock()
+	c.messages += float64(stats.Messages)
+	c.errors += float64(stats.Errors)
+	c.rebalances += float64(stats.Rebalances)
+	messages, errs, rebalances := c.messages, c.errors, c.rebalances
+	c.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.messagesDesc, prometheus.CounterValue, messages)
+	ch <- prometheus.MustNewConstMetric(c.errorsDesc, prometheus.CounterValue, errs)
+	ch <- prometheus.MustNewConstMetric(c.rebalancesDesc, prometheus.CounterValue, rebalances)
+	ch <- prometheus.MustNewConstMetric(c.queueDesc, prometheus.GaugeValue, float64(stats.QueueLength))
+}
@@ -19,25 +19,21 @@ import (
 
 	"github.com/stretchr/testify/require"
 
-	"github.com/bangumi/server/config"
 	"github.com/bangumi/server/internal/mocks"
 	"github.com/bangumi/server/internal/pkg/logger"
 )
 
 func TestOnSubjectChange(t *testing.T) {
 	t.Parallel()
-	session := mocks.NewSessionManager(t)
+	search := mocks.NewSearchClient(t)
 
-	c, err := config.NewAppConfig()
+	registry, err := NewRegistry(logger.Copy(), newSubjectHandlers(newSearchHandler(search, logger.Copy()))...)
 	require.NoError(t, err)
 
-	search := mocks.NewSearchClient(t)
-
 	eh := &eventHandler{
-		config:  c,
-		session: session,
-		search:  search,
-		log:     logger.Named("eventHandler"),
+		search:   search,
+		registry: registry,
+		log:      logger.Named("eventHandler"),
 	}
 
 	err = eh.onMessage([]byte(""), []byte(""))
@@ -0,0 +1,275 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+
+	"github.com/samber/lo"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/dal/query"
+	"github.com/bangumi/server/internal/cachekey"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/cache"
+	"github.com/bangumi/server/internal/subject"
+	"github.com/bangumi/server/internal/tag"
+)
+
+// 一个标签改名时，每次删除的缓存数量.
+const tagCacheBatchSize = 500
+
+// cacheHandler 在数据修改后删除 api 的缓存.
+type cacheHandler struct {
+	cache cache.RedisCache
+	q     *query.Query
+	log   *zap.Logger
+}
+
+type subjectPayload struct {
+	TypeID model.SubjectType `json:"subject_type_id"`
+}
+
+// subjectFieldPayload 只包含会影响浏览页面筛选和排序的字段，debezium 对日期的编码不固定，所以直接比较原始的 json.
+type subjectFieldPayload struct {
+	Redirect json.RawMessage `json:"field_redirect"`
+	Date     json.RawMessage `json:"field_date"`
+	Year     json.RawMessage `json:"field_year"`
+	Mon      json.RawMessage `json:"field_mon"`
+}
+
+type EpisodeKey struct {
+	ID model.EpisodeID `json:"ep_id"`
+}
+
+type episodePayload struct {
+	SubjectID model.SubjectID `json:"ep_subject_id"`
+}
+
+type tagListPayload struct {
+	UID uint32          `json:"tlt_uid"`
+	Cat uint8           `json:"tlt_cat"`
+	Mid model.SubjectID `json:"tlt_mid"`
+}
+
+type TagKey struct {
+	ID uint32 `json:"tag_id"`
+}
+
+type tagIndexPayload struct {
+	Name string `json:"tag_name"`
+	Cat  int8   `json:"tag_cat"`
+}
+
+func newCacheHandlers(c cache.RedisCache, q *query.Query, log *zap.Logger) []TableHandler {
+	h := &cacheHandler{cache: c, q: q, log: log.Named("canal.cache")}
+
+	return []TableHandler{
+		NewHandler(HandlerSpec[SubjectKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "cache.subject",
+				Tables: []string{"chii_subjects"},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: h.onSubject,
+		}),
+		NewHandler(HandlerSpec[SubjectFieldKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "cache.subject_field",
+				Tables: []string{"chii_subject_fields"},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: h.onSubjectField,
+		}),
+		NewHandler(HandlerSpec[EpisodeKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "cache.episode",
+				Tables: []string{"chii_episodes"},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: h.onEpisode,
+		}),
+		NewHandler(HandlerSpec[json.RawMessage]{
+			HandlerInfo: HandlerInfo{
+				Name:   "cache.tag_list",
+				Tables: []string{"chii_tag_neue_list"},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: h.onTagList,
+		}),
+		NewHandler(HandlerSpec[TagKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "cache.tag_index",
+				Tables: []string{"chii_tag_neue_index"},
+				Ops:    []string{opUpdate, opDelete},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: h.onTagIndex,
+		}),
+	}
+}
+
+// decodeImages 解析修改前后的数据，创建时没有 before，删除时没有 after，对应的结果会是零值.
+func decodeImages[T any](payload Payload) (before, after T, err error) {
+	if !isNullImage(payload.Before) {
+		if err = json.Unmarshal(payload.Before, &before); err != nil {
+			return before, after, errgo.Wrap(err, "json.Unmarshal")
+		}
+	}
+
+	if !isNullImage(payload.After) {
+		if err = json.Unmarshal(payload.After, &after); err != nil {
+			return before, after, errgo.Wrap(err, "json.Unmarshal")
+		}
+	}
+
+	return before, after, nil
+}
+
+// onSubject 条目的类型可能被修改，修改前后两个类型的浏览页面都需要失效.
+// 和 onSubjectField 一样，只修改收藏人数时不更新浏览缓存.
+func (h *cacheHandler) onSubject(ctx context.Context, k SubjectKey, payload Payload) error {
+	before, after, err := decodeImages[subjectPayload](payload)
+	if err != nil {
+		return err
+	}
+
+	if err := subject.InvalidateCache(ctx, h.cache, k.ID); err != nil {
+		return err
+	}
+
+	if payload.Op == opUpdate {
+		changed, err := subjectBrowseChanged(payload)
+		if err != nil {
+			return err
+		}
+
+		if !changed {
+			return nil
+		}
+	}
+
+	types := lo.Without(lo.Uniq([]model.SubjectType{before.TypeID, after.TypeID}), 0)
+
+	return subject.InvalidateBrowseCache(ctx, h.cache, types...)
+}
+
+// subjectBrowseChanged 比较修改前后除了收藏人数以外的字段，收藏人数在用户每次修改收藏时都会变化.
+func subjectBrowseChanged(payload Payload) (bool, error) {
+	before, after, err := decodeImages[map[string]json.RawMessage](payload)
+	if err != nil {
+		return false, err
+	}
+
+	for _, column := range collectionTypeColumns {
+		delete(before, column)
+		delete(after, column)
+	}
+
+	return !maps.EqualFunc(before, after, func(a, b json.RawMessage) bool { return bytes.Equal(a, b) }), nil
+}
+
+// onSubjectField 评分和排名经常变化，只在会影响浏览页面结果的字段变化时更新浏览缓存.
+// chii_subject_fields 中没有条目类型，所以会使所有类型的浏览缓存失效.
+func (h *cacheHandler) onSubjectField(ctx context.Context, k SubjectFieldKey, payload Payload) error {
+	before, after, err := decodeImages[subjectFieldPayload](payload)
+	if err != nil {
+		return err
+	}
+
+	if err := subject.InvalidateCache(ctx, h.cache, k.ID); err != nil {
+		return err
+	}
+
+	if payload.Op == opUpdate &&
+		bytes.Equal(before.Redirect, after.Redirect) &&
+		bytes.Equal(before.Date, after.Date) &&
+		bytes.Equal(before.Year, after.Year) &&
+		bytes.Equal(before.Mon, after.Mon) {
+		return nil
+	}
+
+	return subject.InvalidateBrowseCache(ctx, h.cache)
+}
+
+func (h *cacheHandler) onEpisode(ctx context.Context, k EpisodeKey, payload Payload) error {
+	before, after, err := decodeImages[episodePayload](payload)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{cachekey.Episode(k.ID)}
+	for _, id := range lo.Without(lo.Uniq([]model.SubjectID{before.SubjectID, after.SubjectID}), 0) {
+		keys = append(keys, cachekey.Subject(id))
+	}
+
+	return errgo.Wrap(h.cache.Del(ctx, keys...), "cache.Del")
+}
+
+// onTagList 只有 tlt_uid 为 0 的记录是条目的公共标签，用户自己的标签不会被缓存.
+func (h *cacheHandler) onTagList(ctx context.Context, _ json.RawMessage, payload Payload) error {
+	before, after, err := decodeImages[tagListPayload](payload)
+	if err != nil {
+		return err
+	}
+
+	var ids []model.SubjectID
+	for _, p := range []tagListPayload{before, after} {
+		if p.Mid != 0 && p.UID == 0 && p.Cat == tag.CatSubject {
+			ids = append(ids, p.Mid)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tag.InvalidateCache(ctx, h.cache, lo.Uniq(ids)...)
+}
+
+// onTagIndex 标签改名或者删除后，使用这个标签的条目都需要删除缓存.
+// tag_results 会随着用户打标签频繁变化，只影响显示的数量，不删除缓存.
+func (h *cacheHandler) onTagIndex(ctx context.Context, k TagKey, payload Payload) error {
+	before, after, err := decodeImages[tagIndexPayload](payload)
+	if err != nil {
+		return err
+	}
+
+	if before.Cat != tag.CatSubject || (payload.Op == opUpdate && before.Name == after.Name) {
+		return nil
+	}
+
+	var ids []model.SubjectID
+	err = h.q.TagList.WithContext(ctx).
+		Where(h.q.TagList.Tid.Eq(k.ID), h.q.TagList.UID.Eq(0), h.q.TagList.Cat.Eq(tag.CatSubject)).
+		Pluck(h.q.TagList.Mid, &ids)
+	if err != nil {
+		return errgo.Wrap(err, "failed to query tagged subjects")
+	}
+
+	h.log.Info("tag changed, invalidate subject tags cache", zap.Uint32("tag_id", k.ID), zap.Int("subjects", len(ids)))
+
+	for _, chunk := range lo.Chunk(lo.Uniq(ids), tagCacheBatchSize) {
+		if err := tag.InvalidateCache(ctx, h.cache, chunk...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/cachekey"
+	"github.com/bangumi/server/internal/mocks"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/logger"
+)
+
+func TestCacheHandler_onSubject(t *testing.T) {
+	t.Parallel()
+
+	c := mocks.NewRedisCache(t)
+	c.EXPECT().Del(mock.Anything, []string{cachekey.Subject(8)}).Return(nil).Once()
+	c.EXPECT().Set(mock.Anything, cachekey.SubjectBrowseVersion(model.SubjectTypeBook), mock.Anything, mock.Anything).
+		Return(nil).Once()
+	c.EXPECT().Set(mock.Anything, cachekey.SubjectBrowseVersion(model.SubjectTypeAnime), mock.Anything, mock.Anything).
+		Return(nil).Once()
+	c.EXPECT().Set(mock.Anything, cachekey.SubjectBrowseVersion(0), mock.Anything, mock.Anything).
+		Return(nil).Once()
+
+	c.EXPECT().Del(mock.Anything, []string{cachekey.Subject(8)}).Return(nil).Once()
+
+	h := &cacheHandler{cache: c, log: logger.Copy()}
+
+	require.NoError(t, h.onSubject(context.Background(), SubjectKey{ID: 8}, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"subject_type_id":1}`),
+		After:  json.RawMessage(`{"subject_type_id":2}`),
+	}))
+
+	// 只修改收藏人数不更新浏览缓存
+	require.NoError(t, h.onSubject(context.Background(), SubjectKey{ID: 8}, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"subject_type_id":2,"subject_wish":1,"subject_collect":3}`),
+		After:  json.RawMessage(`{"subject_type_id":2,"subject_wish":2,"subject_collect":2}`),
+	}))
+}
+
+func TestCacheHandler_onSubjectField(t *testing.T) {
+	t.Parallel()
+
+	c := mocks.NewRedisCache(t)
+	c.EXPECT().Del(mock.Anything, []string{cachekey.Subject(8)}).Return(nil).Twice()
+	// 修改放送日期时所有类型和不指定类型的浏览缓存都会失效
+	c.EXPECT().Set(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Times(6)
+
+	h := &cacheHandler{cache: c, log: logger.Copy()}
+
+	// 只修改评分不更新浏览缓存
+	require.NoError(t, h.onSubjectField(context.Background(), SubjectFieldKey{ID: 8}, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"field_date":9000,"field_rate_1":1}`),
+		After:  json.RawMessage(`{"field_date":9000,"field_rate_1":2}`),
+	}))
+
+	require.NoError(t, h.onSubjectField(context.Background(), SubjectFieldKey{ID: 8}, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"field_date":9000}`),
+		After:  json.RawMessage(`{"field_date":9001}`),
+	}))
+}
+
+func TestCacheHandler_onTagList(t *testing.T) {
+	t.Parallel()
+
+	c := mocks.NewRedisCache(t)
+	c.EXPECT().Del(mock.Anything, []string{cachekey.SubjectMetaTag(3)}).Return(nil).Once()
+
+	h := &cacheHandler{cache: c, log: logger.Copy()}
+
+	// 用户自己的标签
+	require.NoError(t, h.onTagList(context.Background(), nil, Payload{
+		Op:    opCreate,
+		After: json.RawMessage(`{"tlt_uid":1,"tlt_cat":0,"tlt_mid":3}`),
+	}))
+
+	require.NoError(t, h.onTagList(context.Background(), nil, Payload{
+		Op:     opDelete,
+		Before: json.RawMessage(`{"tlt_uid":0,"tlt_cat":0,"tlt_mid":3}`),
+	}))
+}
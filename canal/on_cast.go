@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/search"
+)
+
+// 角色和人物的 document 中保存了关联的条目，关联变化时需要更新对应的 document.
+
+type characterSubjectKey struct {
+	CharacterID model.CharacterID `json:"crt_id"`
+}
+
+type personSubjectKey struct {
+	PersonID model.PersonID `json:"prsn_id"`
+}
+
+func newCastHandlers(s *searchHandler) []TableHandler {
+	return []TableHandler{
+		NewHandler(HandlerSpec[characterSubjectKey]{
+			HandlerInfo: HandlerInfo{Name: "search.character_subject", Tables: []string{"chii_crt_subject_index"}},
+			Handle: func(ctx context.Context, k characterSubjectKey, _ Payload) error {
+				return s.onChange(ctx, k.CharacterID, search.SearchTargetCharacter, opUpdate)
+			},
+		}),
+		// 配音的条目只会写入人物的 document
+		NewHandler(HandlerSpec[personSubjectKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "search.person_subject",
+				Tables: []string{"chii_person_cs_index", "chii_crt_cast_index"},
+			},
+			Handle: func(ctx context.Context, k personSubjectKey, _ Payload) error {
+				return s.onChange(ctx, k.PersonID, search.SearchTargetPerson, opUpdate)
+			},
+		}),
+	}
+}
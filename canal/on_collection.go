@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/bangumi/server/internal/model"
+)
+
+// collectionHandler 在收藏记录修改了收藏类型或者评分时重新统计条目的收藏数量和评分分布.
+// web 修改收藏时会在同一个事务中重新统计，这里处理其他途径的修改，重新统计是幂等的，消息重复处理不会产生误差.
+// 更新 chii_subjects 和 chii_subject_fields 后产生的 binlog 会删除条目缓存并更新搜索索引.
+type collectionHandler struct {
+	db  *gorm.DB
+	log *zap.Logger
+}
+
+func newCollectionHandlers(db *gorm.DB, log *zap.Logger) []TableHandler {
+	h := &collectionHandler{db: db, log: log.Named("canal.collection")}
+
+	return []TableHandler{
+		NewHandler(HandlerSpec[json.RawMessage]{
+			HandlerInfo: HandlerInfo{
+				Name:   "collection.subject_counts",
+				Tables: []string{"chii_subject_interests"},
+				Ops:    []string{opCreate, opUpdate, opDelete},
+				Images: ImageBefore | ImageAfter,
+				// 重放线上的消息不应该修改线上的计数
+				NoReplay: true,
+			},
+			Handle: h.onInterest,
+		}),
+	}
+}
+
+func (h *collectionHandler) onInterest(ctx context.Context, _ json.RawMessage, payload Payload) error {
+	before, after, err := decodeImages[interestPayload](payload)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range affectedSubjects(before, after) {
+		if err := recountSubjectCounts(ctx, h.db, id); err != nil {
+			return errgo.Trace(err)
+		}
+	}
+
+	return nil
+}
+
+// affectedSubjects 返回计数可能变化的条目，创建时 before 为零值，删除时 after 为零值.
+// 只修改了吐槽、标签等字段时不需要重新统计.
+func affectedSubjects(before, after interestPayload) []model.SubjectID {
+	if before.SubjectID == after.SubjectID {
+		if before.SubjectID == 0 || interestCounts(before) == interestCounts(after) {
+			return nil
+		}
+
+		return []model.SubjectID{after.SubjectID}
+	}
+
+	var ids []model.SubjectID
+	for _, p := range []interestPayload{before, after} {
+		if p.SubjectID != 0 && !interestCounts(p).isZero() {
+			ids = append(ids, p.SubjectID)
+		}
+	}
+
+	return ids
+}
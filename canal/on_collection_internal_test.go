@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/collections/domain/collection"
+	"github.com/bangumi/server/internal/model"
+)
+
+func TestAffectedSubjects(t *testing.T) {
+	t.Parallel()
+
+	const sid model.SubjectID = 8
+
+	testCases := map[string]struct {
+		before   interestPayload
+		after    interestPayload
+		expected []model.SubjectID
+	}{
+		"create": {
+			after:    interestPayload{SubjectID: sid, Type: 2, Rate: 7},
+			expected: []model.SubjectID{sid},
+		},
+		"change type and rate": {
+			before:   interestPayload{SubjectID: sid, Type: 3, Rate: 6},
+			after:    interestPayload{SubjectID: sid, Type: 2, Rate: 9},
+			expected: []model.SubjectID{sid},
+		},
+		"private collection is not rated": {
+			before:   interestPayload{SubjectID: sid, Type: 2, Rate: 6},
+			after:    interestPayload{SubjectID: sid, Type: 2, Rate: 6, Private: uint8(collection.CollectPrivacySelf)},
+			expected: []model.SubjectID{sid},
+		},
+		"only comment changed": {
+			before: interestPayload{SubjectID: sid, Type: 2, Rate: 6},
+			after:  interestPayload{SubjectID: sid, Type: 2, Rate: 6},
+		},
+		"delete": {
+			before:   interestPayload{SubjectID: sid, Type: 1},
+			expected: []model.SubjectID{sid},
+		},
+		"move to another subject": {
+			before:   interestPayload{SubjectID: sid, Type: 1},
+			after:    interestPayload{SubjectID: 9, Type: 1},
+			expected: []model.SubjectID{sid, 9},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.expected, affectedSubjects(tc.before, tc.after))
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import "context"
+
+type subjectImagePayload struct {
+	Image string `json:"subject_image"`
+}
+
+type characterImagePayload struct {
+	Image string `json:"crt_img"`
+}
+
+type personImagePayload struct {
+	Image string `json:"prsn_img"`
+}
+
+// newImageHandlers 在条目封面、角色和人物图片修改或者删除后清除缩放后的图片.
+// 新图片可能使用和旧图片相同的文件名，所以修改前后的前缀都会清除.
+func newImageHandlers(p *imagePurger) []TableHandler {
+	info := func(name, table string) HandlerInfo {
+		return HandlerInfo{
+			Name:   name,
+			Tables: []string{table},
+			Ops:    []string{opUpdate, opDelete},
+			Images: ImageBefore,
+		}
+	}
+
+	return []TableHandler{
+		NewHandler(HandlerSpec[SubjectKey]{
+			HandlerInfo: info("image.subject", "chii_subjects"),
+			Handle: func(ctx context.Context, _ SubjectKey, payload Payload) error {
+				before, after, err := decodeImages[subjectImagePayload](payload)
+				if err != nil || before == after {
+					return err
+				}
+
+				return p.purge(ctx, coverPrefix(before.Image), coverPrefix(after.Image))
+			},
+		}),
+		NewHandler(HandlerSpec[CharacterKey]{
+			HandlerInfo: info("image.character", "chii_characters"),
+			Handle: func(ctx context.Context, _ CharacterKey, payload Payload) error {
+				before, after, err := decodeImages[characterImagePayload](payload)
+				if err != nil || before == after {
+					return err
+				}
+
+				return p.purge(ctx, monoPrefix(before.Image), monoPrefix(after.Image))
+			},
+		}),
+		NewHandler(HandlerSpec[PersonKey]{
+			HandlerInfo: info("image.person", "chii_persons"),
+			Handle: func(ctx context.Context, _ PersonKey, payload Payload) error {
+				before, after, err := decodeImages[personImagePayload](payload)
+				if err != nil || before == after {
+					return err
+				}
+
+				return p.purge(ctx, monoPrefix(before.Image), monoPrefix(after.Image))
+			},
+		}),
+	}
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/search"
+)
+
+type IndexKey struct {
+	ID model.IndexID `json:"idx_id"`
+}
+
+type indexRelatedPayload struct {
+	IndexID model.IndexID `json:"idx_rlt_rid"`
+}
+
+func newIndexHandlers(s *searchHandler) []TableHandler {
+	return []TableHandler{
+		NewHandler(HandlerSpec[IndexKey]{
+			HandlerInfo: HandlerInfo{Name: "search.index", Tables: []string{"chii_index"}},
+			// 删除目录和设为私有都是更新 idx_ban 字段
+			Handle: func(ctx context.Context, k IndexKey, payload Payload) error {
+				return s.onChange(ctx, k.ID, search.SearchTargetIndex, payload.Op)
+			},
+		}),
+		NewHandler(HandlerSpec[json.RawMessage]{
+			HandlerInfo: HandlerInfo{
+				Name:   "search.index_related",
+				Tables: []string{"chii_index_related"},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: s.onIndexRelated,
+		}),
+	}
+}
+
+// onIndexRelated 目录中的条目变化会影响目录的条目数量和 nsfw.
+func (s *searchHandler) onIndexRelated(ctx context.Context, _ json.RawMessage, payload Payload) error {
+	raw := payload.After
+	if payload.Op == opDelete {
+		raw = payload.Before
+	}
+
+	var p indexRelatedPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return errgo.Wrap(err, "json.Unmarshal")
+	}
+
+	return s.onChange(ctx, p.IndexID, search.SearchTargetIndex, opUpdate)
+}
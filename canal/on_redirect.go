@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/internal/search"
+)
+
+type subjectRedirectPayload struct {
+	Redirect uint32 `json:"field_redirect"`
+}
+
+type characterRedirectPayload struct {
+	Redirect uint32 `json:"crt_redirect"`
+}
+
+type personRedirectPayload struct {
+	Redirect uint32 `json:"prsn_redirect"`
+}
+
+// newRedirectHandlers 在条目、角色和人物被合并（或者取消合并）后重新索引合并的目标.
+// 被合并的条目本身由 search.* handler 从索引中删除.
+func newRedirectHandlers(s *searchHandler) []TableHandler {
+	info := func(name, table string) HandlerInfo {
+		return HandlerInfo{
+			Name:   name,
+			Tables: []string{table},
+			Ops:    []string{opUpdate},
+			Images: ImageBefore | ImageAfter,
+		}
+	}
+
+	return []TableHandler{
+		NewHandler(HandlerSpec[SubjectFieldKey]{
+			HandlerInfo: info("search.subject_redirect", "chii_subject_fields"),
+			Handle: func(ctx context.Context, _ SubjectFieldKey, payload Payload) error {
+				before, after, err := decodeImages[subjectRedirectPayload](payload)
+				if err != nil {
+					return err
+				}
+
+				return s.reindex(ctx, search.SearchTargetSubject, redirectTargets(before.Redirect, after.Redirect))
+			},
+		}),
+		NewHandler(HandlerSpec[CharacterKey]{
+			HandlerInfo: info("search.character_redirect", "chii_characters"),
+			Handle: func(ctx context.Context, _ CharacterKey, payload Payload) error {
+				before, after, err := decodeImages[characterRedirectPayload](payload)
+				if err != nil {
+					return err
+				}
+
+				return s.reindex(ctx, search.SearchTargetCharacter, redirectTargets(before.Redirect, after.Redirect))
+			},
+		}),
+		NewHandler(HandlerSpec[PersonKey]{
+			HandlerInfo: info("search.person_redirect", "chii_persons"),
+			Handle: func(ctx context.Context, _ PersonKey, payload Payload) error {
+				before, after, err := decodeImages[personRedirectPayload](payload)
+				if err != nil {
+					return err
+				}
+
+				return s.reindex(ctx, search.SearchTargetPerson, redirectTargets(before.Redirect, after.Redirect))
+			},
+		}),
+	}
+}
+
+// redirectTargets 返回需要重新索引的目标，修改合并目标时新旧两个目标都需要更新.
+func redirectTargets(before, after uint32) []uint32 {
+	if before == after {
+		return nil
+	}
+
+	var ids []uint32
+	for _, id := range []uint32{before, after} {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func (s *searchHandler) reindex(ctx context.Context, target search.SearchTarget, ids []uint32) error {
+	for _, id := range ids {
+		if err := s.search.EventUpdate(ctx, id, target); err != nil {
+			return errgo.Wrap(err, "search.EventUpdate")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/mocks"
+	"github.com/bangumi/server/internal/pkg/logger"
+	"github.com/bangumi/server/internal/search"
+)
+
+func TestRedirectTargets(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, redirectTargets(0, 0))
+	require.Empty(t, redirectTargets(3, 3))
+	require.Equal(t, []uint32{3}, redirectTargets(0, 3))
+	require.Equal(t, []uint32{3}, redirectTargets(3, 0))
+	require.Equal(t, []uint32{3, 4}, redirectTargets(3, 4))
+}
+
+func TestRedirectHandlers_subject(t *testing.T) {
+	t.Parallel()
+
+	c := mocks.NewSearchClient(t)
+	c.EXPECT().EventUpdate(mock.Anything, uint32(5), search.SearchTargetSubject).Return(nil).Once()
+
+	h := newRedirectHandlers(newSearchHandler(c, logger.Copy()))[0]
+
+	// 只修改其他字段
+	require.NoError(t, h.Handle(context.Background(), json.RawMessage(`{"field_sid":8}`), Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"field_redirect":0,"field_rate_1":1}`),
+		After:  json.RawMessage(`{"field_redirect":0,"field_rate_1":2}`),
+	}))
+
+	require.NoError(t, h.Handle(context.Background(), json.RawMessage(`{"field_sid":8}`), Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"field_redirect":0}`),
+		After:  json.RawMessage(`{"field_redirect":5}`),
+	}))
+}
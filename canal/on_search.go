@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/internal/search"
+)
+
+// searchHandler 把条目、角色、人物和目录的变化同步到搜索索引.
+type searchHandler struct {
+	search search.Client
+	log    *zap.Logger
+}
+
+func newSearchHandler(search search.Client, log *zap.Logger) *searchHandler {
+	return &searchHandler{search: search, log: log.Named("canal.search")}
+}
+
+func (s *searchHandler) onChange(ctx context.Context, id uint32, target search.SearchTarget, op string) error {
+	switch op {
+	case opCreate:
+		if err := s.search.EventAdded(ctx, id, target); err != nil {
+			return errgo.Wrap(err, "search.EventAdded")
+		}
+	case opUpdate, opSnapshot:
+		if err := s.search.EventUpdate(ctx, id, target); err != nil {
+			return errgo.Wrap(err, "search.EventUpdate")
+		}
+	case opDelete:
+		if err := s.search.EventDelete(ctx, id, target); err != nil {
+			return errgo.Wrap(err, "search.EventDelete")
+		}
+	default:
+		s.log.Warn("unexpected operator", zap.String("op", op))
+	}
+
+	return nil
+}
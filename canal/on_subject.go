@@ -16,10 +16,6 @@ package canal
 
 import (
 	"context"
-	"encoding/json"
-
-	"github.com/trim21/errgo"
-	"go.uber.org/zap"
 
 	"github.com/bangumi/server/internal/model"
 	"github.com/bangumi/server/internal/search"
@@ -33,41 +29,19 @@ type SubjectFieldKey struct {
 	ID model.SubjectID `json:"field_sid"`
 }
 
-func (e *eventHandler) OnSubject(ctx context.Context, key json.RawMessage, payload Payload) error {
-	var k SubjectKey
-	if err := json.Unmarshal(key, &k); err != nil {
-		return err
-	}
-
-	return e.onSubjectChange(ctx, k.ID, payload.Op)
-}
-
-func (e *eventHandler) OnSubjectField(ctx context.Context, key json.RawMessage, payload Payload) error {
-	var k SubjectFieldKey
-	if err := json.Unmarshal(key, &k); err != nil {
-		return err
+func newSubjectHandlers(s *searchHandler) []TableHandler {
+	return []TableHandler{
+		NewHandler(HandlerSpec[SubjectKey]{
+			HandlerInfo: HandlerInfo{Name: "search.subject", Tables: []string{"chii_subjects"}},
+			Handle: func(ctx context.Context, k SubjectKey, payload Payload) error {
+				return s.onChange(ctx, k.ID, search.SearchTargetSubject, payload.Op)
+			},
+		}),
+		NewHandler(HandlerSpec[SubjectFieldKey]{
+			HandlerInfo: HandlerInfo{Name: "search.subject_field", Tables: []string{"chii_subject_fields"}},
+			Handle: func(ctx context.Context, k SubjectFieldKey, payload Payload) error {
+				return s.onChange(ctx, k.ID, search.SearchTargetSubject, payload.Op)
+			},
+		}),
 	}
-
-	return e.onSubjectChange(ctx, k.ID, payload.Op)
-}
-
-func (e *eventHandler) onSubjectChange(ctx context.Context, subjectID model.SubjectID, op string) error {
-	switch op {
-	case opCreate:
-		if err := e.search.EventAdded(ctx, subjectID, search.SearchTargetSubject); err != nil {
-			return errgo.Wrap(err, "search.OnSubjectAdded")
-		}
-	case opUpdate, opSnapshot:
-		if err := e.search.EventUpdate(ctx, subjectID, search.SearchTargetSubject); err != nil {
-			return errgo.Wrap(err, "search.OnSubjectUpdate")
-		}
-	case opDelete:
-		if err := e.search.EventDelete(ctx, subjectID, search.SearchTargetSubject); err != nil {
-			return errgo.Wrap(err, "search.OnSubjectDelete")
-		}
-	default:
-		e.log.Warn("unexpected operator", zap.String("op", op))
-	}
-
-	return nil
 }
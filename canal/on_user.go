@@ -19,115 +19,168 @@ import (
 	"encoding"
 	"encoding/json"
 	"fmt"
-	"strings"
-	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/redis/rueidis"
-	"github.com/samber/lo"
 	"github.com/trim21/errgo"
 	"go.uber.org/zap"
 
+	"github.com/bangumi/server/internal/auth"
 	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/cache"
+	"github.com/bangumi/server/internal/pkg/gstr"
 	"github.com/bangumi/server/internal/pkg/logger/log"
+	"github.com/bangumi/server/internal/webhook"
+	"github.com/bangumi/server/web/session"
 )
 
-func (e *eventHandler) OnUserChange(ctx context.Context, key json.RawMessage, payload Payload) error {
-	var k UserKey
-	if err := json.Unmarshal(key, &k); err != nil {
-		e.log.Error("failed to unmarshal json", zap.Error(err))
-		return errgo.Wrap(err, "json.Unmarshal")
+// userHandler 处理用户修改密码、用户组、通知和头像，以及删除 access token.
+type userHandler struct {
+	session  session.Manager
+	redis    rueidis.Client
+	cache    cache.RedisCache
+	webhooks webhook.Repo
+	images   *imagePurger
+	log      *zap.Logger
+}
+
+func newUserHandlers(
+	session session.Manager,
+	redis rueidis.Client,
+	c cache.RedisCache,
+	webhooks webhook.Repo,
+	images *imagePurger,
+	log *zap.Logger,
+) []TableHandler {
+	u := &userHandler{
+		session:  session,
+		redis:    redis,
+		cache:    c,
+		webhooks: webhooks,
+		images:   images,
+		log:      log.Named("canal.user"),
+	}
+
+	return []TableHandler{
+		NewHandler(HandlerSpec[UserKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "user",
+				Tables: []string{"chii_members"},
+				Ops:    []string{opUpdate},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: u.OnUserChange,
+		}),
+		NewHandler(HandlerSpec[AccessTokenKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "user.access_token",
+				Tables: []string{"chii_oauth_access_tokens"},
+				Ops:    []string{opDelete},
+				Images: ImageBefore,
+			},
+			Handle: u.OnAccessTokenDelete,
+		}),
+	}
+}
+
+// revokeUser 使用户已经登录的 session 和 access token 缓存失效，下一次请求会重新从数据库读取用户组和权限.
+func (e *userHandler) revokeUser(ctx context.Context, id model.UserID) error {
+	if err := e.session.RevokeUser(ctx, id); err != nil {
+		return errgo.Wrap(err, "session.RevokeUser")
+	}
+
+	return auth.InvalidateUserCache(ctx, e.cache, id)
+}
+
+// OnAccessTokenDelete 删除的 token 可能还在缓存中，用户的所有缓存都需要删除.
+// 使用这个 token 创建的 webhook 也会被删除.
+func (e *userHandler) OnAccessTokenDelete(ctx context.Context, k AccessTokenKey, payload Payload) error {
+	if err := e.deleteWebhooks(ctx, k.ID); err != nil {
+		return err
+	}
+
+	var before accessTokenPayload
+	if err := json.Unmarshal(payload.Before, &before); err != nil {
+		return errgo.Wrap(err, "json")
 	}
 
-	switch payload.Op {
-	case opCreate, opSnapshot, opDelete:
+	id, err := gstr.ParseUint32(before.UserID)
+	if err != nil || id == 0 {
+		e.log.Warn("wrong user_id in deleted access token", zap.String("user_id", before.UserID))
 		return nil
-	case opUpdate:
-		var before userPayload
-		if err := json.Unmarshal(payload.Before, &before); err != nil {
-			return errgo.Wrap(err, "json")
-		}
-		var after userPayload
-		if err := json.Unmarshal(payload.After, &after); err != nil {
-			return errgo.Wrap(err, "json")
-		}
+	}
 
-		if before.Password != after.Password {
-			err := e.OnUserPasswordChange(ctx, k.ID)
-			if err != nil {
-				e.log.Error("failed to clear cache", zap.Error(err))
-			}
-		}
+	e.log.Info("access token deleted, revoke user", log.User(id))
 
-		if before.NewNotify != after.NewNotify {
-			e.redis.Do(ctx, e.redis.B().Publish().
-				Channel(fmt.Sprintf("event-user-notify-%d", k.ID)).
-				Message(rueidis.JSON(redisUserChannel{
-					UserID:    k.ID,
-					NewNotify: after.NewNotify,
-				})).Build())
-		}
+	return e.revokeUser(ctx, id)
+}
 
-		if before.Avatar != after.Avatar {
-			if e.s3 == nil {
-				break
-			}
+func (e *userHandler) deleteWebhooks(ctx context.Context, tokenID uint32) error {
+	hooks, err := e.webhooks.ListByToken(ctx, tokenID)
+	if err != nil {
+		return errgo.Wrap(err, "webhook.ListByToken")
+	}
 
-			e.log.Debug("clear user avatar cache", log.User(k.ID))
-			go e.clearImageCache(context.WithoutCancel(ctx), after.Avatar)
+	for _, w := range hooks {
+		e.log.Info("access token deleted, delete webhook", zap.Uint32("token_id", tokenID), zap.String("webhook", w.ID))
+		if err := e.webhooks.Delete(ctx, w); err != nil {
+			return errgo.Wrap(err, "webhook.Delete")
 		}
 	}
 
 	return nil
 }
 
-func (e *eventHandler) clearImageCache(ctx context.Context, avatar string) {
-	p, q, ok := strings.Cut(avatar, "?")
-	if !ok {
-		p = avatar
+func (e *userHandler) OnUserPasswordChange(ctx context.Context, id model.UserID) error {
+	if err := e.session.RevokeUser(ctx, id); err != nil {
+		e.log.Error("failed to revoke user", log.User(id), zap.Error(err))
+		return errgo.Wrap(err, "session.RevokeUser")
 	}
 
-	p = "/pic/user/l/" + p
+	return nil
+}
 
-	if strings.Contains(q, "hd=1") {
-		p = "/hd" + p
+func (e *userHandler) OnUserChange(ctx context.Context, k UserKey, payload Payload) error {
+	var before userPayload
+	if err := json.Unmarshal(payload.Before, &before); err != nil {
+		return errgo.Wrap(err, "json")
+	}
+	var after userPayload
+	if err := json.Unmarshal(payload.After, &after); err != nil {
+		return errgo.Wrap(err, "json")
 	}
 
-	e.log.Debug("clear image for prefix", zap.String("avatar", avatar), zap.String("prefix", p))
-
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	pages := s3.NewListObjectsV2Paginator(
-		e.s3,
-		&s3.ListObjectsV2Input{Bucket: &e.config.S3ImageResizeBucket, Prefix: &p},
-	)
-
-	for pages.HasMorePages() {
-		output, err := pages.NextPage(ctx)
+	if before.Password != after.Password {
+		err := e.OnUserPasswordChange(ctx, k.ID)
 		if err != nil {
-			break
+			e.log.Error("failed to clear cache", zap.Error(err))
 		}
+	}
 
-		if len(output.Contents) == 0 {
-			break
+	if before.GroupID != after.GroupID {
+		e.log.Info("user group changed, revoke user", log.User(k.ID),
+			zap.Uint8("before", before.GroupID), zap.Uint8("after", after.GroupID))
+		if err := e.revokeUser(ctx, k.ID); err != nil {
+			return err
 		}
+	}
 
-		_, err = e.s3.DeleteObjects(ctx, &s3.DeleteObjectsInput{
-			Bucket: &e.config.S3ImageResizeBucket,
-			Delete: &types.Delete{
-				Objects: lo.Map(output.Contents, func(item types.Object, index int) types.ObjectIdentifier {
-					return types.ObjectIdentifier{
-						Key: item.Key,
-					}
-				}),
-			},
-		})
-		if err != nil {
-			e.log.Error("failed to clear s3 cached image", zap.Error(err))
+	if before.NewNotify != after.NewNotify {
+		e.redis.Do(ctx, e.redis.B().Publish().
+			Channel(fmt.Sprintf("event-user-notify-%d", k.ID)).
+			Message(rueidis.JSON(redisUserChannel{
+				UserID:    k.ID,
+				NewNotify: after.NewNotify,
+			})).Build())
+	}
+
+	if before.Avatar != after.Avatar {
+		e.log.Debug("clear user avatar cache", log.User(k.ID))
+		if err := e.images.purge(ctx, avatarPrefix(after.Avatar)); err != nil {
+			return err
 		}
 	}
+
+	return nil
 }
 
 var _ encoding.BinaryMarshaler = redisUserChannel{}
@@ -145,7 +198,16 @@ type UserKey struct {
 	ID model.UserID `json:"uid"`
 }
 
+type AccessTokenKey struct {
+	ID uint32 `json:"id"`
+}
+
+type accessTokenPayload struct {
+	UserID string `json:"user_id"`
+}
+
 type userPayload struct {
+	GroupID   uint8  `json:"groupid"`
 	Password  string `json:"password_crypt"`
 	NewNotify uint16 `json:"new_notify"`
 	Avatar    string `json:"avatar"`
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/mocks"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/logger"
+	"github.com/bangumi/server/internal/webhook"
+)
+
+func TestUserHandler_groupChange(t *testing.T) {
+	t.Parallel()
+
+	s := mocks.NewSessionManager(t)
+	s.EXPECT().RevokeUser(mock.Anything, model.UserID(1)).Return(nil).Once()
+
+	c := mocks.NewRedisCache(t)
+	c.EXPECT().DelTag(mock.Anything, mock.Anything).Return(nil).Once()
+
+	h := &userHandler{session: s, cache: c, log: logger.Copy()}
+
+	require.NoError(t, h.OnUserChange(context.Background(), UserKey{ID: 1}, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"groupid":10}`),
+		After:  json.RawMessage(`{"groupid":5}`),
+	}))
+
+	// 没有修改用户组
+	require.NoError(t, h.OnUserChange(context.Background(), UserKey{ID: 1}, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"groupid":10}`),
+		After:  json.RawMessage(`{"groupid":10}`),
+	}))
+}
+
+func TestUserHandler_OnAccessTokenDelete(t *testing.T) {
+	t.Parallel()
+
+	s := mocks.NewSessionManager(t)
+	s.EXPECT().RevokeUser(mock.Anything, model.UserID(2)).Return(nil).Once()
+
+	c := mocks.NewRedisCache(t)
+	c.EXPECT().DelTag(mock.Anything, mock.Anything).Return(nil).Once()
+
+	w := mocks.NewWebhookRepo(t)
+	hook := webhook.Webhook{ID: "hook", TokenID: 3, UserID: 2}
+	w.EXPECT().ListByToken(mock.Anything, uint32(3)).Return([]webhook.Webhook{hook}, nil).Once()
+	w.EXPECT().Delete(mock.Anything, hook).Return(nil).Once()
+
+	h := &userHandler{session: s, cache: c, webhooks: w, log: logger.Copy()}
+
+	require.NoError(t, h.OnAccessTokenDelete(context.Background(), AccessTokenKey{ID: 3}, Payload{
+		Op:     opDelete,
+		Before: json.RawMessage(`{"id":3,"access_token":"token","user_id":"2"}`),
+	}))
+}
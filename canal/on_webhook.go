@@ -0,0 +1,256 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/internal/webhook"
+)
+
+// fieldDeleted 是标记删除的字段，从 0 修改为其他值时发送 `*.deleted` 事件.
+const fieldDeleted = "deleted"
+
+// 数据库字段到 api 字段的映射，只有这些字段的修改会产生 `*.updated` 事件.
+// 收藏数，评分，排名等统计字段变化太频繁，不会发送事件.
+//
+//nolint:gochecknoglobals
+var (
+	subjectWebhookFields = map[string]string{
+		"subject_type_id":  "type",
+		"subject_name":     "name",
+		"subject_name_cn":  "name_cn",
+		"subject_image":    "image",
+		"subject_platform": "platform",
+		"field_infobox":    "infobox",
+		"field_meta_tags":  "meta_tags",
+		"field_summary":    "summary",
+		"field_volumes":    "volumes",
+		"field_eps":        "eps",
+		"subject_series":   "series",
+		"subject_nsfw":     "nsfw",
+		"subject_ban":      "locked",
+	}
+
+	subjectFieldWebhookFields = map[string]string{
+		"field_date":     "date",
+		"field_redirect": "redirect",
+	}
+
+	episodeWebhookFields = map[string]string{
+		"ep_subject_id": "subject_id",
+		"ep_sort":       "sort",
+		"ep_type":       "type",
+		"ep_disc":       "disc",
+		"ep_name":       "name",
+		"ep_name_cn":    "name_cn",
+		"ep_duration":   "duration",
+		"ep_airdate":    "airdate",
+		"ep_desc":       "desc",
+		"ep_lock":       "locked",
+		"ep_ban":        fieldDeleted,
+	}
+
+	characterWebhookFields = map[string]string{
+		"crt_name":     "name",
+		"crt_role":     "role",
+		"crt_infobox":  "infobox",
+		"crt_summary":  "summary",
+		"crt_img":      "image",
+		"crt_lock":     "locked",
+		"crt_redirect": "redirect",
+		"crt_nsfw":     "nsfw",
+		"crt_ban":      fieldDeleted,
+	}
+
+	personWebhookFields = map[string]string{
+		"prsn_name":        "name",
+		"prsn_type":        "type",
+		"prsn_infobox":     "infobox",
+		"prsn_producer":    "career",
+		"prsn_mangaka":     "career",
+		"prsn_artist":      "career",
+		"prsn_seiyu":       "career",
+		"prsn_writer":      "career",
+		"prsn_illustrator": "career",
+		"prsn_actor":       "career",
+		"prsn_summary":     "summary",
+		"prsn_img":         "image",
+		"prsn_lock":        "locked",
+		"prsn_redirect":    "redirect",
+		"prsn_nsfw":        "nsfw",
+		"prsn_ban":         fieldDeleted,
+	}
+)
+
+type eventPublisher interface {
+	Publish(ctx context.Context, e webhook.Event) error
+}
+
+// noopPublisher 用于重放消息，不会发送 webhook.
+type noopPublisher struct{}
+
+func (noopPublisher) Publish(context.Context, webhook.Event) error { return nil }
+
+// webhookHandler 把 wiki 数据的修改转换成 webhook 事件.
+type webhookHandler struct {
+	publisher eventPublisher
+}
+
+func newWebhookHandlers(p eventPublisher) []TableHandler {
+	h := &webhookHandler{publisher: p}
+
+	return []TableHandler{
+		NewHandler(HandlerSpec[SubjectKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "webhook.subject",
+				Tables: []string{"chii_subjects"},
+				Ops:    []string{opCreate, opUpdate, opDelete},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: func(ctx context.Context, k SubjectKey, payload Payload) error {
+				return h.publish(ctx, "subject", k.ID, payload, subjectWebhookFields)
+			},
+		}),
+		// 创建和删除条目时会同时修改 chii_subjects，这里只处理更新
+		NewHandler(HandlerSpec[SubjectFieldKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "webhook.subject_field",
+				Tables: []string{"chii_subject_fields"},
+				Ops:    []string{opUpdate},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: func(ctx context.Context, k SubjectFieldKey, payload Payload) error {
+				return h.publish(ctx, "subject", k.ID, payload, subjectFieldWebhookFields)
+			},
+		}),
+		NewHandler(HandlerSpec[EpisodeKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "webhook.episode",
+				Tables: []string{"chii_episodes"},
+				Ops:    []string{opCreate, opUpdate, opDelete},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: func(ctx context.Context, k EpisodeKey, payload Payload) error {
+				return h.publish(ctx, "episode", k.ID, payload, episodeWebhookFields)
+			},
+		}),
+		NewHandler(HandlerSpec[CharacterKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "webhook.character",
+				Tables: []string{"chii_characters"},
+				Ops:    []string{opCreate, opUpdate, opDelete},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: func(ctx context.Context, k CharacterKey, payload Payload) error {
+				return h.publish(ctx, "character", k.ID, payload, characterWebhookFields)
+			},
+		}),
+		NewHandler(HandlerSpec[PersonKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "webhook.person",
+				Tables: []string{"chii_persons"},
+				Ops:    []string{opCreate, opUpdate, opDelete},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: func(ctx context.Context, k PersonKey, payload Payload) error {
+				return h.publish(ctx, "person", k.ID, payload, personWebhookFields)
+			},
+		}),
+	}
+}
+
+func (h *webhookHandler) publish(
+	ctx context.Context, entity string, id uint32, payload Payload, fields map[string]string,
+) error {
+	e := webhook.Event{EntityID: id}
+
+	switch payload.Op {
+	case opCreate:
+		e.Type = entity + ".created"
+	case opDelete:
+		e.Type = entity + ".deleted"
+	case opUpdate:
+		deleted, err := markedDeleted(payload, fields)
+		if err != nil {
+			return err
+		}
+
+		if deleted {
+			e.Type = entity + ".deleted"
+			break
+		}
+
+		changed, err := changedFields(payload, fields)
+		if err != nil {
+			return err
+		}
+
+		if len(changed) == 0 {
+			return nil
+		}
+
+		e.Type = entity + ".updated"
+		e.Changed = changed
+	default:
+		return nil
+	}
+
+	return errgo.Wrap(h.publisher.Publish(ctx, e), "webhook.Publish")
+}
+
+// markedDeleted 返回这次修改是否把 [fieldDeleted] 对应的字段从 0 改成了其他值.
+func markedDeleted(payload Payload, fields map[string]string) (bool, error) {
+	before, after, err := decodeImages[map[string]json.RawMessage](payload)
+	if err != nil {
+		return false, err
+	}
+
+	for column, name := range fields {
+		if name == fieldDeleted && isZero(before[column]) && !isZero(after[column]) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func isZero(v json.RawMessage) bool {
+	return len(v) == 0 || string(v) == "0" || string(v) == "null"
+}
+
+// changedFields 比较修改前后的数据，返回修改过的 api 字段，按字母排序.
+func changedFields(payload Payload, fields map[string]string) ([]string, error) {
+	before, after, err := decodeImages[map[string]json.RawMessage](payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for column, name := range fields {
+		if !bytes.Equal(before[column], after[column]) {
+			changed = append(changed, name)
+		}
+	}
+
+	slices.Sort(changed)
+
+	return slices.Compact(changed), nil
+}
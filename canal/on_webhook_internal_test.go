@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/webhook"
+)
+
+type memoryPublisher struct {
+	events []webhook.Event
+}
+
+func (p *memoryPublisher) Publish(_ context.Context, e webhook.Event) error {
+	p.events = append(p.events, e)
+	return nil
+}
+
+func TestChangedFields(t *testing.T) {
+	t.Parallel()
+
+	changed, err := changedFields(Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"prsn_name":"a","prsn_seiyu":0,"prsn_actor":0,"prsn_collects":1}`),
+		After:  json.RawMessage(`{"prsn_name":"b","prsn_seiyu":1,"prsn_actor":1,"prsn_collects":2}`),
+	}, personWebhookFields)
+	require.NoError(t, err)
+	require.Equal(t, []string{"career", "name"}, changed)
+}
+
+func TestWebhookHandler_publish(t *testing.T) {
+	t.Parallel()
+
+	p := &memoryPublisher{}
+	h := &webhookHandler{publisher: p}
+	ctx := context.Background()
+
+	require.NoError(t, h.publish(ctx, "subject", 8, Payload{
+		Op:    opCreate,
+		After: json.RawMessage(`{"subject_name":"n"}`),
+	}, subjectWebhookFields))
+
+	require.NoError(t, h.publish(ctx, "subject", 8, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"subject_name":"n","subject_wish":1}`),
+		After:  json.RawMessage(`{"subject_name":"n","subject_wish":2}`),
+	}, subjectWebhookFields))
+
+	require.NoError(t, h.publish(ctx, "subject", 8, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"subject_name":"n","field_summary":"a"}`),
+		After:  json.RawMessage(`{"subject_name":"m","field_summary":"b"}`),
+	}, subjectWebhookFields))
+
+	require.NoError(t, h.publish(ctx, "subject", 8, Payload{
+		Op:     opDelete,
+		Before: json.RawMessage(`{"subject_name":"m"}`),
+	}, subjectWebhookFields))
+
+	// 只修改收藏数不会发送事件
+	require.Equal(t, []webhook.Event{
+		{Type: webhook.EventSubjectCreated, EntityID: 8},
+		{Type: webhook.EventSubjectUpdated, EntityID: 8, Changed: []string{"name", "summary"}},
+		{Type: webhook.EventSubjectDeleted, EntityID: 8},
+	}, p.events)
+}
+
+func TestWebhookHandler_publish_ban(t *testing.T) {
+	t.Parallel()
+
+	p := &memoryPublisher{}
+	h := &webhookHandler{publisher: p}
+	ctx := context.Background()
+
+	require.NoError(t, h.publish(ctx, "character", 1, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"crt_name":"n","crt_ban":0}`),
+		After:  json.RawMessage(`{"crt_name":"n","crt_ban":1}`),
+	}, characterWebhookFields))
+
+	// 取消删除是一次修改
+	require.NoError(t, h.publish(ctx, "person", 2, Payload{
+		Op:     opUpdate,
+		Before: json.RawMessage(`{"prsn_name":"n","prsn_ban":1}`),
+		After:  json.RawMessage(`{"prsn_name":"n","prsn_ban":0}`),
+	}, personWebhookFields))
+
+	require.Equal(t, []webhook.Event{
+		{Type: webhook.EventCharacterDeleted, EntityID: 1},
+		{Type: webhook.EventPersonUpdated, EntityID: 2, Changed: []string{"deleted"}},
+	}, p.events)
+}
@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// 每个 worker 排队的任务数量，队列满了之后读取消息会被阻塞.
+const workerQueueSize = 64
+
+// keyedPool 按照 key 把任务分配给固定的 worker，
+// 同一个 key（比如同一个条目）的消息按顺序处理，不同 key 的消息并行处理，key 见 [shardKey].
+type keyedPool struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+}
+
+func newKeyedPool(concurrency int) *keyedPool {
+	p := &keyedPool{queues: make([]chan func(), max(concurrency, 1))}
+	for i := range p.queues {
+		q := make(chan func(), workerQueueSize)
+		p.queues[i] = q
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for fn := range q {
+				fn()
+			}
+		}()
+	}
+
+	return p
+}
+
+func (p *keyedPool) submit(key []byte, fn func()) {
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+
+	p.queues[h.Sum32()%uint32(len(p.queues))] <- fn
+}
+
+// close 等待已经提交的任务全部完成.
+func (p *keyedPool) close() {
+	for _, q := range p.queues {
+		close(q)
+	}
+
+	p.wg.Wait()
+}
+
+// shardEntity 是一个表的消息 key 中实体 id 的字段.
+type shardEntity struct {
+	name  string
+	field string
+}
+
+// shardEntities 中的表按照实体分配 worker，比如 chii_subjects 和 chii_subject_fields 中同一个条目的修改会按顺序处理.
+//
+//nolint:gochecknoglobals
+var shardEntities = map[string]shardEntity{
+	"chii_subjects":          {name: "subject", field: "subject_id"},
+	"chii_subject_fields":    {name: "subject", field: "field_sid"},
+	"chii_characters":        {name: "character", field: "crt_id"},
+	"chii_crt_subject_index": {name: "character", field: "crt_id"},
+	"chii_persons":           {name: "person", field: "prsn_id"},
+	"chii_person_cs_index":   {name: "person", field: "prsn_id"},
+	"chii_crt_cast_index":    {name: "person", field: "prsn_id"},
+	"chii_index":             {name: "index", field: "idx_id"},
+}
+
+// shardKey 返回分配 worker 使用的 key，topic 的最后一段是表名.
+// [shardEntities] 中的表使用 `{实体}:{id}`，其他表直接使用消息的 key，也就是表的主键.
+func shardKey(topic string, key []byte) []byte {
+	entity, ok := shardEntities[topic[strings.LastIndexByte(topic, '.')+1:]]
+	if !ok {
+		return key
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(key, &fields); err != nil {
+		return key
+	}
+
+	id, ok := fields[entity.field]
+	if !ok {
+		return key
+	}
+
+	return bytes.Join([][]byte{[]byte(entity.name), id}, []byte(":"))
+}
+
+type partition struct {
+	topic     string
+	partition int
+}
+
+// offsetTracker 记录每个 partition 中已经读取但还没有提交的消息.
+// 并行处理时后面的消息可能先完成，只有一个 partition 中之前的消息都完成后才能提交.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partition]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	// 按照读取顺序排列，kafka 保证同一个 partition 中的 offset 是递增的
+	pending []int64
+	done    map[int64]kafka.Message
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[partition]*partitionOffsets)}
+}
+
+func (t *offsetTracker) start(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := partition{topic: msg.Topic, partition: msg.Partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{done: make(map[int64]kafka.Message)}
+		t.partitions[key] = p
+	}
+
+	p.pending = append(p.pending, msg.Offset)
+}
+
+// done 标记消息已经完成，返回可以提交的最大的消息.
+// 返回 false 时这个 partition 中还有更早的消息没有完成.
+func (t *offsetTracker) done(msg kafka.Message) (kafka.Message, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[partition{topic: msg.Topic, partition: msg.Partition}]
+	if !ok {
+		return kafka.Message{}, false
+	}
+
+	p.done[msg.Offset] = msg
+
+	var commit kafka.Message
+	var n int
+	for _, offset := range p.pending {
+		m, ok := p.done[offset]
+		if !ok {
+			break
+		}
+
+		delete(p.done, offset)
+		commit = m
+		n++
+	}
+
+	if n == 0 {
+		return kafka.Message{}, false
+	}
+
+	p.pending = p.pending[n:]
+
+	return commit, true
+}
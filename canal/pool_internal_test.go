@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedPool_order(t *testing.T) {
+	t.Parallel()
+
+	p := newKeyedPool(4)
+
+	var mu sync.Mutex
+	processed := make(map[string][]int)
+	for i := range 100 {
+		key := strconv.Itoa(i % 7)
+		p.submit([]byte(key), func() {
+			mu.Lock()
+			defer mu.Unlock()
+			processed[key] = append(processed[key], i)
+		})
+	}
+
+	p.close()
+
+	require.Len(t, processed, 7)
+	for key, values := range processed {
+		require.IsIncreasing(t, values, key)
+	}
+}
+
+func TestShardKey(t *testing.T) {
+	t.Parallel()
+
+	// 同一个条目的两个表使用相同的 key
+	require.Equal(t, []byte("subject:8"), shardKey("debezium.bangumi.chii_subjects", []byte(`{"subject_id":8}`)))
+	require.Equal(t, []byte("subject:8"), shardKey("debezium.bangumi.chii_subject_fields", []byte(`{"field_sid":8}`)))
+
+	require.Equal(t, []byte(`{"uid":8}`), shardKey("debezium.bangumi.chii_members", []byte(`{"uid":8}`)))
+	require.Equal(t, []byte("null"), shardKey("debezium.bangumi.chii_subjects", []byte("null")))
+}
+
+func TestOffsetTracker(t *testing.T) {
+	t.Parallel()
+
+	msg := func(partition int, offset int64) kafka.Message {
+		return kafka.Message{Topic: "t", Partition: partition, Offset: offset}
+	}
+
+	tracker := newOffsetTracker()
+	for offset := range int64(4) {
+		tracker.start(msg(0, offset))
+	}
+	tracker.start(msg(1, 10))
+
+	// 前面的消息还没有完成
+	_, ok := tracker.done(msg(0, 2))
+	require.False(t, ok)
+	_, ok = tracker.done(msg(0, 1))
+	require.False(t, ok)
+
+	commit, ok := tracker.done(msg(0, 0))
+	require.True(t, ok)
+	require.EqualValues(t, 2, commit.Offset)
+
+	// 不同的 partition 互不影响
+	commit, ok = tracker.done(msg(1, 10))
+	require.True(t, ok)
+	require.EqualValues(t, 10, commit.Offset)
+
+	commit, ok = tracker.done(msg(0, 3))
+	require.True(t, ok)
+	require.EqualValues(t, 3, commit.Offset)
+}
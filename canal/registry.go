@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/trim21/errgo"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+//nolint:gochecknoglobals
+var handlerEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Subsystem: "chii",
+	Name:      "canal_handler_events_total",
+	Help:      "binlog events processed by canal table handlers",
+}, []string{"handler", "table", "result"})
+
+//nolint:gochecknoinits
+func init() {
+	prometheus.MustRegister(handlerEvents)
+}
+
+// Image 表示 handler 需要 debezium 消息中的哪些行数据.
+type Image uint8
+
+const (
+	// ImageBefore 表示需要修改前的数据，只对更新和删除有效.
+	ImageBefore Image = 1 << iota
+	// ImageAfter 表示需要修改后的数据，只对创建和更新有效.
+	ImageAfter
+)
+
+// HandlerInfo 描述 handler 处理的表和操作.
+type HandlerInfo struct {
+	Name   string
+	Tables []string
+	// Ops 为空时处理所有操作
+	Ops    []string
+	Images Image
+	// NoReplay 为 true 时 `canal replay` 不会执行这个 handler，用于会修改业务数据的 handler
+	NoReplay bool
+}
+
+// TableHandler 处理一个或多个表的 binlog 事件，通过 fx group `canal_handlers` 注册到 [Registry].
+// 一般使用 [NewHandler] 创建.
+type TableHandler interface {
+	Info() HandlerInfo
+	Handle(ctx context.Context, key json.RawMessage, payload Payload) error
+}
+
+// HandlerSpec 定义一个 handler，K 是消息 key 的类型，也就是表的主键.
+type HandlerSpec[K any] struct {
+	Handle func(ctx context.Context, key K, payload Payload) error
+	HandlerInfo
+}
+
+func NewHandler[K any](spec HandlerSpec[K]) TableHandler {
+	return handler[K]{spec: spec}
+}
+
+type handler[K any] struct {
+	spec HandlerSpec[K]
+}
+
+func (h handler[K]) Info() HandlerInfo {
+	return h.spec.HandlerInfo
+}
+
+func (h handler[K]) Handle(ctx context.Context, key json.RawMessage, payload Payload) error {
+	var k K
+	if err := json.Unmarshal(key, &k); err != nil {
+		return errgo.Wrap(err, "failed to decode key")
+	}
+
+	return h.spec.Handle(ctx, k, payload)
+}
+
+// asHandlers 把返回 []TableHandler 的构造函数注册到 fx group `canal_handlers`.
+func asHandlers(constructor any) any {
+	return fx.Annotate(constructor, fx.ResultTags(`group:"canal_handlers,flatten"`))
+}
+
+// Registry 按表名把 binlog 事件分发给对应的 handler.
+// 同一个事件的多个 handler 互相独立，一个 handler 失败不会影响其他 handler，重试时只会重新执行失败的 handler，见 [HandlerError].
+//
+// 消息至少会被处理一次，进程在提交之前退出时所有 handler 都会重新执行，所以 handler 需要是幂等的.
+type Registry struct {
+	log    *zap.Logger
+	tables map[string][]TableHandler
+}
+
+type registryParams struct {
+	fx.In
+
+	Log      *zap.Logger
+	Handlers []TableHandler `group:"canal_handlers"`
+}
+
+func newRegistry(p registryParams) (*Registry, error) {
+	return NewRegistry(p.Log, p.Handlers...)
+}
+
+func NewRegistry(log *zap.Logger, handlers ...TableHandler) (*Registry, error) {
+	r := &Registry{log: log.Named("canal.registry"), tables: make(map[string][]TableHandler)}
+
+	names := make(map[string]bool, len(handlers))
+	for _, h := range handlers {
+		info := h.Info()
+		if info.Name == "" || len(info.Tables) == 0 {
+			return nil, fmt.Errorf("canal handler %q should have name and tables", info.Name)
+		}
+
+		if names[info.Name] {
+			return nil, fmt.Errorf("duplicated canal handler %q", info.Name)
+		}
+		names[info.Name] = true
+
+		for _, op := range info.Ops {
+			if !slices.Contains([]string{opCreate, opUpdate, opDelete, opSnapshot}, op) {
+				return nil, fmt.Errorf("canal handler %q has unknown op %q", info.Name, op)
+			}
+		}
+
+		for _, table := range info.Tables {
+			r.tables[table] = append(r.tables[table], h)
+		}
+	}
+
+	return r, nil
+}
+
+// withoutNoReplay 返回去掉了 [HandlerInfo.NoReplay] 的 handler 的 Registry，用于 [Replay].
+func (r *Registry) withoutNoReplay() *Registry {
+	tables := make(map[string][]TableHandler, len(r.tables))
+	for table, handlers := range r.tables {
+		for _, h := range handlers {
+			if h.Info().NoReplay {
+				r.log.Info("skip handler in replay", zap.String("handler", h.Info().Name))
+				continue
+			}
+
+			tables[table] = append(tables[table], h)
+		}
+	}
+
+	return &Registry{log: r.log, tables: tables}
+}
+
+// Tables 返回有 handler 的表.
+func (r *Registry) Tables() []string {
+	tables := make([]string, 0, len(r.tables))
+	for table := range r.tables {
+		tables = append(tables, table)
+	}
+	slices.Sort(tables)
+
+	return tables
+}
+
+// HandlerError 是 [Registry.Dispatch] 返回的错误，Failed 是失败的 handler.
+// 重试时只需要把 Failed 交给 Dispatch，已经成功的 handler 不会再次处理这个事件.
+type HandlerError struct {
+	err    error
+	Failed []string
+}
+
+func (e *HandlerError) Error() string {
+	return e.err.Error()
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.err
+}
+
+// failedHandlers 返回 err 中失败的 handler，err 不是 [HandlerError] 时返回 nil，表示需要重新执行所有 handler.
+func failedHandlers(err error) []string {
+	var e *HandlerError
+	if errors.As(err, &e) {
+		return e.Failed
+	}
+
+	return nil
+}
+
+// Dispatch 把事件交给所有处理这个表和操作的 handler，失败时返回 [HandlerError].
+// only 不为空时只交给其中的 handler，用于重试之前失败的 handler.
+func (r *Registry) Dispatch(ctx context.Context, key json.RawMessage, payload Payload, only ...string) error {
+	table := payload.Source.Table
+
+	var errs []error
+	var failed []string
+	for _, h := range r.tables[table] {
+		info := h.Info()
+		if len(info.Ops) != 0 && !slices.Contains(info.Ops, payload.Op) {
+			continue
+		}
+
+		if len(only) != 0 && !slices.Contains(only, info.Name) {
+			continue
+		}
+
+		err := r.handle(ctx, h, key, payload)
+		if err != nil {
+			handlerEvents.WithLabelValues(info.Name, table, "error").Inc()
+			r.log.Error("canal handler failed", zap.String("handler", info.Name),
+				zap.String("table", table), zap.String("op", payload.Op), zap.Error(err))
+			errs = append(errs, errgo.Wrap(err, info.Name))
+			failed = append(failed, info.Name)
+			continue
+		}
+
+		handlerEvents.WithLabelValues(info.Name, table, "ok").Inc()
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return &HandlerError{err: errors.Join(errs...), Failed: failed}
+}
+
+func (r *Registry) handle(ctx context.Context, h TableHandler, key json.RawMessage, payload Payload) (err error) {
+	start := time.Now()
+	defer func() {
+		handlerDuration.WithLabelValues(h.Info().Name).Observe(time.Since(start).Seconds())
+	}()
+
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+
+	if err := checkImages(h.Info().Images, payload); err != nil {
+		return err
+	}
+
+	return h.Handle(ctx, key, payload)
+}
+
+func checkImages(images Image, payload Payload) error {
+	if images&ImageBefore != 0 && (payload.Op == opUpdate || payload.Op == opDelete) && isNullImage(payload.Before) {
+		return errors.New("missing before image")
+	}
+
+	if images&ImageAfter != 0 && payload.Op != opDelete && isNullImage(payload.After) {
+		return errors.New("missing after image")
+	}
+
+	return nil
+}
+
+func isNullImage(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null"))
+}
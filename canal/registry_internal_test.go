@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/logger"
+)
+
+func TestRegistry_Dispatch(t *testing.T) {
+	t.Parallel()
+
+	var subjects []model.SubjectID
+	var updated int
+
+	r, err := NewRegistry(logger.Copy(),
+		NewHandler(HandlerSpec[SubjectKey]{
+			HandlerInfo: HandlerInfo{Name: "failed", Tables: []string{"chii_subjects"}},
+			Handle: func(context.Context, SubjectKey, Payload) error {
+				return errors.New("fail")
+			},
+		}),
+		NewHandler(HandlerSpec[SubjectKey]{
+			HandlerInfo: HandlerInfo{Name: "panic", Tables: []string{"chii_subjects"}},
+			Handle: func(context.Context, SubjectKey, Payload) error {
+				panic("boom")
+			},
+		}),
+		NewHandler(HandlerSpec[SubjectKey]{
+			HandlerInfo: HandlerInfo{Name: "subject", Tables: []string{"chii_subjects"}},
+			Handle: func(_ context.Context, k SubjectKey, _ Payload) error {
+				subjects = append(subjects, k.ID)
+				return nil
+			},
+		}),
+		NewHandler(HandlerSpec[SubjectKey]{
+			HandlerInfo: HandlerInfo{
+				Name:   "update",
+				Tables: []string{"chii_subjects"},
+				Ops:    []string{opUpdate},
+				Images: ImageBefore | ImageAfter,
+			},
+			Handle: func(context.Context, SubjectKey, Payload) error {
+				updated++
+				return nil
+			},
+		}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"chii_subjects"}, r.Tables())
+
+	key := json.RawMessage(`{"subject_id":8}`)
+	payload := Payload{Op: opCreate, After: json.RawMessage(`{}`), Source: source{Table: "chii_subjects"}}
+
+	// 失败和 panic 的 handler 不影响其他 handler
+	err = r.Dispatch(context.Background(), key, payload)
+	require.ErrorContains(t, err, "failed: fail")
+	require.ErrorContains(t, err, "panic: panic: boom")
+	require.Equal(t, []string{"failed", "panic"}, failedHandlers(err))
+	require.Equal(t, []model.SubjectID{8}, subjects)
+	require.Zero(t, updated)
+
+	// 重试时只执行失败的 handler
+	err = r.Dispatch(context.Background(), key, payload, failedHandlers(err)...)
+	require.Equal(t, []string{"failed", "panic"}, failedHandlers(err))
+	require.Equal(t, []model.SubjectID{8}, subjects)
+
+	payload.Op = opUpdate
+	_ = r.Dispatch(context.Background(), key, payload)
+	require.Zero(t, updated, "should skip update without before image")
+
+	payload.Before = json.RawMessage(`{}`)
+	_ = r.Dispatch(context.Background(), key, payload)
+	require.Equal(t, 1, updated)
+
+	payload = Payload{Op: opCreate, Source: source{Table: "chii_persons"}}
+	require.NoError(t, r.Dispatch(context.Background(), key, payload), "tables without handler should be ignored")
+}
+
+func TestNewRegistry_invalid(t *testing.T) {
+	t.Parallel()
+
+	h := NewHandler(HandlerSpec[SubjectKey]{
+		HandlerInfo: HandlerInfo{Name: "subject", Tables: []string{"chii_subjects"}},
+		Handle:      func(context.Context, SubjectKey, Payload) error { return nil },
+	})
+
+	_, err := NewRegistry(logger.Copy(), h, h)
+	require.Error(t, err)
+
+	_, err = NewRegistry(logger.Copy(), NewHandler(HandlerSpec[SubjectKey]{
+		HandlerInfo: HandlerInfo{Name: "subject", Tables: []string{"chii_subjects"}, Ops: []string{"x"}},
+	}))
+	require.Error(t, err)
+}
+
+func TestRegistry_withoutNoReplay(t *testing.T) {
+	t.Parallel()
+
+	var called []string
+	newHandler := func(name string, noReplay bool) TableHandler {
+		return NewHandler(HandlerSpec[SubjectKey]{
+			HandlerInfo: HandlerInfo{Name: name, Tables: []string{"chii_subject_interests"}, NoReplay: noReplay},
+			Handle: func(context.Context, SubjectKey, Payload) error {
+				called = append(called, name)
+				return nil
+			},
+		})
+	}
+
+	r, err := NewRegistry(logger.Copy(), newHandler("counter", true), newHandler("search", false))
+	require.NoError(t, err)
+
+	payload := Payload{Op: opCreate, After: json.RawMessage(`{}`), Source: source{Table: "chii_subject_interests"}}
+	require.NoError(t, r.withoutNoReplay().Dispatch(context.Background(), json.RawMessage(`{}`), payload))
+	require.Equal(t, []string{"search"}, called)
+}
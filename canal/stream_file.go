@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+)
+
+const fileTopic = "file"
+
+// fileRecord 是文件中的一条消息，key 和 value 与 kafka 中的消息相同.
+//
+//	{"topic": "debezium.bangumi.chii_subjects", "key": {"subject_id": 8}, "value": {"op": "u", ...}}
+//
+// 没有 value 字段时整个对象作为 value，key 使用 after 或者 before 中的数据，
+// 所以可以直接读取 debezium 的消息，比如 `canal/example` 中的文件.
+type fileRecord struct {
+	Topic string          `json:"topic"`
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// envelope 是开启 schema 时 debezium 的消息格式，读取时只保留 payload.
+type envelope struct {
+	Schema  json.RawMessage `json:"schema"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// NewFileStream 从 r 中读取 json 消息，可以每行一条，也可以是多个格式化的 json 对象.
+// 读取完毕后 Read 返回，处理失败的消息不会重试.
+func NewFileStream(r io.Reader, log *zap.Logger) Stream {
+	return &fileStream{r: r, log: log.Named("canal.stream.file")}
+}
+
+type fileStream struct {
+	r      io.Reader
+	log    *zap.Logger
+	closed atomic.Bool
+}
+
+func (s *fileStream) Read(_ context.Context, onMessage func(msg Msg) error) error {
+	dec := json.NewDecoder(s.r)
+
+	var total, failed int
+	for !s.closed.Load() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return errgo.Wrap(err, fmt.Sprintf("failed to decode message %d", total+1))
+		}
+
+		total++
+		msg, err := decodeFileRecord(raw)
+		if err != nil {
+			return errgo.Wrap(err, fmt.Sprintf("failed to decode message %d", total))
+		}
+		msg.ID = strconv.Itoa(total)
+
+		if err := onMessage(msg); err != nil {
+			failed++
+			s.log.Error("failed to process message", zap.String("id", msg.ID), zap.Error(err))
+		}
+	}
+
+	s.log.Info("file stream finished", zap.Int("total", total), zap.Int("failed", failed))
+	if failed != 0 {
+		return fmt.Errorf("%d of %d messages failed", failed, total)
+	}
+
+	return nil
+}
+
+func decodeFileRecord(raw json.RawMessage) (Msg, error) {
+	var r fileRecord
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return Msg{}, errgo.Wrap(err, "json.Unmarshal")
+	}
+
+	if r.Value == nil {
+		r.Value = raw
+	}
+
+	value, err := unwrapEnvelope(r.Value)
+	if err != nil {
+		return Msg{}, err
+	}
+
+	key, err := unwrapEnvelope(r.Key)
+	if err != nil {
+		return Msg{}, err
+	}
+
+	if key == nil && value != nil {
+		var p Payload
+		if err := json.Unmarshal(value, &p); err != nil {
+			return Msg{}, errgo.Wrap(err, "json.Unmarshal")
+		}
+
+		// 行数据中包含主键，handler 只会解析 key 中需要的字段
+		key = p.After
+		if isNullImage(key) {
+			key = p.Before
+		}
+	}
+
+	if r.Topic == "" {
+		r.Topic = fileTopic
+	}
+
+	return Msg{Topic: r.Topic, Key: key, Value: value}, nil
+}
+
+// unwrapEnvelope 去掉 debezium 的 schema，null 表示 tombstone 消息，返回 nil.
+func unwrapEnvelope(raw json.RawMessage) ([]byte, error) {
+	if isNullImage(raw) {
+		return nil, nil
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return raw, nil
+	}
+
+	var e envelope
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return nil, errgo.Wrap(err, "json.Unmarshal")
+	}
+
+	if e.Schema != nil && e.Payload != nil {
+		return unwrapEnvelope(e.Payload)
+	}
+
+	return raw, nil
+}
+
+func (s *fileStream) Close() error {
+	s.closed.Store(true)
+	return nil
+}
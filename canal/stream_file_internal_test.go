@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/mocks"
+	"github.com/bangumi/server/internal/pkg/logger"
+	"github.com/bangumi/server/internal/search"
+)
+
+func TestDecodeFileRecord(t *testing.T) {
+	t.Parallel()
+
+	msg, err := decodeFileRecord([]byte(`{"topic":"t","key":{"schema":{},"payload":{"subject_id":8}},"value":null}`))
+	require.NoError(t, err)
+	require.Equal(t, "t", msg.Topic)
+	require.JSONEq(t, `{"subject_id":8}`, string(msg.Key))
+	require.Nil(t, msg.Value)
+
+	// 没有 key 时使用行数据
+	msg, err = decodeFileRecord([]byte(`{"op":"d","before":{"subject_id":8},"after":null}`))
+	require.NoError(t, err)
+	require.Equal(t, fileTopic, msg.Topic)
+	require.JSONEq(t, `{"subject_id":8}`, string(msg.Key))
+}
+
+func TestFileStream_example(t *testing.T) {
+	t.Parallel()
+
+	s := mocks.NewSearchClient(t)
+	s.EXPECT().EventAdded(mock.Anything, uint32(15466), search.SearchTargetIndex).Return(nil).Once()
+	s.EXPECT().EventDelete(mock.Anything, uint32(15466), search.SearchTargetIndex).Return(nil).Once()
+
+	registry, err := NewRegistry(logger.Copy(), newIndexHandlers(newSearchHandler(s, logger.Copy()))...)
+	require.NoError(t, err)
+
+	var files []io.Reader
+	for _, name := range []string{"c.json", "u.json", "d.json"} {
+		f, err := os.Open("example/" + name)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = f.Close() })
+		files = append(files, f)
+	}
+
+	h := newEventHandler(logger.Copy(), NewFileStream(io.MultiReader(files...), logger.Copy()), registry, s)
+	require.NoError(t, h.start())
+}
+
+func TestFileStream_failed(t *testing.T) {
+	t.Parallel()
+
+	input := `{"key":{"idx_id":1},"value":{"op":"u","after":{},"source":{"table":"chii_index"}}}
+{"key":{"idx_id":2},"value":{"op":"u","after":{},"source":{"table":"chii_index"}}}
+`
+
+	var ids []string
+	stream := NewFileStream(strings.NewReader(input), logger.Copy())
+	err := stream.Read(context.Background(), func(msg Msg) error {
+		ids = append(ids, msg.ID)
+		if msg.ID == "1" {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	})
+
+	require.EqualError(t, err, "1 of 2 messages failed")
+	require.Equal(t, []string{"1", "2"}, ids)
+}
@@ -19,9 +19,13 @@ import (
 	"errors"
 	"io"
 	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/segmentio/kafka-go"
+	"github.com/trim21/errgo"
 	"go.uber.org/zap"
 
 	"github.com/bangumi/server/config"
@@ -35,30 +39,95 @@ func newKafkaStream(cfg config.AppConfig) Stream {
 		Brokers:     []string{cfg.Kafka.Broker},
 		GroupID:     groupID,
 		GroupTopics: cfg.Kafka.Topics,
+		// 只在 partition 没有提交过 offset 时使用，比如新加入的 topic.
+		// 历史消息不需要处理：搜索索引在首次启动时导入，收藏数量使用 `collections reconcile` 修正
+		StartOffset: kafka.LastOffset,
 	})
 
+	if err := prometheus.Register(newReaderCollector(k)); err != nil {
+		logger.Warn("failed to register kafka reader metrics", zap.Error(err))
+	}
+
+	dlq := &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Kafka.Broker),
+		Topic:                  cfg.Kafka.DLQTopic,
+		Balancer:               &kafka.Hash{},
+		AllowAutoTopicCreation: true,
+	}
+
 	var ch = make(chan Msg, 1)
+	done, stop := context.WithCancel(context.Background())
 
 	return &kafkaStream{
-		log: logger.Named("canal.search.stream.kafka"),
-		k:   k,
-		ch:  ch,
+		log:         logger.Named("canal.search.stream.kafka"),
+		k:           k,
+		dlq:         dlq,
+		ch:          ch,
+		done:        done,
+		stop:        stop,
+		concurrency: max(cfg.Canal.Concurrency, 1),
+		maxAttempts: max(cfg.Kafka.MaxAttempts, 1),
+		backoff:     cfg.Kafka.RetryBackoff,
+		maxBackoff:  cfg.Kafka.MaxRetryBackoff,
 	}
 }
 
 type kafkaStream struct {
 	log    *zap.Logger
 	k      *kafka.Reader
+	dlq    messageWriter
 	ch     chan Msg
 	closed atomic.Bool
+
+	// done 在 Close 时取消，用于打断重试的等待
+	done context.Context
+	stop context.CancelFunc
+
+	// 同时处理消息的 worker 数量，见 [keyedPool]
+	concurrency int
+
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+
+	// lags 是每个 partition 最后一次读取消息时的 lag
+	lags sync.Map
 }
 
-func (s *kafkaStream) Read(ctx context.Context, onMessage func(msg Msg) error) error {
-	for {
-		if s.closed.Load() {
-			return nil
-		}
+// Lag 返回所有 partition 最后一次读取消息时的 lag 之和.
+func (s *kafkaStream) Lag() int64 {
+	var lag int64
+	s.lags.Range(func(_, value any) bool {
+		lag += value.(int64) //nolint:forcetypeassert
+		return true
+	})
+
+	return lag
+}
 
+func (s *kafkaStream) Read(ctx context.Context, onMessage func(msg Msg) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(s.done, cancel)()
+
+	pool := newKeyedPool(s.concurrency)
+	tracker := newOffsetTracker()
+
+	commits := make(chan kafka.Message, workerQueueSize)
+	committed := make(chan struct{})
+	go func() {
+		defer close(committed)
+		// 关闭时 ctx 会被取消，已经处理完的消息仍然需要提交
+		s.commit(context.WithoutCancel(ctx), commits)
+	}()
+
+	defer func() {
+		pool.close()
+		close(commits)
+		<-committed
+	}()
+
+	for !s.closed.Load() {
 		msg, err := s.k.FetchMessage(ctx)
 		if err != nil {
 			if errors.Is(err, io.EOF) || utils.IsNetworkError(err) {
@@ -71,16 +140,33 @@ func (s *kafkaStream) Read(ctx context.Context, onMessage func(msg Msg) error) e
 		}
 
 		s.log.Debug("new message", zap.String("topic", msg.Topic))
+		s.lags.Store(partition{topic: msg.Topic, partition: msg.Partition}, observeLag(msg))
+
+		tracker.start(msg)
+		pool.submit(shardKey(msg.Topic, msg.Key), func() {
+			// 没有处理成功也没有写入 DLQ 的消息不能提交，否则之后的提交会跳过这条消息
+			if err := s.process(ctx, msg, onMessage); err != nil {
+				if !s.closed.Load() {
+					s.log.Error("failed to process kafka message", zap.Error(err))
+				}
+				return
+			}
 
-		m := Msg{
-			ID:    strconv.FormatInt(msg.Offset, 10),
-			Topic: msg.Topic,
-			Key:   msg.Key,
-			Value: msg.Value,
-		}
+			if m, ok := tracker.done(msg); ok {
+				commits <- m
+			}
+		})
+	}
 
-		if err := onMessage(m); err != nil {
-			s.log.Error("failed to process kafak message", zap.Error(err))
+	return nil
+}
+
+// commit 按顺序提交 offset，多个 worker 发送的消息可能乱序，跳过比已经提交的 offset 更小的消息.
+func (s *kafkaStream) commit(ctx context.Context, commits <-chan kafka.Message) {
+	offsets := make(map[partition]int64)
+	for msg := range commits {
+		key := partition{topic: msg.Topic, partition: msg.Partition}
+		if offset, ok := offsets[key]; ok && msg.Offset <= offset {
 			continue
 		}
 
@@ -88,11 +174,73 @@ func (s *kafkaStream) Read(ctx context.Context, onMessage func(msg Msg) error) e
 			s.log.Error("failed to commit kafak message", zap.Error(err))
 			continue
 		}
+
+		offsets[key] = msg.Offset
+	}
+}
+
+// process 处理一条消息，失败时等待后重试，重试 maxAttempts 次后写入 DLQ.
+// 重试和 DLQ 只包括失败的 handler，见 [HandlerError].
+// 返回 nil 时消息可以提交.
+func (s *kafkaStream) process(ctx context.Context, msg kafka.Message, onMessage func(msg Msg) error) error {
+	m := Msg{
+		ID:    strconv.FormatInt(msg.Offset, 10),
+		Topic: msg.Topic,
+		Key:   msg.Key,
+		Value: msg.Value,
+		// 从 DLQ 重放的消息只交给之前失败的 handler
+		Handlers: messageHandlers(msg),
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = onMessage(m); err == nil {
+			return nil
+		}
+
+		if failed := failedHandlers(err); len(failed) != 0 {
+			m.Handlers = failed
+		}
+
+		if attempt >= s.maxAttempts {
+			break
+		}
+
+		wait := backoff(s.backoff, s.maxBackoff, attempt)
+		s.log.Warn("failed to process kafka message, retry later", zap.Error(err), zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset), zap.Int("attempt", attempt), zap.Duration("backoff", wait))
+		if err := sleep(ctx, wait); err != nil {
+			return errgo.Trace(err)
+		}
 	}
+
+	dlqMsg := newDLQMessage(msg, err, s.maxAttempts, time.Now())
+
+	// 写入 DLQ 失败时消息不能提交，一直重试直到成功
+	for attempt := 1; ; attempt++ {
+		werr := s.dlq.WriteMessages(ctx, dlqMsg)
+		if werr == nil {
+			break
+		}
+
+		s.log.Error("failed to write message to dlq", zap.Error(werr), zap.String("topic", msg.Topic),
+			zap.Int64("offset", msg.Offset))
+		if err := sleep(ctx, backoff(s.backoff, s.maxBackoff, attempt)); err != nil {
+			return errgo.Trace(err)
+		}
+	}
+
+	dlqMessages.WithLabelValues(msg.Topic).Inc()
+	s.log.Error("move kafka message to dlq", zap.Error(err), zap.String("topic", msg.Topic),
+		zap.Int64("offset", msg.Offset), zap.Int("attempts", s.maxAttempts))
+
+	return nil
 }
 
 func (s *kafkaStream) Close() error {
 	s.closed.Store(true)
+	s.stop()
 	close(s.ch)
-	return s.k.Close()
+
+	return errors.Join(s.k.Close(), s.dlq.Close())
 }
@@ -0,0 +1,407 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/rueidis"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/config"
+)
+
+const (
+	redisReadCount   = 100
+	redisReadBlock   = 5 * time.Second
+	redisClaimPeriod = 30 * time.Second
+	// 新建的 consumer group 从 stream 的末尾开始读取，和 kafka 的 StartOffset 相同
+	redisGroupStart = "$"
+)
+
+var errUnexpectedReply = errors.New("unexpected redis reply")
+
+// newRedisStream 使用 consumer group 读取 debezium server 的 redis sink 写入的消息.
+// sink 的每条消息只有一个字段，字段名是 kafka 消息的 key，值是 kafka 消息的 value.
+func newRedisStream(cfg config.AppConfig, redis rueidis.Client, log *zap.Logger) Stream {
+	// 使用固定的 consumer 名，重启后可以继续处理自己 pending 的消息
+	consumer, err := os.Hostname()
+	if err != nil {
+		consumer = groupID
+	}
+
+	done, stop := context.WithCancel(context.Background())
+
+	return &redisStream{
+		log:           log.Named("canal.stream.redis"),
+		redis:         redis,
+		streams:       cfg.Canal.RedisStreams,
+		consumer:      consumer,
+		claimIdle:     cfg.Canal.RedisClaimIdle,
+		maxDeliveries: int64(max(cfg.Canal.RedisMaxDeliveries, 1)),
+		dlqStream:     cfg.Canal.RedisDLQStream,
+		concurrency:   max(cfg.Canal.Concurrency, 1),
+		done:          done,
+		stop:          stop,
+	}
+}
+
+type redisStream struct {
+	log       *zap.Logger
+	redis     rueidis.Client
+	streams   []string
+	consumer  string
+	claimIdle time.Duration
+	closed    atomic.Bool
+
+	// 已经处理失败 maxDeliveries 次的消息在 claim 时写入 dlqStream
+	maxDeliveries int64
+	dlqStream     string
+
+	// inflight 是已经交给 worker 但是还没有处理完的消息，claim 时跳过这些消息，避免同一条消息被同时处理
+	inflight sync.Map
+	// failed 是处理失败的消息中失败的 handler，重新处理时只交给这些 handler.
+	// 只保存在内存中，被其他 consumer 接管或者重启后会交给所有 handler
+	failed sync.Map
+
+	// 同时处理消息的 worker 数量，见 [keyedPool]
+	concurrency int
+
+	// done 在 Close 时取消，用于打断阻塞的 XREADGROUP
+	done context.Context
+	stop context.CancelFunc
+}
+
+func (s *redisStream) Read(ctx context.Context, onMessage func(msg Msg) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(s.done, cancel)()
+
+	if err := s.createGroups(ctx); err != nil {
+		return err
+	}
+
+	pool := newKeyedPool(s.concurrency)
+	defer pool.close()
+
+	// 关闭时 ctx 会被取消，已经处理完的消息仍然需要 ack
+	ackCtx := context.WithoutCancel(ctx)
+	dispatch := func(stream string, entry rueidis.XRangeEntry) {
+		msg := redisEntryToMsg(stream, entry)
+		msg.Handlers = s.failedHandlers(stream, entry.ID)
+		s.inflight.Store(redisEntryKey{stream: stream, id: entry.ID}, struct{}{})
+		pool.submit(shardKey(msg.Topic, msg.Key), func() { s.handle(ackCtx, msg, onMessage) })
+	}
+
+	var lastClaim time.Time
+	for !s.closed.Load() {
+		if time.Since(lastClaim) >= redisClaimPeriod {
+			lastClaim = time.Now()
+			for _, stream := range s.streams {
+				if err := s.claim(ctx, stream, dispatch); err != nil && !s.closed.Load() {
+					s.log.Error("failed to claim pending messages", zap.String("stream", stream), zap.Error(err))
+				}
+			}
+		}
+
+		if err := s.read(ctx, dispatch); err != nil && !s.closed.Load() {
+			s.log.Error("failed to read redis stream", zap.Error(err))
+			_ = sleep(ctx, time.Second)
+		}
+	}
+
+	return nil
+}
+
+func (s *redisStream) createGroups(ctx context.Context) error {
+	for _, stream := range s.streams {
+		err := s.redis.Do(ctx, s.redis.B().XgroupCreate().Key(stream).Group(groupID).
+			Id(redisGroupStart).Mkstream().Build()).Error()
+		if err != nil && !rueidis.IsRedisBusyGroup(err) {
+			return errgo.Wrap(err, "failed to create consumer group for "+stream)
+		}
+	}
+
+	return nil
+}
+
+func (s *redisStream) read(ctx context.Context, dispatch func(string, rueidis.XRangeEntry)) error {
+	ids := make([]string, len(s.streams))
+	for i := range ids {
+		ids[i] = ">"
+	}
+
+	cmd := s.redis.B().Xreadgroup().Group(groupID, s.consumer).
+		Count(redisReadCount).Block(redisReadBlock.Milliseconds()).
+		Streams().Key(s.streams...).Id(ids...).Build()
+
+	result, err := s.redis.Do(ctx, cmd).AsXRead()
+	if err != nil {
+		if rueidis.IsRedisNil(err) {
+			return nil
+		}
+
+		return errgo.Wrap(err, "xreadgroup")
+	}
+
+	for stream, entries := range result {
+		for _, entry := range entries {
+			dispatch(stream, entry)
+		}
+	}
+
+	return nil
+}
+
+// redisPending 是 XPENDING 返回的一条消息，Deliveries 是这条消息被读取的次数.
+type redisPending struct {
+	ID         string
+	Deliveries int64
+}
+
+// claim 使用 XAUTOCLAIM 重新处理超过 claimIdle 没有 ack 的消息，包括之前处理失败的消息和已经退出的 consumer 没有处理完的消息.
+// 正在处理的消息会被跳过，已经处理失败 maxDeliveries 次的消息写入 DLQ.
+func (s *redisStream) claim(ctx context.Context, stream string, dispatch func(string, rueidis.XRangeEntry)) error {
+	minIdle := strconv.FormatInt(s.claimIdle.Milliseconds(), 10)
+	cursor := "0-0"
+	for {
+		values, err := s.redis.Do(ctx, s.redis.B().Xautoclaim().Key(stream).Group(groupID).Consumer(s.consumer).
+			MinIdleTime(minIdle).Start(cursor).Count(redisReadCount).Build()).ToArray()
+		if err != nil {
+			return errgo.Wrap(err, "xautoclaim")
+		}
+
+		next, entries, err := parseAutoClaim(values)
+		if err != nil {
+			return err
+		}
+
+		deliveries, err := s.deliveries(ctx, stream, entries)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			// 正在处理的消息也会被 XAUTOCLAIM 接管，但是仍然属于这个 consumer，不需要重新处理
+			if s.isInflight(stream, entry.ID) {
+				continue
+			}
+
+			// XAUTOCLAIM 接管时也会增加读取次数，所以已经处理失败的次数要减去这一次
+			if attempts := deliveries[entry.ID] - 1; attempts >= s.maxDeliveries {
+				if err := s.deadLetter(ctx, stream, entry, attempts); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			dispatch(stream, entry)
+		}
+
+		if next == "0-0" {
+			return nil
+		}
+
+		cursor = next
+	}
+}
+
+// parseAutoClaim 解析 XAUTOCLAIM 的返回值 [next, entries, deleted]，redis 6.2 中已经被删除的消息是 nil.
+func parseAutoClaim(values []rueidis.RedisMessage) (string, []rueidis.XRangeEntry, error) {
+	if len(values) < 2 {
+		return "", nil, errgo.Wrap(errUnexpectedReply, "xautoclaim")
+	}
+
+	next, err := values[0].ToString()
+	if err != nil {
+		return "", nil, errgo.Wrap(err, "xautoclaim cursor")
+	}
+
+	items, err := values[1].ToArray()
+	if err != nil {
+		return "", nil, errgo.Wrap(err, "xautoclaim entries")
+	}
+
+	entries := make([]rueidis.XRangeEntry, 0, len(items))
+	for _, v := range items {
+		entry, err := v.AsXRangeEntry()
+		if err != nil {
+			if rueidis.IsRedisNil(err) {
+				continue
+			}
+
+			return "", nil, errgo.Wrap(err, "xautoclaim entry")
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return next, entries, nil
+}
+
+// deliveries 使用 XPENDING 查询消息被读取的次数，XAUTOCLAIM 的返回值中没有这个数据.
+func (s *redisStream) deliveries(
+	ctx context.Context, stream string, entries []rueidis.XRangeEntry,
+) (map[string]int64, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	cmds := make(rueidis.Commands, len(entries))
+	for i, entry := range entries {
+		cmds[i] = s.redis.B().Xpending().Key(stream).Group(groupID).Start(entry.ID).End(entry.ID).Count(1).Build()
+	}
+
+	deliveries := make(map[string]int64, len(entries))
+	for _, resp := range s.redis.DoMulti(ctx, cmds...) {
+		values, err := resp.ToArray()
+		if err != nil {
+			return nil, errgo.Wrap(err, "xpending")
+		}
+
+		pending, err := parsePending(values)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range pending {
+			deliveries[p.ID] = p.Deliveries
+		}
+	}
+
+	return deliveries, nil
+}
+
+// deadLetter 把一直处理失败的消息写入 dlqStream 后 ack.
+// DLQ 中的字段和 kafka DLQ 的 header 相同，原消息的 key 和 value 保存在 key 和 value 字段中.
+func (s *redisStream) deadLetter(ctx context.Context, stream string, entry rueidis.XRangeEntry, attempts int64) error {
+	msg := redisEntryToMsg(stream, entry)
+	err := s.redis.Do(ctx, s.redis.B().Xadd().Key(s.dlqStream).Id("*").FieldValue().
+		FieldValue(headerTopic, stream).
+		FieldValue(headerOffset, entry.ID).
+		FieldValue(headerAttempts, strconv.FormatInt(attempts, 10)).
+		FieldValue(headerFailedAt, time.Now().UTC().Format(time.RFC3339)).
+		FieldValue(headerHandlers, strings.Join(s.failedHandlers(stream, entry.ID), ",")).
+		FieldValue("key", string(msg.Key)).
+		FieldValue("value", string(msg.Value)).Build()).Error()
+	if err != nil {
+		return errgo.Wrap(err, "xadd dlq")
+	}
+
+	dlqMessages.WithLabelValues(stream).Inc()
+	s.log.Error("move redis stream message to dlq",
+		zap.String("stream", stream), zap.String("id", entry.ID), zap.Int64("attempts", attempts))
+
+	s.failed.Delete(redisEntryKey{stream: stream, id: entry.ID})
+
+	err = s.redis.Do(ctx, s.redis.B().Xack().Key(stream).Group(groupID).Id(entry.ID).Build()).Error()
+	return errgo.Wrap(err, "xack")
+}
+
+// parsePending 解析 XPENDING 带有 start end count 参数时的返回值，每一项是 [id, consumer, idle, deliveries].
+func parsePending(values []rueidis.RedisMessage) ([]redisPending, error) {
+	pending := make([]redisPending, 0, len(values))
+	for _, v := range values {
+		fields, err := v.ToArray()
+		if err != nil {
+			return nil, errgo.Wrap(err, "xpending entry")
+		}
+
+		if len(fields) < 4 {
+			return nil, errgo.Wrap(errUnexpectedReply, "xpending entry")
+		}
+
+		id, err := fields[0].ToString()
+		if err != nil {
+			return nil, errgo.Wrap(err, "xpending id")
+		}
+
+		deliveries, err := fields[3].AsInt64()
+		if err != nil {
+			return nil, errgo.Wrap(err, "xpending deliveries")
+		}
+
+		pending = append(pending, redisPending{ID: id, Deliveries: deliveries})
+	}
+
+	return pending, nil
+}
+
+type redisEntryKey struct {
+	stream string
+	id     string
+}
+
+func (s *redisStream) failedHandlers(stream, id string) []string {
+	if v, ok := s.failed.Load(redisEntryKey{stream: stream, id: id}); ok {
+		return v.([]string) //nolint:forcetypeassert
+	}
+
+	return nil
+}
+
+func (s *redisStream) isInflight(stream, id string) bool {
+	_, ok := s.inflight.Load(redisEntryKey{stream: stream, id: id})
+	return ok
+}
+
+// handle 只在处理成功后 ack，处理失败的消息留在 pending 列表中，之后由 [redisStream.claim] 重试.
+// 每条消息单独 ack，所以并行处理时不需要像 kafka 一样等待之前的消息完成.
+func (s *redisStream) handle(ctx context.Context, msg Msg, onMessage func(Msg) error) {
+	defer s.inflight.Delete(redisEntryKey{stream: msg.Topic, id: msg.ID})
+
+	key := redisEntryKey{stream: msg.Topic, id: msg.ID}
+	if err := onMessage(msg); err != nil {
+		s.log.Error("failed to process redis stream message, retry later",
+			zap.String("stream", msg.Topic), zap.String("id", msg.ID), zap.Error(err))
+		if failed := failedHandlers(err); len(failed) != 0 {
+			s.failed.Store(key, failed)
+		}
+		return
+	}
+
+	s.failed.Delete(key)
+
+	if err := s.redis.Do(ctx, s.redis.B().Xack().Key(msg.Topic).Group(groupID).Id(msg.ID).Build()).Error(); err != nil {
+		s.log.Error("failed to ack redis stream message",
+			zap.String("stream", msg.Topic), zap.String("id", msg.ID), zap.Error(err))
+	}
+}
+
+func redisEntryToMsg(stream string, entry rueidis.XRangeEntry) Msg {
+	msg := Msg{ID: entry.ID, Topic: stream}
+	for key, value := range entry.FieldValues {
+		msg.Key = []byte(key)
+		msg.Value = []byte(value)
+	}
+
+	return msg
+}
+
+func (s *redisStream) Close() error {
+	s.closed.Store(true)
+	s.stop()
+
+	return nil
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/pkg/logger"
+	"github.com/bangumi/server/internal/pkg/test"
+)
+
+func TestRedisEntryToMsg(t *testing.T) {
+	t.Parallel()
+
+	msg := redisEntryToMsg("debezium.bangumi.chii_subjects", rueidis.XRangeEntry{
+		ID:          "1-0",
+		FieldValues: map[string]string{`{"subject_id":8}`: `{"op":"u"}`},
+	})
+
+	require.Equal(t, Msg{
+		ID:    "1-0",
+		Topic: "debezium.bangumi.chii_subjects",
+		Key:   []byte(`{"subject_id":8}`),
+		Value: []byte(`{"op":"u"}`),
+	}, msg)
+}
+
+func TestRedisStream_claim(t *testing.T) {
+	t.Parallel()
+
+	r := test.GetRedis(t)
+	ctx := context.Background()
+	stream := t.Name() + time.Now().String()
+	t.Cleanup(func() { r.Do(ctx, r.B().Del().Key(stream).Build()) })
+
+	s := &redisStream{
+		log:           logger.Copy(),
+		redis:         r,
+		streams:       []string{stream},
+		consumer:      "test",
+		claimIdle:     10 * time.Millisecond,
+		maxDeliveries: 5,
+	}
+	require.NoError(t, s.createGroups(ctx))
+	// 重复创建 consumer group 不会失败
+	require.NoError(t, s.createGroups(ctx))
+
+	require.NoError(t, r.Do(ctx, r.B().Xadd().Key(stream).Id("*").FieldValue().
+		FieldValue(`{"subject_id":8}`, `{"op":"u"}`).Build()).Error())
+
+	handle := func(onMessage func(Msg) error) func(string, rueidis.XRangeEntry) {
+		return func(stream string, entry rueidis.XRangeEntry) {
+			s.handle(ctx, redisEntryToMsg(stream, entry), onMessage)
+		}
+	}
+
+	// 处理失败的消息不会 ack
+	require.NoError(t, s.read(ctx, handle(func(Msg) error { return errors.New("fail") })))
+
+	pending, err := r.Do(ctx, r.B().Xpending().Key(stream).Group(groupID).Build()).ToArray()
+	require.NoError(t, err)
+	count, err := pending[0].AsInt64()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+
+	time.Sleep(20 * time.Millisecond)
+
+	var msgs []Msg
+	require.NoError(t, s.claim(ctx, stream, handle(func(msg Msg) error {
+		msgs = append(msgs, msg)
+		return nil
+	})))
+	require.Len(t, msgs, 1)
+	require.Equal(t, []byte(`{"subject_id":8}`), msgs[0].Key)
+
+	pending, err = r.Do(ctx, r.B().Xpending().Key(stream).Group(groupID).Build()).ToArray()
+	require.NoError(t, err)
+	count, err = pending[0].AsInt64()
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestRedisStream_claim_skip_inflight(t *testing.T) {
+	t.Parallel()
+
+	r := test.GetRedis(t)
+	ctx := context.Background()
+	stream := t.Name() + time.Now().String()
+	t.Cleanup(func() { r.Do(ctx, r.B().Del().Key(stream).Build()) })
+
+	s := &redisStream{
+		log:           logger.Copy(),
+		redis:         r,
+		streams:       []string{stream},
+		consumer:      "test",
+		claimIdle:     10 * time.Millisecond,
+		maxDeliveries: 5,
+	}
+	require.NoError(t, s.createGroups(ctx))
+
+	require.NoError(t, r.Do(ctx, r.B().Xadd().Key(stream).Id("*").FieldValue().
+		FieldValue(`{"subject_id":8}`, `{"op":"u"}`).Build()).Error())
+
+	// 读取后还没有处理完
+	var id string
+	require.NoError(t, s.read(ctx, func(stream string, entry rueidis.XRangeEntry) {
+		id = entry.ID
+		s.inflight.Store(redisEntryKey{stream: stream, id: entry.ID}, struct{}{})
+	}))
+
+	time.Sleep(20 * time.Millisecond)
+
+	var claimed int
+	require.NoError(t, s.claim(ctx, stream, func(string, rueidis.XRangeEntry) { claimed++ }))
+	require.Zero(t, claimed)
+
+	// 处理完成后没有 ack 的消息可以被重新处理
+	s.handle(ctx, Msg{Topic: stream, ID: id}, func(Msg) error { return errors.New("fail") })
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, s.claim(ctx, stream, func(string, rueidis.XRangeEntry) { claimed++ }))
+	require.Equal(t, 1, claimed)
+}
+
+func TestRedisStream_claim_dlq(t *testing.T) {
+	t.Parallel()
+
+	r := test.GetRedis(t)
+	ctx := context.Background()
+	stream := t.Name() + time.Now().String()
+	dlq := stream + ":dlq"
+	t.Cleanup(func() { r.Do(ctx, r.B().Del().Key(stream, dlq).Build()) })
+
+	s := &redisStream{
+		log:           logger.Copy(),
+		redis:         r,
+		streams:       []string{stream},
+		consumer:      "test",
+		claimIdle:     10 * time.Millisecond,
+		maxDeliveries: 2,
+		dlqStream:     dlq,
+	}
+	require.NoError(t, s.createGroups(ctx))
+
+	require.NoError(t, r.Do(ctx, r.B().Xadd().Key(stream).Id("*").FieldValue().
+		FieldValue(`{"subject_id":8}`, `{"op":"u"}`).Build()).Error())
+
+	fail := func(stream string, entry rueidis.XRangeEntry) {
+		s.handle(ctx, redisEntryToMsg(stream, entry), func(Msg) error { return errors.New("fail") })
+	}
+
+	// 第 1 次读取和第 2 次 claim 都失败
+	require.NoError(t, s.read(ctx, fail))
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, s.claim(ctx, stream, fail))
+	time.Sleep(20 * time.Millisecond)
+
+	// 已经读取 2 次，写入 DLQ 并 ack
+	require.NoError(t, s.claim(ctx, stream, func(string, rueidis.XRangeEntry) {
+		t.Fatal("message should be moved to dlq")
+	}))
+
+	pending, err := r.Do(ctx, r.B().Xpending().Key(stream).Group(groupID).Build()).ToArray()
+	require.NoError(t, err)
+	count, err := pending[0].AsInt64()
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	entries, err := r.Do(ctx, r.B().Xrange().Key(dlq).Start("-").End("+").Build()).AsXRange()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, stream, entries[0].FieldValues[headerTopic])
+	require.Equal(t, "2", entries[0].FieldValues[headerAttempts])
+	require.Equal(t, `{"subject_id":8}`, entries[0].FieldValues["key"])
+	require.Equal(t, `{"op":"u"}`, entries[0].FieldValues["value"])
+}
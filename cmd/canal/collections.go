@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+//nolint:forbidigo
+package canal
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bangumi/server/canal"
+)
+
+var collectionsCommand = &cobra.Command{
+	Use:   "collections",
+	Short: "manage subject collection counters",
+}
+
+var collectionsReconcileArgs struct {
+	fromID    uint32
+	batchSize int
+	dryRun    bool
+}
+
+var collectionsReconcileCommand = &cobra.Command{
+	Use:   "reconcile",
+	Short: "recount subject collections and ratings, fix inconsistent counters",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := canal.ReconcileCollections(cmd.Context(), canal.ReconcileOptions{
+			FromID:    collectionsReconcileArgs.fromID,
+			BatchSize: collectionsReconcileArgs.batchSize,
+			DryRun:    collectionsReconcileArgs.dryRun,
+		})
+		fmt.Printf("%d subjects fixed\n", n)
+
+		return err
+	},
+}
+
+func init() {
+	collectionsReconcileCommand.Flags().Uint32Var(&collectionsReconcileArgs.fromID, "from-id", 0,
+		"start from this subject id")
+	collectionsReconcileCommand.Flags().IntVar(&collectionsReconcileArgs.batchSize, "batch-size", 1000,
+		"number of subjects to recount in one batch")
+	collectionsReconcileCommand.Flags().BoolVar(&collectionsReconcileArgs.dryRun, "dry-run", false,
+		"only print subjects with wrong counters")
+	collectionsCommand.AddCommand(collectionsReconcileCommand)
+	Command.AddCommand(collectionsCommand)
+}
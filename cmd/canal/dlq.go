@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+//nolint:forbidigo
+package canal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/canal"
+	"github.com/bangumi/server/config"
+	"github.com/bangumi/server/internal/pkg/logger"
+)
+
+var dlqCommand = &cobra.Command{
+	Use:   "dlq",
+	Short: "manage kafka messages failed to process",
+}
+
+var dlqReplayArgs struct {
+	idle time.Duration
+}
+
+var dlqReplayCommand = &cobra.Command{
+	Use:   "replay",
+	Short: "write messages in dead-letter topic back to their original topic",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.AppConfigReader(config.AppTypeCanal)()
+		if err != nil {
+			return errgo.Trace(err)
+		}
+
+		n, err := canal.ReplayDLQ(cmd.Context(), cfg, logger.Named("canal.dlq"), dlqReplayArgs.idle)
+		fmt.Printf("%d messages replayed\n", n)
+
+		return err
+	},
+}
+
+func init() {
+	dlqReplayCommand.Flags().DurationVar(&dlqReplayArgs.idle, "idle", 10*time.Second,
+		"stop when no new message is received in this duration")
+	dlqCommand.AddCommand(dlqReplayCommand)
+	Command.AddCommand(dlqCommand)
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package canal
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/canal"
+)
+
+var replayArgs struct {
+	file string
+}
+
+var replayCommand = &cobra.Command{
+	Use:   "replay",
+	Short: "process debezium messages from file instead of kafka",
+	Example: `  canal replay --file canal/example/u.json
+  kafka-console-consumer ... | canal replay --file -`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var r io.Reader = os.Stdin
+		if replayArgs.file != "-" {
+			f, err := os.Open(replayArgs.file)
+			if err != nil {
+				return errgo.Wrap(err, "failed to open file")
+			}
+			defer f.Close()
+
+			r = f
+		}
+
+		return canal.Replay(r)
+	},
+}
+
+func init() {
+	replayCommand.Flags().StringVar(&replayArgs.file, "file", "",
+		"file of newline-delimited debezium messages, \"-\" to read from stdin")
+	_ = replayCommand.MarkFlagRequired("file")
+	Command.AddCommand(replayCommand)
+}
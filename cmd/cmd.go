@@ -19,6 +19,7 @@ import (
 
 	"github.com/bangumi/server/cmd/archive"
 	"github.com/bangumi/server/cmd/canal"
+	"github.com/bangumi/server/cmd/search"
 	"github.com/bangumi/server/cmd/web"
 )
 
@@ -35,5 +36,5 @@ var Root = cobra.Command{
 
 func init() {
 	Root.PersistentFlags().String("config", "", "config file location")
-	Root.AddCommand(canal.Command, web.Command, archive.Command)
+	Root.AddCommand(canal.Command, web.Command, archive.Command, search.Command)
 }
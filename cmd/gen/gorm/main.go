@@ -556,6 +556,15 @@ func main() {
 		}),
 	))
 
+	g.ApplyBasic(g.GenerateModelAs("chii_timeline", "TimeLine",
+		gen.FieldTrimPrefix("tml_"),
+		gen.FieldType("tml_uid", userIDTypeString),
+		gen.FieldRename("tml_uid", "UserID"),
+		gen.FieldType("tml_memo", "[]byte"),
+		gen.FieldType("tml_img", "[]byte"),
+		gen.FieldRename("tml_dateline", createdTime),
+	))
+
 	// execute the action of code generation
 	g.Execute()
 }
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+//nolint:forbidigo
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/internal/search"
+)
+
+var deadLetterArgs struct {
+	target string
+	replay bool
+}
+
+var deadLetterCommand = &cobra.Command{
+	Use:   "dead-letter",
+	Short: "list or replay documents failed to write to meilisearch",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := parseTarget(deadLetterArgs.target)
+		if err != nil {
+			return err
+		}
+
+		return deadLetter(cmd.Context(), target, deadLetterArgs.replay)
+	},
+}
+
+func init() {
+	deadLetterCommand.Flags().StringVar(&deadLetterArgs.target, "target", "",
+		"index to inspect, subject|character|person|index")
+	deadLetterCommand.Flags().BoolVar(&deadLetterArgs.replay, "replay", false, "write all documents to index now")
+	_ = deadLetterCommand.MarkFlagRequired("target")
+}
+
+func deadLetter(ctx context.Context, target search.SearchTarget, replay bool) error {
+	var s search.Client
+	if err := populate(&s); err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if replay {
+		n, err := s.ReplayDeadLetter(ctx, target)
+		if err != nil {
+			return errgo.Wrap(err, "replay dead letter")
+		}
+
+		fmt.Printf("%d documents replayed\n", n)
+	}
+
+	entries, err := s.ListDeadLetter(ctx, target)
+	if err != nil {
+		return errgo.Wrap(err, "list dead letter")
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("dead letter is empty")
+		return nil
+	}
+
+	for _, e := range entries {
+		ids := lo.Map(e.IDs, func(id uint32, _ int) string { return fmt.Sprint(id) })
+		fmt.Printf("%s attempts=%d next_retry=%s\n  ids:   %s\n  error: %s\n",
+			e.StreamID, e.Attempts, e.NextRetry.Format(time.RFC3339), strings.Join(ids, ", "), e.Error)
+	}
+
+	return nil
+}
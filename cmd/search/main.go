@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package search
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/trim21/errgo"
+	"go.uber.org/fx"
+
+	"github.com/bangumi/server/config"
+	"github.com/bangumi/server/dal"
+	"github.com/bangumi/server/internal/character"
+	"github.com/bangumi/server/internal/index"
+	"github.com/bangumi/server/internal/person"
+	"github.com/bangumi/server/internal/pkg/driver"
+	"github.com/bangumi/server/internal/pkg/logger"
+	"github.com/bangumi/server/internal/search"
+	"github.com/bangumi/server/internal/subject"
+	"github.com/bangumi/server/internal/user"
+)
+
+var Command = &cobra.Command{
+	Use:   "search",
+	Short: "manage meilisearch indexes",
+}
+
+func init() {
+	Command.AddCommand(reindexCommand, settingsCommand, deadLetterCommand, pageRankCommand)
+}
+
+func parseTarget(s string) (search.SearchTarget, error) {
+	switch t := search.SearchTarget(s); t {
+	case search.SearchTargetSubject, search.SearchTargetCharacter, search.SearchTargetPerson, search.SearchTargetIndex:
+		return t, nil
+	}
+
+	return "", fmt.Errorf("unknown target %q", s)
+}
+
+func populate(targets ...any) error {
+	err := fx.New(
+		fx.NopLogger,
+		dal.Module,
+
+		fx.Provide(
+			config.NewAppConfig, logger.Copy,
+			driver.NewMysqlDriver, driver.NewRueidisClient,
+
+			subject.NewMysqlRepo, character.NewMysqlRepo, person.NewMysqlRepo,
+			index.NewMysqlRepo, user.NewMysqlRepo,
+			search.New,
+		),
+
+		fx.Populate(targets...),
+	).Err()
+
+	return errgo.Wrap(err, "fx")
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package search
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/redis/rueidis"
+	"github.com/spf13/cobra"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/dal/query"
+	subjectSearcher "github.com/bangumi/server/internal/search/subject"
+)
+
+var pageRankCommand = &cobra.Command{
+	Use:   "page-rank",
+	Short: "compute subject page rank from relations, staff and characters, used by next subject reindex",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		return pageRank(ctx)
+	},
+}
+
+func pageRank(ctx context.Context) error {
+	var q *query.Query
+	var r rueidis.Client
+	var log *zap.Logger
+
+	if err := populate(&q, &r, &log); err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	log = log.Named("search.page-rank")
+
+	n, err := subjectSearcher.ComputePageRank(ctx, q, r, log)
+	if err != nil {
+		return errgo.Wrap(err, "page rank")
+	}
+
+	log.Info("page rank saved, run `search reindex --target subject` to update index", zap.Int("subjects", n))
+
+	return nil
+}
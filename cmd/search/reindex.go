@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package search
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/redis/rueidis"
+	"github.com/spf13/cobra"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/internal/search"
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+var reindexArgs struct {
+	target string
+	fromID uint32
+	batch  int
+}
+
+var reindexCommand = &cobra.Command{
+	Use:   "reindex",
+	Short: "rebuild a search index from mysql, resume from last checkpoint if interrupted",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		target, err := parseTarget(reindexArgs.target)
+		if err != nil {
+			return err
+		}
+
+		return reindex(ctx, target, reindexArgs.fromID, reindexArgs.batch)
+	},
+}
+
+func init() {
+	reindexCommand.Flags().StringVar(&reindexArgs.target, "target", "",
+		"index to rebuild, subject|character|person|index")
+	reindexCommand.Flags().Uint32Var(&reindexArgs.fromID, "from-id", 0,
+		"start from this id, default to continue from last checkpoint")
+	reindexCommand.Flags().IntVar(&reindexArgs.batch, "batch", searcher.DefaultBatchSize, "documents per batch")
+	_ = reindexCommand.MarkFlagRequired("target")
+}
+
+// checkpointKey 不包含版本号，升级后也可以从上一次中断的位置继续.
+func checkpointKey(target search.SearchTarget) string {
+	return "chii:search:reindex:" + string(target)
+}
+
+func reindex(ctx context.Context, target search.SearchTarget, fromID uint32, batch int) error {
+	var s search.Client
+	var r rueidis.Client
+	var log *zap.Logger
+
+	if err := populate(&s, &r, &log); err != nil {
+		return err
+	}
+
+	defer s.Close()
+	defer r.Close()
+
+	log = log.Named("search.reindex").With(zap.String("target", string(target)))
+
+	key := checkpointKey(target)
+	if fromID == 0 {
+		v, err := r.Do(ctx, r.B().Get().Key(key).Build()).AsUint64()
+		if err != nil && !rueidis.IsRedisNil(err) {
+			return errgo.Wrap(err, "failed to read checkpoint")
+		}
+
+		if v != 0 {
+			fromID = uint32(v) + 1
+			log.Info("continue from checkpoint", zap.Uint32("from", fromID))
+		}
+	}
+
+	progress := searcher.LogProgress(log)
+	err := s.Reindex(ctx, target, fromID, batch, func(p searcher.Progress) error {
+		err := r.Do(ctx, r.B().Set().Key(key).Value(strconv.FormatUint(uint64(p.LastID), 10)).Build()).Error()
+		if err != nil {
+			return errgo.Wrap(err, "failed to save checkpoint")
+		}
+
+		return progress(p)
+	})
+	if err != nil {
+		if errors.Is(err, searcher.ErrDeadLettered) {
+			log.Error("batch failed and checkpoint is kept, run again to continue, " +
+				"or failed documents will be retried by canal or `search dead-letter --replay`")
+		}
+
+		return errgo.Wrap(err, "reindex")
+	}
+
+	if err := r.Do(ctx, r.B().Del().Key(key).Build()).Error(); err != nil {
+		return errgo.Wrap(err, "failed to clear checkpoint")
+	}
+
+	log.Info("finish reindex")
+
+	return nil
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+//nolint:forbidigo
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/internal/search"
+)
+
+var settingsArgs struct {
+	target string
+	dryRun bool
+}
+
+var settingsCommand = &cobra.Command{
+	Use:   "settings",
+	Short: "update index settings to match document definition",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := parseTarget(settingsArgs.target)
+		if err != nil {
+			return err
+		}
+
+		return reconcileSettings(cmd.Context(), target, settingsArgs.dryRun)
+	},
+}
+
+func init() {
+	settingsCommand.Flags().StringVar(&settingsArgs.target, "target", "",
+		"index to update, subject|character|person|index")
+	settingsCommand.Flags().BoolVar(&settingsArgs.dryRun, "dry-run", false, "only print difference")
+	_ = settingsCommand.MarkFlagRequired("target")
+}
+
+func reconcileSettings(ctx context.Context, target search.SearchTarget, dryRun bool) error {
+	var s search.Client
+	if err := populate(&s); err != nil {
+		return err
+	}
+	defer s.Close()
+
+	diff, err := s.ReconcileSettings(ctx, target, dryRun)
+	if err != nil {
+		return errgo.Wrap(err, "reconcile settings")
+	}
+
+	if len(diff) == 0 {
+		fmt.Println("index settings are up to date")
+		return nil
+	}
+
+	for _, d := range diff {
+		fmt.Printf("%s:\n  current:  [%s]\n  expected: [%s]\n",
+			d.Name, strings.Join(d.Current, ", "), strings.Join(d.Expected, ", "))
+	}
+
+	if dryRun {
+		fmt.Println("dry run, nothing changed")
+	}
+
+	return nil
+}
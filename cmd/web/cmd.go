@@ -43,6 +43,7 @@ import (
 	"github.com/bangumi/server/internal/tag"
 	"github.com/bangumi/server/internal/timeline"
 	"github.com/bangumi/server/internal/user"
+	"github.com/bangumi/server/internal/webhook"
 	"github.com/bangumi/server/web"
 )
 
@@ -83,13 +84,15 @@ func start() error {
 
 			user.NewMysqlRepo,
 			index.NewMysqlRepo, auth.NewMysqlRepo, episode.NewMysqlRepo, revision.NewMysqlRepo, infra.NewMysqlRepo,
-			timeline.NewSrv,
+			timeline.NewSrv, timeline.NewMysqlRepo,
 
 			dam.New, subject.NewMysqlRepo, subject.NewCachedRepo,
 			character.NewMysqlRepo, person.NewMysqlRepo,
 
 			tag.NewCachedRepo, tag.NewMysqlRepo,
 
+			webhook.NewMysqlRepo,
+
 			auth.NewService, person.NewService, search.New,
 		),
 
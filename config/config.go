@@ -22,6 +22,12 @@ import (
 const AppTypeCanal = "canal"
 const AppTypeHTTP = "http"
 
+// SearchBackendEmbedded 使用进程内的内存索引代替 meilisearch，用于本地开发和测试.
+const SearchBackendEmbedded = "embedded"
+
+// CanalBackendRedis 从 redis stream 读取 debezium 消息，不需要部署 kafka.
+const CanalBackendRedis = "redis"
+
 type AppConfig struct {
 	Debug struct {
 		Gorm bool `toml:"gorm"`
@@ -56,13 +62,44 @@ type AppConfig struct {
 	Kafka struct {
 		Broker string   `toml:"broker" env:"KAFKA_BROKER"`
 		Topics []string `toml:"topics"`
+
+		// 处理失败的消息会重试 MaxAttempts 次，之后写入 DLQTopic 并提交 offset
+		MaxAttempts     int           `toml:"max-attempts" env:"KAFKA_MAX_ATTEMPTS" env-default:"5"`
+		RetryBackoff    time.Duration `toml:"retry-backoff" env:"KAFKA_RETRY_BACKOFF" env-default:"1s"`
+		MaxRetryBackoff time.Duration `toml:"max-retry-backoff" env:"KAFKA_MAX_RETRY_BACKOFF" env-default:"1m"`
+		DLQTopic        string        `toml:"dlq-topic" env:"KAFKA_DLQ_TOPIC" env-default:"chii.canal.dlq"`
 	} `toml:"kafka"`
 
+	Canal struct {
+		// Backend 为空时使用 kafka，见 [CanalBackendRedis]
+		Backend string `toml:"backend" env:"CANAL_BACKEND"`
+
+		// Concurrency 是并行处理消息的数量，同一个实体的消息总是按顺序处理
+		Concurrency int `toml:"concurrency" env:"CANAL_CONCURRENCY" env-default:"1"`
+
+		// 还有没读取的消息但是超过 StallTimeout 没有处理完任何消息时 readyz 返回失败
+		StallTimeout time.Duration `toml:"stall-timeout" env:"CANAL_STALL_TIMEOUT" env-default:"5m"`
+
+		// RedisStreams 是 Backend 为 redis 时读取的 stream，每个表一个，和 kafka.topics 相同
+		RedisStreams []string `toml:"redis-streams"`
+		// 超过 RedisClaimIdle 没有 ack 的消息会被重新处理
+		RedisClaimIdle time.Duration `toml:"redis-claim-idle" env:"CANAL_REDIS_CLAIM_IDLE" env-default:"1m"`
+		// 已经处理失败 RedisMaxDeliveries 次的消息会写入 RedisDLQStream 并 ack
+		RedisMaxDeliveries int    `toml:"redis-max-deliveries" env:"CANAL_REDIS_MAX_DELIVERIES" env-default:"5"`
+		RedisDLQStream     string `toml:"redis-dlq-stream" env:"CANAL_REDIS_DLQ_STREAM" env-default:"chii:canal:dlq"`
+	} `toml:"canal"`
+
 	Search struct {
+		// Backend 为空时使用 meilisearch，见 [SearchBackendEmbedded]
+		Backend string `toml:"backend" env:"SEARCH_BACKEND"`
+
 		MeiliSearch struct {
 			URL     string        `toml:"url" env:"MEILISEARCH_URL"`
 			Key     string        `toml:"key" env:"MEILISEARCH_KEY"`
 			Timeout time.Duration `toml:"timeout" env:"MEILISEARCH_REQUEST_TIMEOUT" env-default:"2s"`
+
+			// SuggestTimeout 是输入补全请求的超时，需要比 Timeout 短得多
+			SuggestTimeout time.Duration `toml:"suggest-timeout" env:"MEILISEARCH_SUGGEST_TIMEOUT" env-default:"300ms"`
 		} `toml:"meilisearch"`
 	} `toml:"search"`
 
@@ -46,6 +46,7 @@ func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 		SubjectRevision:   newSubjectRevision(db, opts...),
 		TagIndex:          newTagIndex(db, opts...),
 		TagList:           newTagList(db, opts...),
+		TimeLine:          newTimeLine(db, opts...),
 		UserGroup:         newUserGroup(db, opts...),
 		WebSession:        newWebSession(db, opts...),
 	}
@@ -82,6 +83,7 @@ type Query struct {
 	SubjectRevision   subjectRevision
 	TagIndex          tagIndex
 	TagList           tagList
+	TimeLine          timeLine
 	UserGroup         userGroup
 	WebSession        webSession
 }
@@ -119,6 +121,7 @@ func (q *Query) clone(db *gorm.DB) *Query {
 		SubjectRevision:   q.SubjectRevision.clone(db),
 		TagIndex:          q.TagIndex.clone(db),
 		TagList:           q.TagList.clone(db),
+		TimeLine:          q.TimeLine.clone(db),
 		UserGroup:         q.UserGroup.clone(db),
 		WebSession:        q.WebSession.clone(db),
 	}
@@ -163,6 +166,7 @@ func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 		SubjectRevision:   q.SubjectRevision.replaceDB(db),
 		TagIndex:          q.TagIndex.replaceDB(db),
 		TagList:           q.TagList.replaceDB(db),
+		TimeLine:          q.TimeLine.replaceDB(db),
 		UserGroup:         q.UserGroup.replaceDB(db),
 		WebSession:        q.WebSession.replaceDB(db),
 	}
@@ -197,6 +201,7 @@ type queryCtx struct {
 	SubjectRevision   *subjectRevisionDo
 	TagIndex          *tagIndexDo
 	TagList           *tagListDo
+	TimeLine          *timeLineDo
 	UserGroup         *userGroupDo
 	WebSession        *webSessionDo
 }
@@ -231,6 +236,7 @@ func (q *Query) WithContext(ctx context.Context) *queryCtx {
 		SubjectRevision:   q.SubjectRevision.WithContext(ctx),
 		TagIndex:          q.TagIndex.WithContext(ctx),
 		TagList:           q.TagList.WithContext(ctx),
+		TimeLine:          q.TimeLine.WithContext(ctx),
 		UserGroup:         q.UserGroup.WithContext(ctx),
 		WebSession:        q.WebSession.WithContext(ctx),
 	}
@@ -41,6 +41,7 @@ type UserInfo struct {
 	Permission Permission
 	Scope      Scope
 	Legacy     bool
+	TokenID    uint32
 }
 
 // Auth is the basic authorization represent a user.
@@ -52,6 +53,8 @@ type Auth struct {
 	Permission Permission
 	Scope      Scope
 	Legacy     bool
+	// 当前请求使用的 access token 的 id，cookie 登录时为 0
+	TokenID uint32
 }
 
 type Scope map[string]bool
@@ -26,5 +26,10 @@ func User(userID model.UserID) string {
 }
 
 func Auth(token string) string {
-	return config.RedisKeyPrefix + "auth:access-token:" + token
+	return config.RedisKeyPrefix + "auth:access-token:v2:" + token
+}
+
+// UserTokens 记录用户所有的 [Auth] 缓存.
+func UserTokens(userID model.UserID) string {
+	return config.RedisKeyPrefix + "auth:user-tokens:" + strconv.FormatUint(uint64(userID), 10)
 }
@@ -49,10 +49,11 @@ type mysqlRepo struct {
 func (m mysqlRepo) GetByToken(ctx context.Context, token string) (UserInfo, error) {
 	var access struct {
 		UserID string         `db:"user_id"`
+		ID     uint32         `db:"id"`
 		Scope  sql.NullString `db:"scope"`
 	}
 	err := m.db.GetContext(ctx, &access,
-		`select user_id, scope from chii_oauth_access_tokens
+		`select id, user_id, scope from chii_oauth_access_tokens
                where access_token = BINARY ? and expires > ? limit 1`, token, time.Now())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -98,6 +99,7 @@ func (m mysqlRepo) GetByToken(ctx context.Context, token string) (UserInfo, erro
 		Permission: perm,
 		Scope:      scope,
 		Legacy:     legacy,
+		TokenID:    access.ID,
 	}, nil
 }
 
@@ -22,6 +22,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/bangumi/server/internal/auth/internal/cachekey"
+	"github.com/bangumi/server/internal/model"
 	"github.com/bangumi/server/internal/pkg/cache"
 	"github.com/bangumi/server/internal/user"
 )
@@ -29,6 +30,8 @@ import (
 const TokenTypeOauthToken = 0
 const TokenTypeAccessToken = 1
 
+const authCacheTTL = time.Minute * 10
+
 func NewService(repo Repo, u user.Repo, logger *zap.Logger, c cache.RedisCache) Service {
 	return service{
 		permCache: cache.NewMemoryCache[user.GroupID, Permission](),
@@ -62,7 +65,8 @@ func (s service) GetByToken(ctx context.Context, token string) (Auth, error) {
 			return Auth{}, errgo.Wrap(err, "AuthRepo.GetByID")
 		}
 
-		_ = s.cache.Set(ctx, cacheKey, a, time.Minute*10)
+		_ = s.cache.Set(ctx, cacheKey, a, authCacheTTL)
+		_ = s.cache.Tag(ctx, cachekey.UserTokens(a.ID), cacheKey, authCacheTTL)
 	}
 
 	permission, err := s.getPermission(ctx, a.GroupID)
@@ -78,9 +82,15 @@ func (s service) GetByToken(ctx context.Context, token string) (Auth, error) {
 		Permission: permission.Merge(a.Permission),
 		Scope:      a.Scope,
 		Legacy:     a.Legacy,
+		TokenID:    a.TokenID,
 	}, nil
 }
 
+// InvalidateUserCache 删除用户所有 access token 的缓存，用于用户被封禁或者修改用户组后立即生效.
+func InvalidateUserCache(ctx context.Context, c cache.RedisCache, id model.UserID) error {
+	return errgo.Wrap(c.DelTag(ctx, cachekey.UserTokens(id)), "cache.DelTag")
+}
+
 func (s service) getPermission(ctx context.Context, id user.GroupID) (Permission, error) {
 	p, ok := s.permCache.Get(ctx, id)
 
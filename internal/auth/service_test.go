@@ -47,3 +47,23 @@ func TestService_GetByToken(t *testing.T) {
 	require.Equal(t, user.GroupID(2), a.GroupID)
 	require.True(t, a.Permission.EpEdit)
 }
+
+func TestService_GetByToken_tagUserCache(t *testing.T) {
+	t.Parallel()
+
+	var m = mocks.NewAuthRepo(t)
+	m.EXPECT().GetByToken(mock.Anything, test.TreeHoleAccessToken).
+		Return(auth.UserInfo{ID: 1, GroupID: 2}, nil)
+	m.EXPECT().GetPermission(mock.Anything, user.GroupID(2)).Return(auth.Permission{}, nil)
+
+	var c = mocks.NewRedisCache(t)
+	c.EXPECT().Get(mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+	c.EXPECT().Set(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	// 用户被封禁时通过这个 tag 删除所有 token 的缓存
+	c.EXPECT().Tag(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	s := auth.NewService(m, mocks.NewUserRepo(t), zap.NewNop(), c)
+
+	_, err := s.GetByToken(context.Background(), test.TreeHoleAccessToken)
+	require.NoError(t, err)
+}
@@ -35,12 +35,18 @@ func Subject(id model.SubjectID) string {
 	return resPrefix + "subject:" + strconv.FormatUint(uint64(id), 10)
 }
 
-func SubjectBrowse(s string, limit, offset int) string {
-	return resPrefix + "subject::browse:" + s + ":" + strconv.Itoa(limit) + ":" + strconv.Itoa(offset)
+// SubjectBrowseVersion 保存一个条目类型的浏览缓存版本，版本变化后旧的浏览缓存不会再被读取.
+func SubjectBrowseVersion(t model.SubjectType) string {
+	return resPrefix + "subject::browse-version:" + strconv.FormatUint(uint64(t), 10)
 }
 
-func SubjectBrowseCount(s string) string {
-	return resPrefix + "subject::browse:" + s + "::count"
+func SubjectBrowse(version int64, s string, limit, offset int) string {
+	return resPrefix + "subject::browse:" + strconv.FormatInt(version, 10) + ":" + s + ":" +
+		strconv.Itoa(limit) + ":" + strconv.Itoa(offset)
+}
+
+func SubjectBrowseCount(version int64, s string) string {
+	return resPrefix + "subject::browse:" + strconv.FormatInt(version, 10) + ":" + s + "::count"
 }
 
 func Episode(id model.EpisodeID) string {
@@ -30,6 +30,8 @@ type Repo interface {
 //nolint:revive
 type IndexRepo interface {
 	Get(ctx context.Context, id model.IndexID) (model.Index, error)
+	// GetByIDs return indices not deleted, private indices are included.
+	GetByIDs(ctx context.Context, ids []model.IndexID) (map[model.IndexID]model.Index, error)
 	New(ctx context.Context, i *model.Index) error
 	Update(ctx context.Context, id model.IndexID, title string, desc string) error
 	Delete(ctx context.Context, id model.IndexID) error
@@ -79,6 +79,45 @@ func (r mysqlRepo) Get(ctx context.Context, id model.IndexID) (model.Index, erro
 	return *ret, nil
 }
 
+func (r mysqlRepo) GetByIDs(ctx context.Context, ids []model.IndexID) (map[model.IndexID]model.Index, error) {
+	var result = make(map[model.IndexID]model.Index, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	records, err := r.q.Index.WithContext(ctx).
+		Where(
+			r.q.Index.ID.In(ids...),
+			r.q.Index.Privacy.Neq(uint8(model.IndexPrivacyDeleted)),
+		).
+		Find()
+	if err != nil {
+		return nil, errgo.Wrap(err, "dal")
+	}
+
+	var nsfw []model.IndexID
+	err = r.q.IndexSubject.WithContext(ctx).
+		Join(r.q.Subject, r.q.IndexSubject.SubjectID.EqCol(r.q.Subject.ID)).
+		Where(r.q.IndexSubject.IndexID.In(ids...), r.q.IndexSubject.Cat.Eq(0), r.q.Subject.Nsfw.Is(true)).
+		Pluck(r.q.IndexSubject.IndexID, &nsfw)
+	if err != nil {
+		return nil, errgo.Wrap(err, "dal")
+	}
+
+	for _, record := range records {
+		result[record.ID] = *daoToModel(record)
+	}
+
+	for _, id := range nsfw {
+		if i, ok := result[id]; ok {
+			i.NSFW = true
+			result[id] = i
+		}
+	}
+
+	return result, nil
+}
+
 func (r mysqlRepo) New(ctx context.Context, i *model.Index) error {
 	dao := modelToDAO(i)
 	if err := r.q.Index.WithContext(ctx).Create(dao); err != nil {
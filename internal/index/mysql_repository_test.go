@@ -55,6 +55,20 @@ func TestMysqlRepo_Get(t *testing.T) {
 	require.False(t, i.NSFW)
 }
 
+func TestMysqlRepo_GetByIDs(t *testing.T) {
+	test.RequireEnv(t, test.EnvMysql)
+	t.Parallel()
+
+	repo := getRepo(t)
+
+	indices, err := repo.GetByIDs(context.Background(), []model.IndexID{15045, 0})
+	require.NoError(t, err)
+
+	require.Len(t, indices, 1)
+	require.EqualValues(t, 14127, indices[15045].CreatorID)
+	require.False(t, indices[15045].NSFW)
+}
+
 func TestMysqlRepo_GetPrivateIndex(t *testing.T) {
 	test.RequireEnv(t, test.EnvMysql)
 	t.Parallel()
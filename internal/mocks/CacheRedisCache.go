@@ -104,6 +104,63 @@ func (_c *RedisCache_Del_Call) RunAndReturn(run func(ctx context.Context, keys .
 	return _c
 }
 
+// DelTag provides a mock function for the type RedisCache
+func (_mock *RedisCache) DelTag(ctx context.Context, tag string) error {
+	ret := _mock.Called(ctx, tag)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DelTag")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = returnFunc(ctx, tag)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// RedisCache_DelTag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DelTag'
+type RedisCache_DelTag_Call struct {
+	*mock.Call
+}
+
+// DelTag is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tag string
+func (_e *RedisCache_Expecter) DelTag(ctx interface{}, tag interface{}) *RedisCache_DelTag_Call {
+	return &RedisCache_DelTag_Call{Call: _e.mock.On("DelTag", ctx, tag)}
+}
+
+func (_c *RedisCache_DelTag_Call) Run(run func(ctx context.Context, tag string)) *RedisCache_DelTag_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *RedisCache_DelTag_Call) Return(err error) *RedisCache_DelTag_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *RedisCache_DelTag_Call) RunAndReturn(run func(ctx context.Context, tag string) error) *RedisCache_DelTag_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Get provides a mock function for the type RedisCache
 func (_mock *RedisCache) Get(ctx context.Context, key string, value any) (bool, error) {
 	ret := _mock.Called(ctx, key, value)
@@ -307,3 +364,72 @@ func (_c *RedisCache_Set_Call) RunAndReturn(run func(ctx context.Context, key st
 	_c.Call.Return(run)
 	return _c
 }
+
+// Tag provides a mock function for the type RedisCache
+func (_mock *RedisCache) Tag(ctx context.Context, tag string, key string, ttl time.Duration) error {
+	ret := _mock.Called(ctx, tag, key, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Tag")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string, time.Duration) error); ok {
+		r0 = returnFunc(ctx, tag, key, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// RedisCache_Tag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Tag'
+type RedisCache_Tag_Call struct {
+	*mock.Call
+}
+
+// Tag is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tag string
+//   - key string
+//   - ttl time.Duration
+func (_e *RedisCache_Expecter) Tag(ctx interface{}, tag interface{}, key interface{}, ttl interface{}) *RedisCache_Tag_Call {
+	return &RedisCache_Tag_Call{Call: _e.mock.On("Tag", ctx, tag, key, ttl)}
+}
+
+func (_c *RedisCache_Tag_Call) Run(run func(ctx context.Context, tag string, key string, ttl time.Duration)) *RedisCache_Tag_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		var arg3 time.Duration
+		if args[3] != nil {
+			arg3 = args[3].(time.Duration)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *RedisCache_Tag_Call) Return(err error) *RedisCache_Tag_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *RedisCache_Tag_Call) RunAndReturn(run func(ctx context.Context, tag string, key string, ttl time.Duration) error) *RedisCache_Tag_Call {
+	_c.Call.Return(run)
+	return _c
+}
@@ -509,6 +509,74 @@ func (_c *IndexRepo_Get_Call) RunAndReturn(run func(ctx context.Context, id mode
 	return _c
 }
 
+// GetByIDs provides a mock function for the type IndexRepo
+func (_mock *IndexRepo) GetByIDs(ctx context.Context, ids []model.IndexID) (map[model.IndexID]model.Index, error) {
+	ret := _mock.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByIDs")
+	}
+
+	var r0 map[model.IndexID]model.Index
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []model.IndexID) (map[model.IndexID]model.Index, error)); ok {
+		return returnFunc(ctx, ids)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []model.IndexID) map[model.IndexID]model.Index); ok {
+		r0 = returnFunc(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[model.IndexID]model.Index)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, []model.IndexID) error); ok {
+		r1 = returnFunc(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// IndexRepo_GetByIDs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetByIDs'
+type IndexRepo_GetByIDs_Call struct {
+	*mock.Call
+}
+
+// GetByIDs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ids []model.IndexID
+func (_e *IndexRepo_Expecter) GetByIDs(ctx interface{}, ids interface{}) *IndexRepo_GetByIDs_Call {
+	return &IndexRepo_GetByIDs_Call{Call: _e.mock.On("GetByIDs", ctx, ids)}
+}
+
+func (_c *IndexRepo_GetByIDs_Call) Run(run func(ctx context.Context, ids []model.IndexID)) *IndexRepo_GetByIDs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []model.IndexID
+		if args[1] != nil {
+			arg1 = args[1].([]model.IndexID)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *IndexRepo_GetByIDs_Call) Return(vToIndex map[model.IndexID]model.Index, err error) *IndexRepo_GetByIDs_Call {
+	_c.Call.Return(vToIndex, err)
+	return _c
+}
+
+func (_c *IndexRepo_GetByIDs_Call) RunAndReturn(run func(ctx context.Context, ids []model.IndexID) (map[model.IndexID]model.Index, error)) *IndexRepo_GetByIDs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetIndexCollect provides a mock function for the type IndexRepo
 func (_mock *IndexRepo) GetIndexCollect(ctx context.Context, id model.IndexID, uid model.UserID) (*index.IndexCollect, error) {
 	ret := _mock.Called(ctx, id, uid)
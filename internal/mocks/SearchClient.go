@@ -8,6 +8,7 @@ import (
 	"context"
 
 	"github.com/bangumi/server/internal/search"
+	"github.com/bangumi/server/internal/search/searcher"
 	"github.com/labstack/echo/v5"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -317,3 +318,337 @@ func (_c *SearchClient_Handle_Call) RunAndReturn(run func(c *echo.Context, targe
 	_c.Call.Return(run)
 	return _c
 }
+
+// ListDeadLetter provides a mock function for the type SearchClient
+func (_mock *SearchClient) ListDeadLetter(ctx context.Context, target search.SearchTarget) ([]searcher.DeadLetterEntry, error) {
+	ret := _mock.Called(ctx, target)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDeadLetter")
+	}
+
+	var r0 []searcher.DeadLetterEntry
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, search.SearchTarget) ([]searcher.DeadLetterEntry, error)); ok {
+		return returnFunc(ctx, target)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, search.SearchTarget) []searcher.DeadLetterEntry); ok {
+		r0 = returnFunc(ctx, target)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]searcher.DeadLetterEntry)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, search.SearchTarget) error); ok {
+		r1 = returnFunc(ctx, target)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// SearchClient_ListDeadLetter_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListDeadLetter'
+type SearchClient_ListDeadLetter_Call struct {
+	*mock.Call
+}
+
+// ListDeadLetter is a helper method to define mock.On call
+//   - ctx context.Context
+//   - target search.SearchTarget
+func (_e *SearchClient_Expecter) ListDeadLetter(ctx interface{}, target interface{}) *SearchClient_ListDeadLetter_Call {
+	return &SearchClient_ListDeadLetter_Call{Call: _e.mock.On("ListDeadLetter", ctx, target)}
+}
+
+func (_c *SearchClient_ListDeadLetter_Call) Run(run func(ctx context.Context, target search.SearchTarget)) *SearchClient_ListDeadLetter_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 search.SearchTarget
+		if args[1] != nil {
+			arg1 = args[1].(search.SearchTarget)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *SearchClient_ListDeadLetter_Call) Return(deadLetterEntrys []searcher.DeadLetterEntry, err error) *SearchClient_ListDeadLetter_Call {
+	_c.Call.Return(deadLetterEntrys, err)
+	return _c
+}
+
+func (_c *SearchClient_ListDeadLetter_Call) RunAndReturn(run func(ctx context.Context, target search.SearchTarget) ([]searcher.DeadLetterEntry, error)) *SearchClient_ListDeadLetter_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ReconcileSettings provides a mock function for the type SearchClient
+func (_mock *SearchClient) ReconcileSettings(ctx context.Context, target search.SearchTarget, dryRun bool) ([]searcher.SettingDiff, error) {
+	ret := _mock.Called(ctx, target, dryRun)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReconcileSettings")
+	}
+
+	var r0 []searcher.SettingDiff
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, search.SearchTarget, bool) ([]searcher.SettingDiff, error)); ok {
+		return returnFunc(ctx, target, dryRun)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, search.SearchTarget, bool) []searcher.SettingDiff); ok {
+		r0 = returnFunc(ctx, target, dryRun)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]searcher.SettingDiff)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, search.SearchTarget, bool) error); ok {
+		r1 = returnFunc(ctx, target, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// SearchClient_ReconcileSettings_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReconcileSettings'
+type SearchClient_ReconcileSettings_Call struct {
+	*mock.Call
+}
+
+// ReconcileSettings is a helper method to define mock.On call
+//   - ctx context.Context
+//   - target search.SearchTarget
+//   - dryRun bool
+func (_e *SearchClient_Expecter) ReconcileSettings(ctx interface{}, target interface{}, dryRun interface{}) *SearchClient_ReconcileSettings_Call {
+	return &SearchClient_ReconcileSettings_Call{Call: _e.mock.On("ReconcileSettings", ctx, target, dryRun)}
+}
+
+func (_c *SearchClient_ReconcileSettings_Call) Run(run func(ctx context.Context, target search.SearchTarget, dryRun bool)) *SearchClient_ReconcileSettings_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 search.SearchTarget
+		if args[1] != nil {
+			arg1 = args[1].(search.SearchTarget)
+		}
+		var arg2 bool
+		if args[2] != nil {
+			arg2 = args[2].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *SearchClient_ReconcileSettings_Call) Return(settingDiffs []searcher.SettingDiff, err error) *SearchClient_ReconcileSettings_Call {
+	_c.Call.Return(settingDiffs, err)
+	return _c
+}
+
+func (_c *SearchClient_ReconcileSettings_Call) RunAndReturn(run func(ctx context.Context, target search.SearchTarget, dryRun bool) ([]searcher.SettingDiff, error)) *SearchClient_ReconcileSettings_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Reindex provides a mock function for the type SearchClient
+func (_mock *SearchClient) Reindex(ctx context.Context, target search.SearchTarget, fromID uint32, batchSize int, onBatch func(searcher.Progress) error) error {
+	ret := _mock.Called(ctx, target, fromID, batchSize, onBatch)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reindex")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, search.SearchTarget, uint32, int, func(searcher.Progress) error) error); ok {
+		r0 = returnFunc(ctx, target, fromID, batchSize, onBatch)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// SearchClient_Reindex_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Reindex'
+type SearchClient_Reindex_Call struct {
+	*mock.Call
+}
+
+// Reindex is a helper method to define mock.On call
+//   - ctx context.Context
+//   - target search.SearchTarget
+//   - fromID uint32
+//   - batchSize int
+//   - onBatch func(searcher.Progress) error
+func (_e *SearchClient_Expecter) Reindex(ctx interface{}, target interface{}, fromID interface{}, batchSize interface{}, onBatch interface{}) *SearchClient_Reindex_Call {
+	return &SearchClient_Reindex_Call{Call: _e.mock.On("Reindex", ctx, target, fromID, batchSize, onBatch)}
+}
+
+func (_c *SearchClient_Reindex_Call) Run(run func(ctx context.Context, target search.SearchTarget, fromID uint32, batchSize int, onBatch func(searcher.Progress) error)) *SearchClient_Reindex_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 search.SearchTarget
+		if args[1] != nil {
+			arg1 = args[1].(search.SearchTarget)
+		}
+		var arg2 uint32
+		if args[2] != nil {
+			arg2 = args[2].(uint32)
+		}
+		var arg3 int
+		if args[3] != nil {
+			arg3 = args[3].(int)
+		}
+		var arg4 func(searcher.Progress) error
+		if args[4] != nil {
+			arg4 = args[4].(func(searcher.Progress) error)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+		)
+	})
+	return _c
+}
+
+func (_c *SearchClient_Reindex_Call) Return(err error) *SearchClient_Reindex_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *SearchClient_Reindex_Call) RunAndReturn(run func(ctx context.Context, target search.SearchTarget, fromID uint32, batchSize int, onBatch func(searcher.Progress) error) error) *SearchClient_Reindex_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ReplayDeadLetter provides a mock function for the type SearchClient
+func (_mock *SearchClient) ReplayDeadLetter(ctx context.Context, target search.SearchTarget) (int, error) {
+	ret := _mock.Called(ctx, target)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplayDeadLetter")
+	}
+
+	var r0 int
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, search.SearchTarget) (int, error)); ok {
+		return returnFunc(ctx, target)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, search.SearchTarget) int); ok {
+		r0 = returnFunc(ctx, target)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, search.SearchTarget) error); ok {
+		r1 = returnFunc(ctx, target)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// SearchClient_ReplayDeadLetter_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReplayDeadLetter'
+type SearchClient_ReplayDeadLetter_Call struct {
+	*mock.Call
+}
+
+// ReplayDeadLetter is a helper method to define mock.On call
+//   - ctx context.Context
+//   - target search.SearchTarget
+func (_e *SearchClient_Expecter) ReplayDeadLetter(ctx interface{}, target interface{}) *SearchClient_ReplayDeadLetter_Call {
+	return &SearchClient_ReplayDeadLetter_Call{Call: _e.mock.On("ReplayDeadLetter", ctx, target)}
+}
+
+func (_c *SearchClient_ReplayDeadLetter_Call) Run(run func(ctx context.Context, target search.SearchTarget)) *SearchClient_ReplayDeadLetter_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 search.SearchTarget
+		if args[1] != nil {
+			arg1 = args[1].(search.SearchTarget)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *SearchClient_ReplayDeadLetter_Call) Return(n int, err error) *SearchClient_ReplayDeadLetter_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *SearchClient_ReplayDeadLetter_Call) RunAndReturn(run func(ctx context.Context, target search.SearchTarget) (int, error)) *SearchClient_ReplayDeadLetter_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Suggest provides a mock function for the type SearchClient
+func (_mock *SearchClient) Suggest(c *echo.Context) error {
+	ret := _mock.Called(c)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Suggest")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(*echo.Context) error); ok {
+		r0 = returnFunc(c)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// SearchClient_Suggest_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Suggest'
+type SearchClient_Suggest_Call struct {
+	*mock.Call
+}
+
+// Suggest is a helper method to define mock.On call
+//   - c *echo.Context
+func (_e *SearchClient_Expecter) Suggest(c interface{}) *SearchClient_Suggest_Call {
+	return &SearchClient_Suggest_Call{Call: _e.mock.On("Suggest", c)}
+}
+
+func (_c *SearchClient_Suggest_Call) Run(run func(c *echo.Context)) *SearchClient_Suggest_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *echo.Context
+		if args[0] != nil {
+			arg0 = args[0].(*echo.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *SearchClient_Suggest_Call) Return(err error) *SearchClient_Suggest_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *SearchClient_Suggest_Call) RunAndReturn(run func(c *echo.Context) error) *SearchClient_Suggest_Call {
+	_c.Call.Return(run)
+	return _c
+}
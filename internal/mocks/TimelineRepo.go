@@ -0,0 +1,114 @@
+// Code generated by mockery; DO NOT EDIT.
+// github.com/vektra/mockery
+// template: testify
+
+package mocks
+
+import (
+	"context"
+
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/timeline"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// NewTimelineRepo creates a new instance of TimelineRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewTimelineRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *TimelineRepo {
+	mock := &TimelineRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// TimelineRepo is an autogenerated mock type for the Repo type
+type TimelineRepo struct {
+	mock.Mock
+}
+
+type TimelineRepo_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *TimelineRepo) EXPECT() *TimelineRepo_Expecter {
+	return &TimelineRepo_Expecter{mock: &_m.Mock}
+}
+
+// List provides a mock function for the type TimelineRepo
+func (_mock *TimelineRepo) List(ctx context.Context, userIDs []model.UserID, filter timeline.Filter) ([]timeline.TimeLine, error) {
+	ret := _mock.Called(ctx, userIDs, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []timeline.TimeLine
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []model.UserID, timeline.Filter) ([]timeline.TimeLine, error)); ok {
+		return returnFunc(ctx, userIDs, filter)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []model.UserID, timeline.Filter) []timeline.TimeLine); ok {
+		r0 = returnFunc(ctx, userIDs, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]timeline.TimeLine)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, []model.UserID, timeline.Filter) error); ok {
+		r1 = returnFunc(ctx, userIDs, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// TimelineRepo_List_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'List'
+type TimelineRepo_List_Call struct {
+	*mock.Call
+}
+
+// List is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userIDs []model.UserID
+//   - filter timeline.Filter
+func (_e *TimelineRepo_Expecter) List(ctx interface{}, userIDs interface{}, filter interface{}) *TimelineRepo_List_Call {
+	return &TimelineRepo_List_Call{Call: _e.mock.On("List", ctx, userIDs, filter)}
+}
+
+func (_c *TimelineRepo_List_Call) Run(run func(ctx context.Context, userIDs []model.UserID, filter timeline.Filter)) *TimelineRepo_List_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []model.UserID
+		if args[1] != nil {
+			arg1 = args[1].([]model.UserID)
+		}
+		var arg2 timeline.Filter
+		if args[2] != nil {
+			arg2 = args[2].(timeline.Filter)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *TimelineRepo_List_Call) Return(timeLines []timeline.TimeLine, err error) *TimelineRepo_List_Call {
+	_c.Call.Return(timeLines, err)
+	return _c
+}
+
+func (_c *TimelineRepo_List_Call) RunAndReturn(run func(ctx context.Context, userIDs []model.UserID, filter timeline.Filter) ([]timeline.TimeLine, error)) *TimelineRepo_List_Call {
+	_c.Call.Return(run)
+	return _c
+}
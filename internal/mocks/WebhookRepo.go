@@ -0,0 +1,480 @@
+// Code generated by mockery; DO NOT EDIT.
+// github.com/vektra/mockery
+// template: testify
+
+package mocks
+
+import (
+	"context"
+
+	"github.com/bangumi/server/internal/webhook"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// NewWebhookRepo creates a new instance of WebhookRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWebhookRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *WebhookRepo {
+	mock := &WebhookRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// WebhookRepo is an autogenerated mock type for the Repo type
+type WebhookRepo struct {
+	mock.Mock
+}
+
+type WebhookRepo_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *WebhookRepo) EXPECT() *WebhookRepo_Expecter {
+	return &WebhookRepo_Expecter{mock: &_m.Mock}
+}
+
+// AddDelivery provides a mock function for the type WebhookRepo
+func (_mock *WebhookRepo) AddDelivery(ctx context.Context, webhookID string, d webhook.Delivery) error {
+	ret := _mock.Called(ctx, webhookID, d)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddDelivery")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, webhook.Delivery) error); ok {
+		r0 = returnFunc(ctx, webhookID, d)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// WebhookRepo_AddDelivery_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddDelivery'
+type WebhookRepo_AddDelivery_Call struct {
+	*mock.Call
+}
+
+// AddDelivery is a helper method to define mock.On call
+//   - ctx context.Context
+//   - webhookID string
+//   - d webhook.Delivery
+func (_e *WebhookRepo_Expecter) AddDelivery(ctx interface{}, webhookID interface{}, d interface{}) *WebhookRepo_AddDelivery_Call {
+	return &WebhookRepo_AddDelivery_Call{Call: _e.mock.On("AddDelivery", ctx, webhookID, d)}
+}
+
+func (_c *WebhookRepo_AddDelivery_Call) Run(run func(ctx context.Context, webhookID string, d webhook.Delivery)) *WebhookRepo_AddDelivery_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 webhook.Delivery
+		if args[2] != nil {
+			arg2 = args[2].(webhook.Delivery)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *WebhookRepo_AddDelivery_Call) Return(err error) *WebhookRepo_AddDelivery_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *WebhookRepo_AddDelivery_Call) RunAndReturn(run func(ctx context.Context, webhookID string, d webhook.Delivery) error) *WebhookRepo_AddDelivery_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Create provides a mock function for the type WebhookRepo
+func (_mock *WebhookRepo) Create(ctx context.Context, w webhook.Webhook) error {
+	ret := _mock.Called(ctx, w)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, webhook.Webhook) error); ok {
+		r0 = returnFunc(ctx, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// WebhookRepo_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type WebhookRepo_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - w webhook.Webhook
+func (_e *WebhookRepo_Expecter) Create(ctx interface{}, w interface{}) *WebhookRepo_Create_Call {
+	return &WebhookRepo_Create_Call{Call: _e.mock.On("Create", ctx, w)}
+}
+
+func (_c *WebhookRepo_Create_Call) Run(run func(ctx context.Context, w webhook.Webhook)) *WebhookRepo_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 webhook.Webhook
+		if args[1] != nil {
+			arg1 = args[1].(webhook.Webhook)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *WebhookRepo_Create_Call) Return(err error) *WebhookRepo_Create_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *WebhookRepo_Create_Call) RunAndReturn(run func(ctx context.Context, w webhook.Webhook) error) *WebhookRepo_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Delete provides a mock function for the type WebhookRepo
+func (_mock *WebhookRepo) Delete(ctx context.Context, w webhook.Webhook) error {
+	ret := _mock.Called(ctx, w)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, webhook.Webhook) error); ok {
+		r0 = returnFunc(ctx, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// WebhookRepo_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type WebhookRepo_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - w webhook.Webhook
+func (_e *WebhookRepo_Expecter) Delete(ctx interface{}, w interface{}) *WebhookRepo_Delete_Call {
+	return &WebhookRepo_Delete_Call{Call: _e.mock.On("Delete", ctx, w)}
+}
+
+func (_c *WebhookRepo_Delete_Call) Run(run func(ctx context.Context, w webhook.Webhook)) *WebhookRepo_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 webhook.Webhook
+		if args[1] != nil {
+			arg1 = args[1].(webhook.Webhook)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *WebhookRepo_Delete_Call) Return(err error) *WebhookRepo_Delete_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *WebhookRepo_Delete_Call) RunAndReturn(run func(ctx context.Context, w webhook.Webhook) error) *WebhookRepo_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Get provides a mock function for the type WebhookRepo
+func (_mock *WebhookRepo) Get(ctx context.Context, id string) (webhook.Webhook, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 webhook.Webhook
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) (webhook.Webhook, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) webhook.Webhook); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		r0 = ret.Get(0).(webhook.Webhook)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// WebhookRepo_Get_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Get'
+type WebhookRepo_Get_Call struct {
+	*mock.Call
+}
+
+// Get is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *WebhookRepo_Expecter) Get(ctx interface{}, id interface{}) *WebhookRepo_Get_Call {
+	return &WebhookRepo_Get_Call{Call: _e.mock.On("Get", ctx, id)}
+}
+
+func (_c *WebhookRepo_Get_Call) Run(run func(ctx context.Context, id string)) *WebhookRepo_Get_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *WebhookRepo_Get_Call) Return(webhook1 webhook.Webhook, err error) *WebhookRepo_Get_Call {
+	_c.Call.Return(webhook1, err)
+	return _c
+}
+
+func (_c *WebhookRepo_Get_Call) RunAndReturn(run func(ctx context.Context, id string) (webhook.Webhook, error)) *WebhookRepo_Get_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListAll provides a mock function for the type WebhookRepo
+func (_mock *WebhookRepo) ListAll(ctx context.Context) ([]webhook.Webhook, error) {
+	ret := _mock.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAll")
+	}
+
+	var r0 []webhook.Webhook
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context) ([]webhook.Webhook, error)); ok {
+		return returnFunc(ctx)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context) []webhook.Webhook); ok {
+		r0 = returnFunc(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webhook.Webhook)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = returnFunc(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// WebhookRepo_ListAll_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListAll'
+type WebhookRepo_ListAll_Call struct {
+	*mock.Call
+}
+
+// ListAll is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *WebhookRepo_Expecter) ListAll(ctx interface{}) *WebhookRepo_ListAll_Call {
+	return &WebhookRepo_ListAll_Call{Call: _e.mock.On("ListAll", ctx)}
+}
+
+func (_c *WebhookRepo_ListAll_Call) Run(run func(ctx context.Context)) *WebhookRepo_ListAll_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *WebhookRepo_ListAll_Call) Return(webhooks []webhook.Webhook, err error) *WebhookRepo_ListAll_Call {
+	_c.Call.Return(webhooks, err)
+	return _c
+}
+
+func (_c *WebhookRepo_ListAll_Call) RunAndReturn(run func(ctx context.Context) ([]webhook.Webhook, error)) *WebhookRepo_ListAll_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListByToken provides a mock function for the type WebhookRepo
+func (_mock *WebhookRepo) ListByToken(ctx context.Context, tokenID uint32) ([]webhook.Webhook, error) {
+	ret := _mock.Called(ctx, tokenID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListByToken")
+	}
+
+	var r0 []webhook.Webhook
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint32) ([]webhook.Webhook, error)); ok {
+		return returnFunc(ctx, tokenID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uint32) []webhook.Webhook); ok {
+		r0 = returnFunc(ctx, tokenID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webhook.Webhook)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uint32) error); ok {
+		r1 = returnFunc(ctx, tokenID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// WebhookRepo_ListByToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListByToken'
+type WebhookRepo_ListByToken_Call struct {
+	*mock.Call
+}
+
+// ListByToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tokenID uint32
+func (_e *WebhookRepo_Expecter) ListByToken(ctx interface{}, tokenID interface{}) *WebhookRepo_ListByToken_Call {
+	return &WebhookRepo_ListByToken_Call{Call: _e.mock.On("ListByToken", ctx, tokenID)}
+}
+
+func (_c *WebhookRepo_ListByToken_Call) Run(run func(ctx context.Context, tokenID uint32)) *WebhookRepo_ListByToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uint32
+		if args[1] != nil {
+			arg1 = args[1].(uint32)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *WebhookRepo_ListByToken_Call) Return(webhooks []webhook.Webhook, err error) *WebhookRepo_ListByToken_Call {
+	_c.Call.Return(webhooks, err)
+	return _c
+}
+
+func (_c *WebhookRepo_ListByToken_Call) RunAndReturn(run func(ctx context.Context, tokenID uint32) ([]webhook.Webhook, error)) *WebhookRepo_ListByToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListDeliveries provides a mock function for the type WebhookRepo
+func (_mock *WebhookRepo) ListDeliveries(ctx context.Context, webhookID string) ([]webhook.Delivery, error) {
+	ret := _mock.Called(ctx, webhookID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListDeliveries")
+	}
+
+	var r0 []webhook.Delivery
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) ([]webhook.Delivery, error)); ok {
+		return returnFunc(ctx, webhookID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string) []webhook.Delivery); ok {
+		r0 = returnFunc(ctx, webhookID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]webhook.Delivery)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = returnFunc(ctx, webhookID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// WebhookRepo_ListDeliveries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListDeliveries'
+type WebhookRepo_ListDeliveries_Call struct {
+	*mock.Call
+}
+
+// ListDeliveries is a helper method to define mock.On call
+//   - ctx context.Context
+//   - webhookID string
+func (_e *WebhookRepo_Expecter) ListDeliveries(ctx interface{}, webhookID interface{}) *WebhookRepo_ListDeliveries_Call {
+	return &WebhookRepo_ListDeliveries_Call{Call: _e.mock.On("ListDeliveries", ctx, webhookID)}
+}
+
+func (_c *WebhookRepo_ListDeliveries_Call) Run(run func(ctx context.Context, webhookID string)) *WebhookRepo_ListDeliveries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *WebhookRepo_ListDeliveries_Call) Return(deliverys []webhook.Delivery, err error) *WebhookRepo_ListDeliveries_Call {
+	_c.Call.Return(deliverys, err)
+	return _c
+}
+
+func (_c *WebhookRepo_ListDeliveries_Call) RunAndReturn(run func(ctx context.Context, webhookID string) ([]webhook.Delivery, error)) *WebhookRepo_ListDeliveries_Call {
+	_c.Call.Return(run)
+	return _c
+}
@@ -40,3 +40,11 @@ func (n noop) Del(context.Context, ...string) error {
 func (n noop) MGet(ctx context.Context, key []string, result any) error {
 	return nil
 }
+
+func (n noop) Tag(context.Context, string, string, time.Duration) error {
+	return nil
+}
+
+func (n noop) DelTag(context.Context, string) error {
+	return nil
+}
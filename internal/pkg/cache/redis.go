@@ -42,6 +42,12 @@ type RedisCache interface {
 	//	var s []struct{}
 	//	cache.MGet(ctx, keys, &s)
 	MGet(ctx context.Context, key []string, result any) error
+
+	// Tag 把 key 记录到 tag 中，之后可以使用 DelTag 删除 tag 中所有的 key.
+	// ttl 应该不小于 key 的 ttl，每次调用都会刷新 tag 的 ttl.
+	Tag(ctx context.Context, tag string, key string, ttl time.Duration) error
+	// DelTag 删除 tag 中记录的所有 key 和 tag 本身.
+	DelTag(ctx context.Context, tag string) error
 }
 
 // NewRedisCache create a redis backed cache.
@@ -138,3 +144,26 @@ func (c redisCache) Del(ctx context.Context, keys ...string) error {
 	err := c.ru.Do(ctx, c.ru.B().Del().Key(keys...).Build()).Error()
 	return errgo.Wrap(err, "redis.Del")
 }
+
+func (c redisCache) Tag(ctx context.Context, tag string, key string, ttl time.Duration) error {
+	for _, resp := range c.ru.DoMulti(ctx,
+		c.ru.B().Sadd().Key(tag).Member(key).Build(),
+		c.ru.B().Expire().Key(tag).Seconds(int64(ttl.Seconds())).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			return errgo.Wrap(err, "redis sadd")
+		}
+	}
+
+	return nil
+}
+
+func (c redisCache) DelTag(ctx context.Context, tag string) error {
+	keys, err := c.ru.Do(ctx, c.ru.B().Smembers().Key(tag).Build()).AsStrSlice()
+	if err != nil {
+		return errgo.Wrap(err, "redis smembers")
+	}
+
+	err = c.ru.Do(ctx, c.ru.B().Del().Key(append(keys, tag)...).Build()).Error()
+	return errgo.Wrap(err, "redis.Del")
+}
@@ -67,3 +67,24 @@ func TestRedisCache_Del(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, exist)
 }
+
+func TestRedisCache_DelTag(t *testing.T) {
+	t.Parallel()
+
+	var tag = t.Name() + "redis_tag"
+	var keys = []string{t.Name() + "redis_key_1", t.Name() + "redis_key_2"}
+
+	r := test.GetRedis(t)
+	c := cache.NewRedisCache(r)
+
+	for _, key := range keys {
+		require.NoError(t, c.Set(context.Background(), key, "", time.Hour))
+		require.NoError(t, c.Tag(context.Background(), tag, key, time.Hour))
+	}
+
+	require.NoError(t, c.DelTag(context.Background(), tag))
+
+	n, err := r.Do(context.TODO(), r.B().Exists().Key(append(keys, tag)...).Build()).AsInt64()
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
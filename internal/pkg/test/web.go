@@ -45,6 +45,7 @@ import (
 	"github.com/bangumi/server/internal/tag"
 	"github.com/bangumi/server/internal/timeline"
 	"github.com/bangumi/server/internal/user"
+	"github.com/bangumi/server/internal/webhook"
 	"github.com/bangumi/server/web"
 	"github.com/bangumi/server/web/handler"
 )
@@ -62,8 +63,11 @@ type Mock struct {
 	IndexRepo         index.Repo
 	RevisionRepo      revision.Repo
 	CollectionRepo    collections.Repo
+	WebhookRepo       webhook.Repo
+	TimelineRepo      timeline.Repo
 	TimeLineSrv       timeline.Service
 	Cache             cache.RedisCache
+	Search            search.Handler
 	HTTPMock          *httpmock.MockTransport
 	Dam               *dam.Dam
 }
@@ -103,10 +107,11 @@ func GetWebApp(tb testing.TB, m Mock) *echo.Echo {
 		MockRevisionRepo(m.RevisionRepo),
 		MockTimeLineSrv(m.TimeLineSrv),
 		MockTagRepo(m.TagRepo),
+		MockWebhookRepo(m.WebhookRepo),
+		MockTimelineRepo(m.TimelineRepo),
 
 		// don't need a default mock for these repositories.
 		fx.Provide(func() collections.Repo { return m.CollectionRepo }),
-		fx.Provide(func() search.Handler { return search.NoopClient{} }),
 
 		fx.Provide(driver.NewRueidisClient),
 
@@ -121,6 +126,12 @@ func GetWebApp(tb testing.TB, m Mock) *echo.Echo {
 		options = append(options, fx.Provide(dam.New))
 	}
 
+	if m.Search == nil {
+		options = append(options, fx.Provide(func() search.Handler { return search.NoopClient{} }))
+	} else {
+		options = append(options, fx.Provide(func() search.Handler { return m.Search }))
+	}
+
 	if m.Cache == nil {
 		options = append(options, MockEmptyCache())
 	} else {
@@ -145,6 +156,20 @@ func MockRevisionRepo(repo revision.Repo) fx.Option {
 	return fx.Supply(fx.Annotate(repo, fx.As(new(revision.Repo))))
 }
 
+func MockWebhookRepo(repo webhook.Repo) fx.Option {
+	if repo == nil {
+		repo = &mocks.WebhookRepo{}
+	}
+	return fx.Supply(fx.Annotate(repo, fx.As(new(webhook.Repo))))
+}
+
+func MockTimelineRepo(repo timeline.Repo) fx.Option {
+	if repo == nil {
+		repo = &mocks.TimelineRepo{}
+	}
+	return fx.Supply(fx.Annotate(repo, fx.As(new(timeline.Repo))))
+}
+
 func MockIndexRepo(repo index.Repo) fx.Option {
 	if repo == nil {
 		mocker := &mocks.IndexRepo{}
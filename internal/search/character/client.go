@@ -2,13 +2,15 @@ package character
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
-	"strconv"
 
 	"github.com/meilisearch/meilisearch-go"
+	"github.com/redis/rueidis"
 	"github.com/trim21/errgo"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	"github.com/bangumi/server/config"
 	"github.com/bangumi/server/dal/query"
@@ -25,75 +27,137 @@ func New(
 	cfg config.AppConfig,
 	meili meilisearch.ServiceManager,
 	repo character.Repo,
+	redis rueidis.Client,
 	log *zap.Logger,
 	query *query.Query,
 ) (searcher.Searcher, error) {
 	if repo == nil {
 		return nil, fmt.Errorf("nil characterRepo")
 	}
+
 	c := &client{
-		meili: meili,
 		repo:  repo,
 		index: meili.Index(idx),
 		log:   log.Named("search").With(zap.String("index", idx)),
 		q:     query,
 	}
 
+	c.writer = searcher.NewWriter(meili, redis, c.log, idx, indexVersion, searcher.IndexSpec{
+		Type:     reflect.TypeOf(document{}),
+		RankRule: rankRule(),
+		Load:     c.load,
+		MaxID:    c.maxID,
+	})
+	c.docs = c.writer
+
 	if cfg.AppType != config.AppTypeCanal {
 		return c, nil
 	}
 
-	return c, c.canalInit(cfg)
+	if err := searcher.ValidateConfigs(cfg); err != nil {
+		return nil, errgo.Wrap(err, "validate search config")
+	}
+
+	return c, c.writer.CanalInit(context.Background(), c.OnUpdate)
 }
 
 type client struct {
 	repo  character.Repo
-	index meilisearch.IndexManager
+	index searcher.SearchIndex
+	docs  searcher.DocumentWriter
 
-	meili meilisearch.ServiceManager
-	log   *zap.Logger
-	q     *query.Query
+	// writer 只在使用 meilisearch 时存在，用于维护索引
+	writer *searcher.Writer
+
+	log *zap.Logger
+	q   *query.Query
 }
 
-func (c *client) canalInit(cfg config.AppConfig) error {
-	if err := searcher.ValidateConfigs(cfg); err != nil {
-		return errgo.Wrap(err, "validate search config")
-	}
-	shouldCreateIndex, err := searcher.NeedFirstRun(c.meili, idx)
-	if err != nil {
-		return err
+func (c *client) ReconcileSettings(ctx context.Context, dryRun bool) ([]searcher.SettingDiff, error) {
+	if c.writer == nil {
+		return nil, searcher.ErrEmbedded
 	}
-	if shouldCreateIndex {
-		go c.firstRun()
+
+	return c.writer.ReconcileSettings(ctx, dryRun)
+}
+
+func (c *client) DeadLetter() *searcher.DeadLetter {
+	if c.writer == nil {
+		return nil
 	}
-	return nil
+
+	return c.writer.DeadLetter()
 }
 
-//nolint:funlen
-func (c *client) firstRun() {
-	c.log.Info("search initialize")
-	rt := reflect.TypeOf(document{})
-	searcher.InitIndex(c.log, c.meili, idx, rt, rankRule())
+func (c *client) Reindex(
+	ctx context.Context,
+	fromID model.CharacterID,
+	batchSize int,
+	onBatch func(searcher.Progress) error,
+) error {
+	if c.writer == nil {
+		return searcher.ErrEmbedded
+	}
 
-	ctx := context.Background()
+	return c.writer.Reindex(ctx, fromID, batchSize, onBatch)
+}
 
+// maxID 返回数据库中最大的 id，没有数据时返回 0.
+func (c *client) maxID(ctx context.Context) (model.CharacterID, error) {
 	maxItem, err := c.q.Character.WithContext(ctx).Limit(1).Order(c.q.Character.ID.Desc()).Take()
 	if err != nil {
-		c.log.Fatal("failed to get current max id", zap.Error(err))
-		return
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+
+		return 0, errgo.Wrap(err, "failed to get current max id")
 	}
 
-	c.log.Info(fmt.Sprintf("run full search index with max %s id %d", idx, maxItem.ID))
+	return maxItem.ID, nil
+}
 
-	width := len(strconv.Itoa(int(maxItem.ID)))
-	for i := model.CharacterID(1); i <= maxItem.ID; i++ {
-		if i%10000 == 0 {
-			c.log.Info(fmt.Sprintf("progress %*d/%d", width, i, maxItem.ID))
-		}
+func (c *client) load(ctx context.Context, ids []model.CharacterID) ([]searcher.Document, []model.CharacterID, error) {
+	characters, err := c.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, errgo.Wrap(err, "characterRepo.GetByIDs")
+	}
 
-		err := c.OnUpdate(ctx, i)
-		if err != nil {
-			c.log.Error("error when updating", zap.Error(err))
+	subjects, err := c.subjects(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var docs = make([]searcher.Document, 0, len(characters))
+	var removed []model.CharacterID
+	for _, id := range ids {
+		s, ok := characters[id]
+		if !ok || s.Redirect != 0 {
+			removed = append(removed, id)
+			continue
 		}
+
+		docs = append(docs, extract(&s, subjects[id]))
 	}
+
+	return docs, removed, nil
+}
+
+// subjects 返回角色出场的条目.
+func (c *client) subjects(
+	ctx context.Context,
+	ids []model.CharacterID,
+) (map[model.CharacterID][]model.SubjectID, error) {
+	relations, err := c.q.CharacterSubjects.WithContext(ctx).
+		Select(c.q.CharacterSubjects.CharacterID, c.q.CharacterSubjects.SubjectID).
+		Where(c.q.CharacterSubjects.CharacterID.In(ids...)).Find()
+	if err != nil {
+		return nil, errgo.Wrap(err, "load character subjects")
+	}
+
+	subjects := make(map[model.CharacterID][]model.SubjectID, len(ids))
+	for _, r := range relations {
+		subjects[r.CharacterID] = append(subjects[r.CharacterID], r.SubjectID)
+	}
+
+	return subjects, nil
 }
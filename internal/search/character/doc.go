@@ -7,15 +7,27 @@ import (
 
 	"github.com/bangumi/server/internal/model"
 	"github.com/bangumi/server/internal/search/searcher"
+	"github.com/bangumi/server/web/res"
 )
 
+// indexVersion 修改 document 或者 rankRule 后需要增加，见 [searcher.Writer].
+const indexVersion = 6
+
 type document struct {
-	ID      model.CharacterID `json:"id"`
-	Name    string            `json:"name" searchable:"true"`
-	Aliases []string          `json:"aliases,omitempty" searchable:"true"`
-	Comment uint32            `json:"comment" sortable:"true"`
-	Collect uint32            `json:"collect" sortable:"true"`
-	NSFW    bool              `json:"nsfw" filterable:"true"`
+	ID       model.CharacterID `json:"id"`
+	Name     string            `json:"name" searchable:"true"`
+	Aliases  []string          `json:"aliases,omitempty" searchable:"true"`
+	Phonetic []string          `json:"phonetic,omitempty" searchable:"true"`
+	NameCN   string            `json:"name_cn,omitempty"` // name_cn 和 image 只用于输入补全
+	Image    string            `json:"image,omitempty"`
+	Comment  uint32            `json:"comment" sortable:"true"`
+	Collect  uint32            `json:"collect" sortable:"true"`
+	NSFW     bool              `json:"nsfw" filterable:"true"`
+
+	Gender    string            `json:"gender,omitempty" filterable:"true"`
+	BloodType uint8             `json:"blood_type,omitempty" filterable:"true"`
+	BirthYear uint16            `json:"birth_year,omitempty" filterable:"true"`
+	Subjects  []model.SubjectID `json:"subject,omitempty" filterable:"true"` // 出场的条目
 }
 
 func (d *document) GetID() string {
@@ -38,15 +50,33 @@ func rankRule() *[]string {
 	}
 }
 
-func extract(c *model.Character) searcher.Document {
+func extract(c *model.Character, subjects []model.SubjectID) searcher.Document {
 	w := wiki.ParseOmitError(c.Infobox)
+	aliases := searcher.ExtractAliases(w)
 
 	return &document{
-		ID:      c.ID,
-		Name:    c.Name,
-		Aliases: searcher.ExtractAliases(w),
-		Comment: c.CommentCount,
-		Collect: c.CollectCount,
-		NSFW:    c.NSFW,
+		ID:       c.ID,
+		Name:     c.Name,
+		Aliases:  aliases,
+		Phonetic: searcher.PhoneticAliases(append([]string{c.Name}, aliases...)...),
+		NameCN:   searcher.ExtractNameCN(w),
+		Image:    c.Image,
+		Comment:  c.CommentCount,
+		Collect:  c.CollectCount,
+		NSFW:     c.NSFW,
+
+		Gender:    res.GenderMap[c.FieldGender],
+		BloodType: c.FieldBloodType,
+		BirthYear: birthYear(c.FieldBirthYear, w),
+		Subjects:  subjects,
 	}
 }
+
+// birthYear 优先使用数据库中的生日，没有时从 infobox 中读取.
+func birthYear(year uint16, w wiki.Wiki) uint16 {
+	if year != 0 {
+		return year
+	}
+
+	return searcher.ExtractBirthYear(w)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/bangumi/server/internal/model"
 	"github.com/bangumi/server/internal/pkg/generic/slice"
 	"github.com/bangumi/server/internal/pkg/null"
+	"github.com/bangumi/server/internal/search/searcher"
 	"github.com/bangumi/server/web/accessor"
 	"github.com/bangumi/server/web/req"
 	"github.com/bangumi/server/web/res"
@@ -23,14 +24,31 @@ const maxLimit = 20
 type Req struct {
 	Keyword string    `json:"keyword"`
 	Filter  ReqFilter `json:"filter"`
+	Facets  []string  `json:"facets"`
+
+	// Highlight 为 true 时返回匹配到关键词的字段
+	Highlight bool `json:"highlight"`
 }
 
+var allowedFacets = []string{"nsfw", "gender", "blood_type", "birth_year"}
+
 type ReqFilter struct { //nolint:musttag
+	Gender    []string          `json:"gender"`     // or
+	BloodType []uint8           `json:"blood_type"` // or
+	BirthYear []string          `json:"birth_year"` // and
+	Subjects  []model.SubjectID `json:"subject"`    // and
+
 	NSFW null.Bool `json:"nsfw"`
 }
 
 type hit struct {
-	ID model.CharacterID `json:"id"`
+	Formatted searcher.Formatted `json:"_formatted"` //nolint:tagliatelle
+	ID        model.CharacterID  `json:"id"`
+}
+
+type ResponseCharacter struct {
+	res.CharacterV0
+	Highlight []searcher.Match `json:"highlight,omitempty"`
 }
 
 //nolint:funlen
@@ -50,7 +68,16 @@ func (c *client) Handle(ctx *echo.Context) error {
 		r.Filter.NSFW = null.Bool{Set: true, Value: false}
 	}
 
-	result, err := c.doSearch(r.Keyword, filterToMeiliFilter(r.Filter), q.Limit, q.Offset)
+	if err = searcher.CheckFacets(r.Facets, allowedFacets...); err != nil {
+		return err
+	}
+
+	meiliFilter, err := filterToMeiliFilter(r.Filter)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.doSearch(r.Keyword, meiliFilter, r.Facets, r.Highlight, q.Limit, q.Offset)
 	if err != nil {
 		return errgo.Wrap(err, "search")
 	}
@@ -66,27 +93,35 @@ func (c *client) Handle(ctx *echo.Context) error {
 		return errgo.Wrap(err, "characterRepo.GetByIDs")
 	}
 
-	var data = make([]res.CharacterV0, 0, len(characters))
-	for _, id := range ids {
-		s, ok := characters[id]
+	var data = make([]ResponseCharacter, 0, len(characters))
+	for _, h := range hits {
+		s, ok := characters[h.ID]
 		if !ok {
 			continue
 		}
-		character := res.ConvertModelCharacter(s)
+		character := ResponseCharacter{CharacterV0: res.ConvertModelCharacter(s)}
+		if r.Highlight {
+			character.Highlight = h.Formatted.Matches("name", "aliases")
+		}
 		data = append(data, character)
 	}
 
-	return ctx.JSON(http.StatusOK, res.Paged{
-		Data:   data,
-		Total:  result.EstimatedTotalHits,
-		Limit:  q.Limit,
-		Offset: q.Offset,
+	return ctx.JSON(http.StatusOK, searcher.PagedFacets{
+		Paged: res.Paged{
+			Data:   data,
+			Total:  result.EstimatedTotalHits,
+			Limit:  q.Limit,
+			Offset: q.Offset,
+		},
+		Facets: result.FacetDistribution,
 	})
 }
 
 func (c *client) doSearch(
 	words string,
 	filter [][]string,
+	facets []string,
+	highlight bool,
 	limit, offset int,
 ) (*meiliSearchResponse, error) {
 	if limit == 0 {
@@ -95,11 +130,18 @@ func (c *client) doSearch(
 		limit = 50
 	}
 
-	raw, err := c.index.SearchRaw(words, &meilisearch.SearchRequest{
+	searchReq := &meilisearch.SearchRequest{
 		Offset: int64(offset),
 		Limit:  int64(limit),
 		Filter: filter,
-	})
+		Facets: facets,
+	}
+
+	if highlight {
+		searcher.SetHighlight(searchReq, []string{"name", "aliases"})
+	}
+
+	raw, err := c.index.SearchRaw(words, searchReq)
 	if err != nil {
 		return nil, errgo.Wrap(err, "meilisearch search")
 	}
@@ -115,14 +157,34 @@ func (c *client) doSearch(
 type meiliSearchResponse struct {
 	Hits               json.RawMessage `json:"hits"`
 	EstimatedTotalHits int64           `json:"estimatedTotalHits"` //nolint:tagliatelle
+	FacetDistribution  searcher.Facets `json:"facetDistribution"`  //nolint:tagliatelle
 }
 
-func filterToMeiliFilter(req ReqFilter) [][]string {
-	var filter = make([][]string, 0, 1)
+func filterToMeiliFilter(req ReqFilter) ([][]string, error) {
+	filter, err := searcher.IntFilter("birth_year", req.BirthYear)
+	if err != nil {
+		return nil, err
+	}
+
+	// OR
+
+	if len(req.Gender) != 0 {
+		filter = append(filter, searcher.AnyOf("gender", req.Gender))
+	}
+
+	if len(req.BloodType) != 0 {
+		filter = append(filter, searcher.AnyOf("blood_type", req.BloodType))
+	}
 
 	if req.NSFW.Set {
 		filter = append(filter, []string{fmt.Sprintf("nsfw = %t", req.NSFW.Value)})
 	}
 
-	return filter
+	// AND
+
+	for _, id := range req.Subjects {
+		filter = append(filter, []string{fmt.Sprintf("subject = %d", id)})
+	}
+
+	return filter, nil
 }
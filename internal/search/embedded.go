@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package search
+
+import (
+	"github.com/redis/rueidis"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/config"
+	"github.com/bangumi/server/dal/query"
+	"github.com/bangumi/server/internal/character"
+	"github.com/bangumi/server/internal/index"
+	"github.com/bangumi/server/internal/person"
+	"github.com/bangumi/server/internal/pkg/cache"
+	characterSearcher "github.com/bangumi/server/internal/search/character"
+	indexSearcher "github.com/bangumi/server/internal/search/index"
+	personSearcher "github.com/bangumi/server/internal/search/person"
+	"github.com/bangumi/server/internal/search/searcher"
+	subjectSearcher "github.com/bangumi/server/internal/search/subject"
+	"github.com/bangumi/server/internal/subject"
+	"github.com/bangumi/server/internal/user"
+)
+
+// newEmbedded 创建使用内存索引的搜索，所有数据在启动时从数据库导入，不会收到 canal 的更新.
+func newEmbedded(
+	cfg config.AppConfig,
+	subjectRepo subject.Repo,
+	characterRepo character.Repo,
+	personRepo person.Repo,
+	indexRepo index.Repo,
+	userRepo user.Repo,
+	redis rueidis.Client,
+	log *zap.Logger,
+	query *query.Query,
+) (Client, error) {
+	set := searcher.EmbeddedSet{}
+
+	subject, err := subjectSearcher.NewEmbedded(set, subjectRepo, redis, log, query)
+	if err != nil {
+		return nil, errgo.Wrap(err, "subject search")
+	}
+	character, err := characterSearcher.NewEmbedded(set, characterRepo, log, query)
+	if err != nil {
+		return nil, errgo.Wrap(err, "character search")
+	}
+	person, err := personSearcher.NewEmbedded(set, personRepo, log, query)
+	if err != nil {
+		return nil, errgo.Wrap(err, "person search")
+	}
+	index, err := indexSearcher.NewEmbedded(set, indexRepo, userRepo, log, query)
+	if err != nil {
+		return nil, errgo.Wrap(err, "index search")
+	}
+
+	return &Search{
+		searchers: map[SearchTarget]searcher.Searcher{
+			SearchTargetSubject:   subject,
+			SearchTargetCharacter: character,
+			SearchTargetPerson:    person,
+			SearchTargetIndex:     index,
+		},
+		meili:          set,
+		cache:          cache.NewRedisCache(redis),
+		log:            log.Named("search"),
+		suggestTimeout: cfg.Search.MeiliSearch.SuggestTimeout,
+	}, nil
+}
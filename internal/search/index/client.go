@@ -0,0 +1,142 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/redis/rueidis"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/bangumi/server/config"
+	"github.com/bangumi/server/dal/query"
+	"github.com/bangumi/server/internal/index"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/search/searcher"
+	"github.com/bangumi/server/internal/user"
+)
+
+const (
+	idx = "indices"
+)
+
+func New(
+	cfg config.AppConfig,
+	meili meilisearch.ServiceManager,
+	repo index.Repo,
+	userRepo user.Repo,
+	redis rueidis.Client,
+	log *zap.Logger,
+	query *query.Query,
+) (searcher.Searcher, error) {
+	if repo == nil {
+		return nil, fmt.Errorf("nil indexRepo")
+	}
+
+	c := &client{
+		repo:     repo,
+		userRepo: userRepo,
+		index:    meili.Index(idx),
+		log:      log.Named("search").With(zap.String("index", idx)),
+		q:        query,
+	}
+
+	c.writer = searcher.NewWriter(meili, redis, c.log, idx, indexVersion, searcher.IndexSpec{
+		Type:     reflect.TypeOf(document{}),
+		RankRule: rankRule(),
+		Load:     c.load,
+		MaxID:    c.maxID,
+	})
+	c.docs = c.writer
+
+	if cfg.AppType != config.AppTypeCanal {
+		return c, nil
+	}
+
+	if err := searcher.ValidateConfigs(cfg); err != nil {
+		return nil, errgo.Wrap(err, "validate search config")
+	}
+
+	return c, c.writer.CanalInit(context.Background(), c.OnUpdate)
+}
+
+type client struct {
+	repo     index.Repo
+	userRepo user.Repo
+	index    searcher.SearchIndex
+	docs     searcher.DocumentWriter
+
+	// writer 只在使用 meilisearch 时存在，用于维护索引
+	writer *searcher.Writer
+
+	log *zap.Logger
+	q   *query.Query
+}
+
+func (c *client) ReconcileSettings(ctx context.Context, dryRun bool) ([]searcher.SettingDiff, error) {
+	if c.writer == nil {
+		return nil, searcher.ErrEmbedded
+	}
+
+	return c.writer.ReconcileSettings(ctx, dryRun)
+}
+
+func (c *client) DeadLetter() *searcher.DeadLetter {
+	if c.writer == nil {
+		return nil
+	}
+
+	return c.writer.DeadLetter()
+}
+
+func (c *client) Reindex(
+	ctx context.Context,
+	fromID model.IndexID,
+	batchSize int,
+	onBatch func(searcher.Progress) error,
+) error {
+	if c.writer == nil {
+		return searcher.ErrEmbedded
+	}
+
+	return c.writer.Reindex(ctx, fromID, batchSize, onBatch)
+}
+
+// maxID 返回数据库中最大的 id，没有数据时返回 0.
+func (c *client) maxID(ctx context.Context) (model.IndexID, error) {
+	maxItem, err := c.q.Index.WithContext(ctx).Limit(1).Order(c.q.Index.ID.Desc()).Take()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+
+		return 0, errgo.Wrap(err, "failed to get current max id")
+	}
+
+	return maxItem.ID, nil
+}
+
+func (c *client) load(ctx context.Context, ids []model.IndexID) ([]searcher.Document, []model.IndexID, error) {
+	indices, err := c.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, errgo.Wrap(err, "indexRepo.GetByIDs")
+	}
+
+	var docs = make([]searcher.Document, 0, len(indices))
+	var removed []model.IndexID
+	for _, id := range ids {
+		i, ok := indices[id]
+		if !ok || !searchable(i) {
+			removed = append(removed, id)
+			continue
+		}
+
+		docs = append(docs, extract(&i))
+	}
+
+	return docs, removed, nil
+}
@@ -0,0 +1,57 @@
+package index
+
+import (
+	"strconv"
+
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+// indexVersion 修改 document 或者 rankRule 后需要增加，见 [searcher.Writer].
+const indexVersion = 1
+
+// 私有和已删除的目录不会被索引.
+type document struct {
+	ID           model.IndexID `json:"id"`
+	Title        string        `json:"title" searchable:"true"`
+	Description  string        `json:"description" searchable:"true"`
+	Creator      model.UserID  `json:"creator" filterable:"true"`
+	SubjectCount uint32        `json:"subject_count" filterable:"true" sortable:"true"`
+	Collect      uint32        `json:"collect" sortable:"true"`
+	NSFW         bool          `json:"nsfw" filterable:"true"`
+}
+
+func (d *document) GetID() string {
+	return strconv.FormatUint(uint64(d.ID), 10)
+}
+
+func rankRule() *[]string {
+	return &[]string{
+		// 相似度最优先
+		"exactness",
+		"words",
+		"typo",
+		"proximity",
+		"attribute",
+		"sort",
+		"collect:desc",
+		"subject_count:desc",
+		"nsfw:asc",
+	}
+}
+
+func searchable(i model.Index) bool {
+	return i.Privacy == model.IndexPrivacyPublic
+}
+
+func extract(i *model.Index) searcher.Document {
+	return &document{
+		ID:           i.ID,
+		Title:        i.Title,
+		Description:  i.Description,
+		Creator:      i.CreatorID,
+		SubjectCount: i.Total,
+		Collect:      i.Collects,
+		NSFW:         i.NSFW,
+	}
+}
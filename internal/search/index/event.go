@@ -0,0 +1,35 @@
+package index
+
+import (
+	"context"
+	"errors"
+
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/domain/gerr"
+	"github.com/bangumi/server/internal/model"
+)
+
+func (c *client) OnAdded(ctx context.Context, id model.IndexID) error {
+	return c.OnUpdate(ctx, id)
+}
+
+func (c *client) OnUpdate(ctx context.Context, id model.IndexID) error {
+	i, err := c.repo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, gerr.ErrNotFound) {
+			return c.OnDelete(ctx, id)
+		}
+		return errgo.Wrap(err, "indexRepo.Get")
+	}
+
+	if !searchable(i) {
+		return c.OnDelete(ctx, id)
+	}
+
+	return c.docs.UpdateDocuments(ctx, extract(&i))
+}
+
+func (c *client) OnDelete(ctx context.Context, id model.IndexID) error {
+	return errgo.Wrap(c.docs.DeleteDocument(ctx, id), "search")
+}
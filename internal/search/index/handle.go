@@ -0,0 +1,163 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/samber/lo"
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/generic/slice"
+	"github.com/bangumi/server/internal/pkg/null"
+	"github.com/bangumi/server/web/accessor"
+	"github.com/bangumi/server/web/req"
+	"github.com/bangumi/server/web/res"
+)
+
+const defaultLimit = 10
+const maxLimit = 20
+
+type Req struct {
+	Keyword string    `json:"keyword"`
+	Sort    string    `json:"sort"`
+	Filter  ReqFilter `json:"filter"`
+}
+
+type ReqFilter struct { //nolint:musttag
+	Creator []model.UserID `json:"creator"` // or
+
+	// if NSFW index is enabled
+	NSFW null.Bool `json:"nsfw"`
+}
+
+type hit struct {
+	ID model.IndexID `json:"id"`
+}
+
+//nolint:funlen
+func (c *client) Handle(ctx *echo.Context) error {
+	auth := accessor.GetFromCtx(ctx)
+	q, err := req.GetPageQuerySoftLimit(ctx, defaultLimit, maxLimit)
+	if err != nil {
+		return err
+	}
+
+	var r Req
+	if err = json.NewDecoder(ctx.Request().Body).Decode(&r); err != nil {
+		return res.JSONError(ctx, err)
+	}
+
+	if !auth.AllowNSFW() {
+		r.Filter.NSFW = null.Bool{Set: true, Value: false}
+	}
+
+	result, err := c.doSearch(r.Keyword, filterToMeiliFilter(r.Filter), r.Sort, q.Limit, q.Offset)
+	if err != nil {
+		return errgo.Wrap(err, "search")
+	}
+
+	var hits []hit
+	if err = json.Unmarshal(result.Hits, &hits); err != nil {
+		return errgo.Wrap(err, "json.Unmarshal")
+	}
+	ids := slice.Map(hits, func(h hit) model.IndexID { return h.ID })
+
+	indices, err := c.repo.GetByIDs(ctx.Request().Context(), ids)
+	if err != nil {
+		return errgo.Wrap(err, "indexRepo.GetByIDs")
+	}
+
+	users, err := c.userRepo.GetByIDs(ctx.Request().Context(),
+		lo.Uniq(lo.MapToSlice(indices, func(_ model.IndexID, i model.Index) model.UserID { return i.CreatorID })))
+	if err != nil {
+		return errgo.Wrap(err, "userRepo.GetByIDs")
+	}
+
+	var data = make([]res.Index, 0, len(indices))
+	for _, id := range ids {
+		i, ok := indices[id]
+		// 搜索索引可能还没有收到目录被删除或者设为私有的事件
+		if !ok || !searchable(i) {
+			continue
+		}
+
+		if i.NSFW && !auth.AllowNSFW() {
+			continue
+		}
+
+		data = append(data, res.IndexModelToResponse(&i, users[i.CreatorID]))
+	}
+
+	return ctx.JSON(http.StatusOK, res.Paged{
+		Data:   data,
+		Total:  result.EstimatedTotalHits,
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	})
+}
+
+func (c *client) doSearch(
+	words string,
+	filter [][]string,
+	sort string,
+	limit, offset int,
+) (*meiliSearchResponse, error) {
+	if limit == 0 {
+		limit = 10
+	} else if limit > 50 {
+		limit = 50
+	}
+
+	var sortOpt []string
+	switch sort {
+	case "", "match":
+	case "collect":
+		sortOpt = []string{"collect:desc"}
+	case "subject_count":
+		sortOpt = []string{"subject_count:desc"}
+	default:
+		return nil, res.BadRequest("sort not supported")
+	}
+
+	raw, err := c.index.SearchRaw(words, &meilisearch.SearchRequest{
+		Offset: int64(offset),
+		Limit:  int64(limit),
+		Filter: filter,
+		Sort:   sortOpt,
+	})
+	if err != nil {
+		return nil, errgo.Wrap(err, "meilisearch search")
+	}
+
+	var r meiliSearchResponse
+	if err := json.Unmarshal(*raw, &r); err != nil {
+		return nil, errgo.Wrap(err, "json.Unmarshal")
+	}
+
+	return &r, nil
+}
+
+type meiliSearchResponse struct {
+	Hits               json.RawMessage `json:"hits"`
+	EstimatedTotalHits int64           `json:"estimatedTotalHits"` //nolint:tagliatelle
+}
+
+func filterToMeiliFilter(req ReqFilter) [][]string {
+	var filter = make([][]string, 0, 2)
+
+	if len(req.Creator) != 0 {
+		filter = append(filter, slice.Map(req.Creator, func(id model.UserID) string {
+			return fmt.Sprintf("creator = %d", id)
+		}))
+	}
+
+	if req.NSFW.Set {
+		filter = append(filter, []string{fmt.Sprintf("nsfw = %t", req.NSFW.Value)})
+	}
+
+	return filter
+}
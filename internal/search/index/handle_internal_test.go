@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/null"
+)
+
+func Test_ReqFilterToMeiliFilter(t *testing.T) {
+	t.Parallel()
+
+	actual := filterToMeiliFilter(ReqFilter{
+		Creator: []model.UserID{1, 2},
+		NSFW:    null.Bool{Set: true, Value: false},
+	})
+
+	require.Equal(t, [][]string{
+		{`creator = 1`, `creator = 2`},
+		{`nsfw = false`},
+	}, actual)
+}
+
+func Test_searchable(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, searchable(model.Index{Privacy: model.IndexPrivacyPublic}))
+	require.False(t, searchable(model.Index{Privacy: model.IndexPrivacyPrivate}))
+	require.False(t, searchable(model.Index{Privacy: model.IndexPrivacyDeleted}))
+}
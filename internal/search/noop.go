@@ -16,11 +16,16 @@ package search
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v5"
+
+	"github.com/bangumi/server/internal/search/searcher"
 )
 
+var errSearchDisabled = errors.New("search is not enabled, MEILISEARCH_URL is empty")
+
 var _ Client = NoopClient{}
 
 type NoopClient struct {
@@ -30,6 +35,10 @@ func (n NoopClient) Handle(c *echo.Context, _ SearchTarget) error {
 	return c.String(http.StatusOK, "search is not enable")
 }
 
+func (n NoopClient) Suggest(c *echo.Context) error {
+	return c.String(http.StatusOK, "search is not enable")
+}
+
 func (n NoopClient) EventAdded(ctx context.Context, _ uint32, _ SearchTarget) error {
 	return nil
 }
@@ -42,5 +51,23 @@ func (n NoopClient) EventDelete(_ context.Context, _ uint32, _ SearchTarget) err
 	return nil
 }
 
+func (n NoopClient) Reindex(
+	_ context.Context, _ SearchTarget, _ uint32, _ int, _ func(searcher.Progress) error,
+) error {
+	return errSearchDisabled
+}
+
+func (n NoopClient) ReconcileSettings(_ context.Context, _ SearchTarget, _ bool) ([]searcher.SettingDiff, error) {
+	return nil, errSearchDisabled
+}
+
+func (n NoopClient) ListDeadLetter(_ context.Context, _ SearchTarget) ([]searcher.DeadLetterEntry, error) {
+	return nil, errSearchDisabled
+}
+
+func (n NoopClient) ReplayDeadLetter(_ context.Context, _ SearchTarget) (int, error) {
+	return 0, errSearchDisabled
+}
+
 func (n NoopClient) Close() {
 }
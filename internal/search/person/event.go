@@ -3,10 +3,7 @@ package person
 import (
 	"context"
 	"errors"
-	"strconv"
 
-	"github.com/meilisearch/meilisearch-go"
-	"github.com/samber/lo"
 	"github.com/trim21/errgo"
 
 	"github.com/bangumi/server/domain/gerr"
@@ -26,10 +23,14 @@ func (c *client) OnAdded(ctx context.Context, id model.PersonID) error {
 		return c.OnDelete(ctx, id)
 	}
 
-	extracted := extract(&s)
+	subjects, err := c.subjects(ctx, []model.PersonID{id})
+	if err != nil {
+		return err
+	}
+
+	extracted := extract(&s, subjects[id])
 
-	_, err = c.index.UpdateDocumentsWithContext(ctx, extracted, &meilisearch.DocumentOptions{PrimaryKey: lo.ToPtr("id")})
-	return err
+	return c.docs.UpdateDocuments(ctx, extracted)
 }
 
 func (c *client) OnUpdate(ctx context.Context, id model.PersonID) error {
@@ -45,15 +46,16 @@ func (c *client) OnUpdate(ctx context.Context, id model.PersonID) error {
 		return c.OnDelete(ctx, id)
 	}
 
-	extracted := extract(&s)
+	subjects, err := c.subjects(ctx, []model.PersonID{id})
+	if err != nil {
+		return err
+	}
 
-	_, err = c.index.UpdateDocumentsWithContext(ctx, extracted, &meilisearch.DocumentOptions{PrimaryKey: lo.ToPtr("id")})
+	extracted := extract(&s, subjects[id])
 
-	return err
+	return c.docs.UpdateDocuments(ctx, extracted)
 }
 
 func (c *client) OnDelete(ctx context.Context, id model.PersonID) error {
-	_, err := c.index.DeleteDocumentWithContext(ctx, strconv.FormatUint(uint64(id), 10), nil)
-
-	return errgo.Wrap(err, "search")
+	return errgo.Wrap(c.docs.DeleteDocument(ctx, id), "search")
 }
@@ -2,6 +2,7 @@ package person
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -11,6 +12,7 @@ import (
 
 	"github.com/bangumi/server/internal/model"
 	"github.com/bangumi/server/internal/pkg/generic/slice"
+	"github.com/bangumi/server/internal/search/searcher"
 	"github.com/bangumi/server/web/req"
 	"github.com/bangumi/server/web/res"
 )
@@ -21,14 +23,30 @@ const maxLimit = 20
 type Req struct {
 	Keyword string    `json:"keyword"`
 	Filter  ReqFilter `json:"filter"`
+	Facets  []string  `json:"facets"`
+
+	// Highlight 为 true 时返回匹配到关键词的字段
+	Highlight bool `json:"highlight"`
 }
 
+var allowedFacets = []string{"career", "gender", "blood_type", "birth_year"}
+
 type ReqFilter struct { //nolint:musttag
-	Careers []string `json:"career"` // and
+	Careers   []string          `json:"career"`     // and
+	Gender    []string          `json:"gender"`     // or
+	BloodType []uint8           `json:"blood_type"` // or
+	BirthYear []string          `json:"birth_year"` // and
+	Subjects  []model.SubjectID `json:"subject"`    // and
 }
 
 type hit struct {
-	ID model.PersonID `json:"id"`
+	Formatted searcher.Formatted `json:"_formatted"` //nolint:tagliatelle
+	ID        model.PersonID     `json:"id"`
+}
+
+type ResponsePerson struct {
+	res.PersonV0
+	Highlight []searcher.Match `json:"highlight,omitempty"`
 }
 
 //nolint:funlen
@@ -43,7 +61,16 @@ func (c *client) Handle(ctx *echo.Context) error {
 		return res.JSONError(ctx, err)
 	}
 
-	result, err := c.doSearch(r.Keyword, filterToMeiliFilter(r.Filter), q.Limit, q.Offset)
+	if err = searcher.CheckFacets(r.Facets, allowedFacets...); err != nil {
+		return err
+	}
+
+	meiliFilter, err := filterToMeiliFilter(r.Filter)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.doSearch(r.Keyword, meiliFilter, r.Facets, r.Highlight, q.Limit, q.Offset)
 	if err != nil {
 		return errgo.Wrap(err, "search")
 	}
@@ -59,27 +86,35 @@ func (c *client) Handle(ctx *echo.Context) error {
 		return errgo.Wrap(err, "personRepo.GetByIDs")
 	}
 
-	var data = make([]res.PersonV0, 0, len(persons))
-	for _, id := range ids {
-		s, ok := persons[id]
+	var data = make([]ResponsePerson, 0, len(persons))
+	for _, h := range hits {
+		s, ok := persons[h.ID]
 		if !ok {
 			continue
 		}
-		person := res.ConvertModelPerson(s)
+		person := ResponsePerson{PersonV0: res.ConvertModelPerson(s)}
+		if r.Highlight {
+			person.Highlight = h.Formatted.Matches("name", "aliases")
+		}
 		data = append(data, person)
 	}
 
-	return ctx.JSON(http.StatusOK, res.Paged{
-		Data:   data,
-		Total:  result.EstimatedTotalHits,
-		Limit:  q.Limit,
-		Offset: q.Offset,
+	return ctx.JSON(http.StatusOK, searcher.PagedFacets{
+		Paged: res.Paged{
+			Data:   data,
+			Total:  result.EstimatedTotalHits,
+			Limit:  q.Limit,
+			Offset: q.Offset,
+		},
+		Facets: result.FacetDistribution,
 	})
 }
 
 func (c *client) doSearch(
 	words string,
 	filter [][]string,
+	facets []string,
+	highlight bool,
 	limit, offset int,
 ) (*meiliSearchResponse, error) {
 	if limit == 0 {
@@ -88,11 +123,18 @@ func (c *client) doSearch(
 		limit = 50
 	}
 
-	raw, err := c.index.SearchRaw(words, &meilisearch.SearchRequest{
+	searchReq := &meilisearch.SearchRequest{
 		Offset: int64(offset),
 		Limit:  int64(limit),
 		Filter: filter,
-	})
+		Facets: facets,
+	}
+
+	if highlight {
+		searcher.SetHighlight(searchReq, []string{"name", "aliases"})
+	}
+
+	raw, err := c.index.SearchRaw(words, searchReq)
 	if err != nil {
 		return nil, errgo.Wrap(err, "meilisearch search")
 	}
@@ -108,14 +150,34 @@ func (c *client) doSearch(
 type meiliSearchResponse struct {
 	Hits               json.RawMessage `json:"hits"`
 	EstimatedTotalHits int64           `json:"estimatedTotalHits"` //nolint:tagliatelle
+	FacetDistribution  searcher.Facets `json:"facetDistribution"`  //nolint:tagliatelle
 }
 
-func filterToMeiliFilter(req ReqFilter) [][]string {
-	var filter = make([][]string, 0, len(req.Careers))
+func filterToMeiliFilter(req ReqFilter) ([][]string, error) {
+	filter, err := searcher.IntFilter("birth_year", req.BirthYear)
+	if err != nil {
+		return nil, err
+	}
+
+	// OR
+
+	if len(req.Gender) != 0 {
+		filter = append(filter, searcher.AnyOf("gender", req.Gender))
+	}
+
+	if len(req.BloodType) != 0 {
+		filter = append(filter, searcher.AnyOf("blood_type", req.BloodType))
+	}
+
+	// AND
 
 	for _, career := range req.Careers {
 		filter = append(filter, []string{"career = " + strconv.Quote(career)})
 	}
 
-	return filter
+	for _, id := range req.Subjects {
+		filter = append(filter, []string{fmt.Sprintf("subject = %d", id)})
+	}
+
+	return filter, nil
 }
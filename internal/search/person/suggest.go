@@ -0,0 +1,19 @@
+package person
+
+import (
+	"github.com/meilisearch/meilisearch-go"
+
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+var _ searcher.Suggester = (*client)(nil)
+
+// SuggestRequest 人物没有 nsfw 字段.
+func (c *client) SuggestRequest(keyword string, limit int64, _ bool) *meilisearch.SearchRequest {
+	return &meilisearch.SearchRequest{
+		IndexUID:             idx,
+		Query:                keyword,
+		Limit:                limit,
+		AttributesToRetrieve: searcher.SuggestAttributes,
+	}
+}
@@ -16,62 +16,108 @@ package search
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/labstack/echo/v5"
 	"github.com/meilisearch/meilisearch-go"
+	"github.com/redis/rueidis"
 	"github.com/trim21/errgo"
 	"go.uber.org/zap"
 
 	"github.com/bangumi/server/config"
 	"github.com/bangumi/server/dal/query"
 	"github.com/bangumi/server/internal/character"
+	"github.com/bangumi/server/internal/index"
 	"github.com/bangumi/server/internal/person"
+	"github.com/bangumi/server/internal/pkg/cache"
 	characterSearcher "github.com/bangumi/server/internal/search/character"
+	indexSearcher "github.com/bangumi/server/internal/search/index"
 	personSearcher "github.com/bangumi/server/internal/search/person"
 	"github.com/bangumi/server/internal/search/searcher"
 	subjectSearcher "github.com/bangumi/server/internal/search/subject"
 	"github.com/bangumi/server/internal/subject"
+	"github.com/bangumi/server/internal/user"
 )
 
+var errDeadLetterUnsupported = errors.New("dead letter is not supported by embedded search index")
+
 type SearchTarget string
 
 const (
 	SearchTargetSubject   SearchTarget = "subject"
 	SearchTargetCharacter SearchTarget = "character"
 	SearchTargetPerson    SearchTarget = "person"
+	SearchTargetIndex     SearchTarget = "index"
 )
 
 type Client interface {
 	Handle(c *echo.Context, target SearchTarget) error
+	Suggest(c *echo.Context) error
 	Close()
 
 	EventAdded(ctx context.Context, id uint32, target SearchTarget) error
 	EventUpdate(ctx context.Context, id uint32, target SearchTarget) error
 	EventDelete(ctx context.Context, id uint32, target SearchTarget) error
+
+	// Reindex 重建 target 对应的整个索引，见 [searcher.Reindex].
+	Reindex(ctx context.Context, target SearchTarget, fromID uint32, batchSize int,
+		onBatch func(searcher.Progress) error) error
+
+	// ReconcileSettings 对比并更新 target 索引的设置，见 [searcher.ReconcileSettings].
+	ReconcileSettings(ctx context.Context, target SearchTarget, dryRun bool) ([]searcher.SettingDiff, error)
+
+	// ListDeadLetter 返回 target 索引写入失败的 document，见 [searcher.DeadLetter].
+	ListDeadLetter(ctx context.Context, target SearchTarget) ([]searcher.DeadLetterEntry, error)
+
+	// ReplayDeadLetter 忽略重试时间，立即重新写入所有失败的 document.
+	ReplayDeadLetter(ctx context.Context, target SearchTarget) (int, error)
 }
 
 type Handler interface {
 	Handle(c *echo.Context, target SearchTarget) error
+	Suggest(c *echo.Context) error
 }
 
 type Search struct {
 	searchers map[SearchTarget]searcher.Searcher
+	meili     multiSearcher
+	cache     cache.RedisCache
+	log       *zap.Logger
+
+	suggestTimeout time.Duration
+}
+
+// multiSearcher 由 [meilisearch.ServiceManager] 和 [searcher.EmbeddedSet] 实现.
+type multiSearcher interface {
+	MultiSearchWithContext(
+		ctx context.Context,
+		req *meilisearch.MultiSearchRequest,
+	) (*meilisearch.MultiSearchResponse, error)
 }
 
 // New provide a search app is AppConfig.MeiliSearchURL is empty string, return nope search client.
 //
 // see `MeiliSearchURL` and `MeiliSearchKey` in [config.AppConfig].
+// If `Search.Backend` is [config.SearchBackendEmbedded], use in-process index instead of meilisearch.
 func New(
 	cfg config.AppConfig,
 	subjectRepo subject.Repo,
 	characterRepo character.Repo,
 	personRepo person.Repo,
+	indexRepo index.Repo,
+	userRepo user.Repo,
+	redis rueidis.Client,
 	log *zap.Logger,
 	query *query.Query,
 ) (Client, error) {
+	if cfg.Search.Backend == config.SearchBackendEmbedded {
+		return newEmbedded(cfg, subjectRepo, characterRepo, personRepo, indexRepo, userRepo, redis, log, query)
+	}
+
 	if cfg.Search.MeiliSearch.URL == "" {
 		return NoopClient{}, nil
 	}
@@ -87,26 +133,36 @@ func New(
 		return nil, errgo.Wrap(err, "meilisearch")
 	}
 
-	subject, err := subjectSearcher.New(cfg, meili, subjectRepo, log, query)
+	subject, err := subjectSearcher.New(cfg, meili, subjectRepo, redis, log, query)
 	if err != nil {
 		return nil, errgo.Wrap(err, "subject search")
 	}
-	character, err := characterSearcher.New(cfg, meili, characterRepo, log, query)
+	character, err := characterSearcher.New(cfg, meili, characterRepo, redis, log, query)
 	if err != nil {
 		return nil, errgo.Wrap(err, "character search")
 	}
-	person, err := personSearcher.New(cfg, meili, personRepo, log, query)
+	person, err := personSearcher.New(cfg, meili, personRepo, redis, log, query)
 	if err != nil {
 		return nil, errgo.Wrap(err, "person search")
 	}
 
+	index, err := indexSearcher.New(cfg, meili, indexRepo, userRepo, redis, log, query)
+	if err != nil {
+		return nil, errgo.Wrap(err, "index search")
+	}
+
 	searchers := map[SearchTarget]searcher.Searcher{
 		SearchTargetSubject:   subject,
 		SearchTargetCharacter: character,
 		SearchTargetPerson:    person,
+		SearchTargetIndex:     index,
 	}
 	s := &Search{
-		searchers: searchers,
+		searchers:      searchers,
+		meili:          meili,
+		cache:          cache.NewRedisCache(redis),
+		log:            log.Named("search"),
+		suggestTimeout: cfg.Search.MeiliSearch.SuggestTimeout,
 	}
 	return s, nil
 }
@@ -143,4 +199,62 @@ func (s *Search) EventDelete(ctx context.Context, id uint32, target SearchTarget
 	return searcher.OnDelete(ctx, id)
 }
 
+func (s *Search) Reindex(
+	ctx context.Context,
+	target SearchTarget,
+	fromID uint32,
+	batchSize int,
+	onBatch func(searcher.Progress) error,
+) error {
+	searcher := s.searchers[target]
+	if searcher == nil {
+		return fmt.Errorf("searcher not found for %s", target)
+	}
+	return searcher.Reindex(ctx, fromID, batchSize, onBatch)
+}
+
+func (s *Search) ReconcileSettings(
+	ctx context.Context,
+	target SearchTarget,
+	dryRun bool,
+) ([]searcher.SettingDiff, error) {
+	searcher := s.searchers[target]
+	if searcher == nil {
+		return nil, fmt.Errorf("searcher not found for %s", target)
+	}
+	return searcher.ReconcileSettings(ctx, dryRun)
+}
+
+func (s *Search) deadLetter(target SearchTarget) (searcher.Searcher, *searcher.DeadLetter, error) {
+	searcher := s.searchers[target]
+	if searcher == nil {
+		return nil, nil, fmt.Errorf("searcher not found for %s", target)
+	}
+
+	dl := searcher.DeadLetter()
+	if dl == nil {
+		return nil, nil, errDeadLetterUnsupported
+	}
+
+	return searcher, dl, nil
+}
+
+func (s *Search) ListDeadLetter(ctx context.Context, target SearchTarget) ([]searcher.DeadLetterEntry, error) {
+	_, dl, err := s.deadLetter(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return dl.List(ctx)
+}
+
+func (s *Search) ReplayDeadLetter(ctx context.Context, target SearchTarget) (int, error) {
+	searcher, dl, err := s.deadLetter(target)
+	if err != nil {
+		return 0, err
+	}
+
+	return dl.Replay(ctx, searcher.OnUpdate, true)
+}
+
 func (s *Search) Close() {}
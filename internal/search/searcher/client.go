@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +27,15 @@ type Searcher interface {
 	OnAdded(ctx context.Context, id uint32) error
 	OnUpdate(ctx context.Context, id uint32) error
 	OnDelete(ctx context.Context, id uint32) error
+
+	// Reindex 从 fromID 开始分批重建整个索引，见 [Reindex].
+	Reindex(ctx context.Context, fromID uint32, batchSize int, onBatch func(Progress) error) error
+
+	// ReconcileSettings 更新线上索引的设置，见 [ReconcileSettings].
+	ReconcileSettings(ctx context.Context, dryRun bool) ([]SettingDiff, error)
+
+	// DeadLetter 返回写入失败的 document 队列，使用内存索引时返回 nil.
+	DeadLetter() *DeadLetter
 }
 
 type Document interface {
@@ -34,10 +43,6 @@ type Document interface {
 }
 
 func NeedFirstRun(meili meilisearch.ServiceManager, idx string) (bool, error) {
-	if os.Getenv("CHII_SEARCH_INIT") == "true" {
-		return true, nil
-	}
-
 	index, err := meili.GetIndex(idx)
 	if err != nil {
 		var e *meilisearch.Error
@@ -77,6 +82,36 @@ func ExtractAliases(w wiki.Wiki) []string {
 	return aliases
 }
 
+func pushDeadLetter(deadLetter *DeadLetter, items []Document, cause error) error {
+	ids := make([]uint32, len(items))
+	for i, item := range items {
+		id, err := strconv.ParseUint(item.GetID(), 10, 32)
+		if err != nil {
+			return errgo.Wrap(err, "parse document id")
+		}
+		ids[i] = uint32(id)
+	}
+
+	return errgo.Wrap(deadLetter.Push(context.Background(), ids, cause), "push dead letter")
+}
+
+// ExtractNameCN 返回 infobox 中的中文名，没有时返回空字符串.
+func ExtractNameCN(w wiki.Wiki) string {
+	for _, key := range []string{"简体中文名", "中文名"} {
+		for _, field := range w.Fields {
+			if field.Key != key {
+				continue
+			}
+
+			if values := GetWikiValues(field); len(values) != 0 {
+				return values[0]
+			}
+		}
+	}
+
+	return ""
+}
+
 func GetWikiValues(f wiki.Field) []string {
 	if f.Null {
 		return nil
@@ -93,7 +128,12 @@ func GetWikiValues(f wiki.Field) []string {
 	return s
 }
 
-func NewSendBatch(log *zap.Logger, index meilisearch.IndexManager) func([]Document) {
+// ErrDeadLettered 表示一批 document 多次重试仍然写入失败，id 已经放入 [DeadLetter].
+var ErrDeadLettered = errors.New("failed to send batch, documents are put into dead letter")
+
+// NewSendBatch 返回批量写入 document 的函数，多次重试仍然失败时会把 id 放入 deadLetter 并返回 [ErrDeadLettered].
+// deadLetter 为 nil 时直接返回错误.
+func NewSendBatch(log *zap.Logger, index meilisearch.IndexManager, deadLetter *DeadLetter) func([]Document) error {
 	var retrier = retry.New(
 		retry.OnRetry(func(n uint, err error) {
 			log.Warn("failed to send batch", zap.Uint("attempt", n), zap.Error(err))
@@ -107,7 +147,7 @@ func NewSendBatch(log *zap.Logger, index meilisearch.IndexManager) func([]Docume
 		}),
 	)
 
-	return func(items []Document) {
+	return func(items []Document) error {
 		log.Debug("send batch to meilisearch", zap.Int("len", len(items)))
 		err := retrier.Do(func() error {
 			_, err := index.UpdateDocuments(items, &meilisearch.DocumentOptions{PrimaryKey: lo.ToPtr("id")})
@@ -115,7 +155,18 @@ func NewSendBatch(log *zap.Logger, index meilisearch.IndexManager) func([]Docume
 		})
 		if err != nil {
 			log.Error("failed to send batch", zap.Error(err))
+			if deadLetter == nil {
+				return errgo.Wrap(err, "meilisearch.UpdateDocuments")
+			}
+
+			if e := pushDeadLetter(deadLetter, items, err); e != nil {
+				return errors.Join(errgo.Wrap(err, "meilisearch.UpdateDocuments"), e)
+			}
+
+			return fmt.Errorf("%w: %w", ErrDeadLettered, err)
 		}
+
+		return nil
 	}
 }
 
@@ -151,14 +202,16 @@ func getJSONFieldName(f reflect.StructField) string {
 	return strings.Split(t, ",")[0]
 }
 
-func InitIndex(log *zap.Logger, meili meilisearch.ServiceManager, idx string, rt reflect.Type, rankRule *[]string) {
+// InitIndex 创建索引并设置 document 定义的索引设置.
+func InitIndex(
+	log *zap.Logger, meili meilisearch.ServiceManager, idx string, rt reflect.Type, rankRule *[]string,
+) error {
 	_, err := meili.CreateIndex(&meilisearch.IndexConfig{
 		Uid:        idx,
 		PrimaryKey: "id",
 	})
 	if err != nil {
-		log.Fatal("failed to create search index", zap.Error(err))
-		return
+		return errgo.Wrap(err, "create search index")
 	}
 
 	index := meili.Index(idx)
@@ -166,8 +219,7 @@ func InitIndex(log *zap.Logger, meili meilisearch.ServiceManager, idx string, rt
 	log.Info("set sortable attributes", zap.Strings("attributes", *GetAttributes(rt, "sortable")))
 	_, err = index.UpdateSortableAttributes(GetAttributes(rt, "sortable"))
 	if err != nil {
-		log.Fatal("failed to update search index sortable attributes", zap.Error(err))
-		return
+		return errgo.Wrap(err, "update search index sortable attributes")
 	}
 
 	log.Info("set filterable attributes", zap.Strings("attributes", *GetAttributes(rt, "filterable")))
@@ -176,21 +228,20 @@ func InitIndex(log *zap.Logger, meili meilisearch.ServiceManager, idx string, rt
 			return s
 		})))
 	if err != nil {
-		log.Fatal("failed to update search index filterable attributes", zap.Error(err))
-		return
+		return errgo.Wrap(err, "update search index filterable attributes")
 	}
 
 	log.Info("set searchable attributes", zap.Strings("attributes", *GetAttributes(rt, "searchable")))
 	_, err = index.UpdateSearchableAttributes(GetAttributes(rt, "searchable"))
 	if err != nil {
-		log.Fatal("failed to update search index searchable attributes", zap.Error(err))
-		return
+		return errgo.Wrap(err, "update search index searchable attributes")
 	}
 
 	log.Info("set ranking rules", zap.Strings("rule", *rankRule))
 	_, err = index.UpdateRankingRules(rankRule)
 	if err != nil {
-		log.Fatal("failed to update search index searchable attributes", zap.Error(err))
-		return
+		return errgo.Wrap(err, "update search index ranking rules")
 	}
+
+	return nil
 }
@@ -0,0 +1,226 @@
+package searcher
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/rueidis"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+)
+
+const (
+	deadLetterInterval   = time.Second * 30
+	deadLetterMinBackoff = time.Minute
+	deadLetterMaxBackoff = time.Hour
+)
+
+//nolint:gochecknoglobals
+var deadLetterDocuments = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Subsystem: "chii",
+	Name:      "search_dead_letter_documents",
+	Help:      "documents failed to write to meilisearch and waiting for retry",
+}, []string{"index"})
+
+//nolint:gochecknoinits
+func init() {
+	prometheus.MustRegister(deadLetterDocuments)
+}
+
+// DeadLetter 保存多次重试后仍然写入 meilisearch 失败的 document id.
+//
+// 数据保存在 redis stream `chii:search:dead-letter:{uid}` 中，每条消息是一批 id。
+// 重新写入时会从数据库读取最新的数据，所以期间被删除的数据不会被重新写入索引。
+type DeadLetter struct {
+	redis rueidis.Client
+	key   string
+	uid   string
+}
+
+func NewDeadLetter(redis rueidis.Client, uid string) *DeadLetter {
+	return &DeadLetter{redis: redis, key: "chii:search:dead-letter:" + uid, uid: uid}
+}
+
+// DeadLetterEntry 是一批写入失败的 document.
+type DeadLetterEntry struct {
+	NextRetry time.Time `json:"next_retry"`
+	// StreamID 是 redis stream 中的消息 id
+	StreamID string   `json:"stream_id"`
+	Error    string   `json:"error"`
+	IDs      []uint32 `json:"ids"`
+	Attempts int      `json:"attempts"`
+}
+
+// Push 把一批写入失败的 document 放入队列.
+func (d *DeadLetter) Push(ctx context.Context, ids []uint32, cause error) error {
+	return d.add(ctx, DeadLetterEntry{IDs: ids, Error: cause.Error(), NextRetry: time.Now()})
+}
+
+func (d *DeadLetter) add(ctx context.Context, e DeadLetterEntry) error {
+	ids, err := json.Marshal(e.IDs)
+	if err != nil {
+		return errgo.Wrap(err, "json.Marshal")
+	}
+
+	err = d.redis.Do(ctx, d.redis.B().Xadd().Key(d.key).Id("*").FieldValue().
+		FieldValue("ids", string(ids)).
+		FieldValue("attempts", strconv.Itoa(e.Attempts)).
+		FieldValue("next_retry", strconv.FormatInt(e.NextRetry.Unix(), 10)).
+		FieldValue("error", e.Error).
+		Build()).Error()
+
+	return errgo.Wrap(err, "redis xadd")
+}
+
+// List 返回队列中所有的消息.
+func (d *DeadLetter) List(ctx context.Context) ([]DeadLetterEntry, error) {
+	messages, err := d.redis.Do(ctx, d.redis.B().Xrange().Key(d.key).Start("-").End("+").Build()).AsXRange()
+	if err != nil {
+		return nil, errgo.Wrap(err, "redis xrange")
+	}
+
+	entries := make([]DeadLetterEntry, 0, len(messages))
+	for _, m := range messages {
+		e, err := parseDeadLetterEntry(m)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func parseDeadLetterEntry(m rueidis.XRangeEntry) (DeadLetterEntry, error) {
+	e := DeadLetterEntry{StreamID: m.ID, Error: m.FieldValues["error"]}
+
+	if err := json.Unmarshal([]byte(m.FieldValues["ids"]), &e.IDs); err != nil {
+		return e, errgo.Wrap(err, "parse ids of "+m.ID)
+	}
+
+	attempts, err := strconv.Atoi(m.FieldValues["attempts"])
+	if err != nil {
+		return e, errgo.Wrap(err, "parse attempts of "+m.ID)
+	}
+	e.Attempts = attempts
+
+	next, err := strconv.ParseInt(m.FieldValues["next_retry"], 10, 64)
+	if err != nil {
+		return e, errgo.Wrap(err, "parse next_retry of "+m.ID)
+	}
+	e.NextRetry = time.Unix(next, 0)
+
+	return e, nil
+}
+
+// Replay 重新写入到了重试时间的消息，force 为 true 时忽略重试时间。
+// 仍然失败的 id 会以新的消息放回队列，返回成功写入的 document 数量.
+func (d *DeadLetter) Replay(
+	ctx context.Context,
+	update func(ctx context.Context, id uint32) error,
+	force bool,
+) (int, error) {
+	entries, err := d.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var replayed int
+	now := time.Now()
+	for _, e := range entries {
+		if !force && now.Before(e.NextRetry) {
+			continue
+		}
+
+		var failed []uint32
+		var lastErr error
+		for _, id := range e.IDs {
+			if err := update(ctx, id); err != nil {
+				failed = append(failed, id)
+				lastErr = err
+				continue
+			}
+			replayed++
+		}
+
+		if len(failed) != 0 {
+			err = d.add(ctx, DeadLetterEntry{
+				IDs:       failed,
+				Attempts:  e.Attempts + 1,
+				NextRetry: now.Add(deadLetterBackoff(e.Attempts + 1)),
+				Error:     lastErr.Error(),
+			})
+			if err != nil {
+				return replayed, err
+			}
+		}
+
+		if err := d.redis.Do(ctx, d.redis.B().Xdel().Key(d.key).Id(e.StreamID).Build()).Error(); err != nil {
+			return replayed, errgo.Wrap(err, "redis xdel")
+		}
+	}
+
+	return replayed, nil
+}
+
+// Pending 返回队列中 document 的数量.
+func (d *DeadLetter) Pending(ctx context.Context) (int, error) {
+	entries, err := d.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, e := range entries {
+		n += len(e.IDs)
+	}
+
+	return n, nil
+}
+
+// Run 在 canal 中定期重新写入队列中的 document，并更新 prometheus 中待重试的 document 数量.
+func (d *DeadLetter) Run(ctx context.Context, log *zap.Logger, update func(ctx context.Context, id uint32) error) {
+	log = log.With(zap.String("dead_letter", d.key))
+	ticker := time.NewTicker(deadLetterInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := d.Replay(ctx, update, false)
+		if err != nil {
+			log.Error("failed to replay dead letter", zap.Error(err))
+		} else if n != 0 {
+			log.Info("replayed documents from dead letter", zap.Int("count", n))
+		}
+
+		pending, err := d.Pending(ctx)
+		if err != nil {
+			log.Error("failed to count dead letter", zap.Error(err))
+			continue
+		}
+
+		deadLetterDocuments.WithLabelValues(d.uid).Set(float64(pending))
+	}
+}
+
+// deadLetterBackoff 从 1 分钟开始每次翻倍，最多 1 小时.
+func deadLetterBackoff(attempts int) time.Duration {
+	backoff := deadLetterMinBackoff
+	for range attempts - 1 {
+		backoff *= 2
+		if backoff >= deadLetterMaxBackoff {
+			return deadLetterMaxBackoff
+		}
+	}
+
+	return backoff
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package searcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_deadLetterBackoff(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, time.Minute, deadLetterBackoff(1))
+	require.Equal(t, time.Minute*2, deadLetterBackoff(2))
+	require.Equal(t, time.Minute*32, deadLetterBackoff(6))
+	require.Equal(t, time.Hour, deadLetterBackoff(7))
+	require.Equal(t, time.Hour, deadLetterBackoff(100))
+}
+
+func Test_parseDeadLetterEntry(t *testing.T) {
+	t.Parallel()
+
+	e, err := parseDeadLetterEntry(rueidis.XRangeEntry{
+		ID: "1-0",
+		FieldValues: map[string]string{
+			"ids":        "[1,2,3]",
+			"attempts":   "2",
+			"next_retry": "1700000000",
+			"error":      "timeout",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, DeadLetterEntry{
+		StreamID:  "1-0",
+		IDs:       []uint32{1, 2, 3},
+		Attempts:  2,
+		NextRetry: time.Unix(1700000000, 0),
+		Error:     "timeout",
+	}, e)
+
+	_, err = parseDeadLetterEntry(rueidis.XRangeEntry{ID: "2-0", FieldValues: map[string]string{"ids": "bad"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,388 @@
+package searcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/trim21/errgo"
+)
+
+// SearchIndex 是 handler 搜索时使用的索引，[meilisearch.IndexManager] 和 [Embedded] 都实现了这个接口.
+type SearchIndex interface {
+	SearchRaw(query string, req *meilisearch.SearchRequest) (*json.RawMessage, error)
+}
+
+// DocumentWriter 是 canal 事件更新索引时使用的接口，见 [Writer] 和 [Embedded].
+type DocumentWriter interface {
+	UpdateDocuments(ctx context.Context, doc Document) error
+	DeleteDocument(ctx context.Context, id uint32) error
+}
+
+var _ SearchIndex = (*Embedded)(nil)
+var _ DocumentWriter = (*Embedded)(nil)
+
+// Embedded 是基于 bleve 的进程内内存索引，用于本地开发和测试，不需要启动 meilisearch.
+//
+// 搜索时接受和 meilisearch 相同的 [meilisearch.SearchRequest]，
+// filter 和 sort 使用 meilisearch 的语法，所以各个 searcher 的请求参数不需要修改。
+// 不支持高亮，补全和搜索结果中的 document 和 meilisearch 相同。
+type Embedded struct {
+	index      bleve.Index
+	searchable []string
+
+	mu   sync.RWMutex
+	docs map[string]json.RawMessage
+}
+
+// NewEmbedded 根据 document 的 struct tag 创建内存索引，
+// searchable 字段使用 cjk 分词，filterable 和 sortable 的字符串字段不分词.
+func NewEmbedded(rt reflect.Type) (*Embedded, error) {
+	dm := bleve.NewDocumentStaticMapping()
+	dm.AddFieldMappingsAt("id", bleve.NewNumericFieldMapping())
+
+	var searchable []string
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+
+		if f.Tag.Get("searchable") == "true" {
+			fm := bleve.NewTextFieldMapping()
+			fm.Analyzer = cjk.AnalyzerName
+			dm.AddFieldMappingsAt(name, fm)
+			searchable = append(searchable, name)
+			continue
+		}
+
+		if f.Tag.Get("filterable") != "true" && f.Tag.Get("sortable") != "true" {
+			continue
+		}
+
+		kind := f.Type.Kind()
+		if kind == reflect.Slice {
+			kind = f.Type.Elem().Kind()
+		}
+
+		switch kind {
+		case reflect.String:
+			dm.AddFieldMappingsAt(name, bleve.NewKeywordFieldMapping())
+		case reflect.Bool:
+			dm.AddFieldMappingsAt(name, bleve.NewBooleanFieldMapping())
+		default:
+			dm.AddFieldMappingsAt(name, bleve.NewNumericFieldMapping())
+		}
+	}
+
+	im := bleve.NewIndexMapping()
+	im.DefaultMapping = dm
+
+	index, err := bleve.NewMemOnly(im)
+	if err != nil {
+		return nil, errgo.Wrap(err, "bleve.NewMemOnly")
+	}
+
+	return &Embedded{index: index, searchable: searchable, docs: make(map[string]json.RawMessage)}, nil
+}
+
+func (e *Embedded) UpdateDocuments(_ context.Context, doc Document) error {
+	return e.SendBatch([]Document{doc})
+}
+
+func (e *Embedded) DeleteDocument(_ context.Context, id uint32) error {
+	key := strconv.FormatUint(uint64(id), 10)
+
+	e.mu.Lock()
+	delete(e.docs, key)
+	e.mu.Unlock()
+
+	return errgo.Wrap(e.index.Delete(key), "bleve.Delete")
+}
+
+// SendBatch 和 [NewSendBatch] 返回的函数相同，用于 [Reindex].
+func (e *Embedded) SendBatch(docs []Document) error {
+	batch := e.index.NewBatch()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, doc := range docs {
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return errgo.Wrap(err, "json.Marshal")
+		}
+
+		// 使用 map 而不是 struct，bleve 才会使用 json tag 作为字段名
+		var fields map[string]any
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return errgo.Wrap(err, "json.Unmarshal")
+		}
+
+		if err := batch.Index(doc.GetID(), fields); err != nil {
+			return errgo.Wrap(err, "bleve.Batch.Index")
+		}
+
+		e.docs[doc.GetID()] = raw
+	}
+
+	return errgo.Wrap(e.index.Batch(batch), "bleve.Batch")
+}
+
+// Load 把 1 到 maxID 的所有数据导入索引.
+func (e *Embedded) Load(ctx context.Context, maxID uint32, load Loader) error {
+	return Reindex(ctx, e.SendBatch, nil, 1, maxID, DefaultBatchSize, load, nil)
+}
+
+type embeddedResponse struct {
+	Hits               []json.RawMessage `json:"hits"`
+	EstimatedTotalHits uint64            `json:"estimatedTotalHits"`          //nolint:tagliatelle
+	FacetDistribution  Facets            `json:"facetDistribution,omitempty"` //nolint:tagliatelle
+}
+
+// SearchRaw 返回和 meilisearch 相同结构的搜索结果.
+func (e *Embedded) SearchRaw(keyword string, req *meilisearch.SearchRequest) (*json.RawMessage, error) {
+	q, err := e.query(keyword, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = 20
+	}
+
+	r := bleve.NewSearchRequestOptions(q, limit, int(req.Offset), false)
+	r.SortBy(embeddedSort(req.Sort))
+
+	result, err := e.index.Search(r)
+	if err != nil {
+		return nil, errgo.Wrap(err, "bleve.Search")
+	}
+
+	resp := embeddedResponse{
+		Hits:               make([]json.RawMessage, 0, len(result.Hits)),
+		EstimatedTotalHits: result.Total,
+	}
+
+	e.mu.RLock()
+	for _, hit := range result.Hits {
+		if doc, ok := e.docs[hit.ID]; ok {
+			resp.Hits = append(resp.Hits, doc)
+		}
+	}
+	e.mu.RUnlock()
+
+	if len(req.Facets) != 0 {
+		resp.FacetDistribution, err = e.facets(q, result.Total, req.Facets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return nil, errgo.Wrap(err, "json.Marshal")
+	}
+
+	return (*json.RawMessage)(&raw), nil
+}
+
+func (e *Embedded) query(keyword string, filter any) (query.Query, error) {
+	conjuncts := make([]query.Query, 0, 1)
+
+	if keyword == "" {
+		conjuncts = append(conjuncts, bleve.NewMatchAllQuery())
+	} else {
+		fields := make([]query.Query, len(e.searchable))
+		for i, field := range e.searchable {
+			q := bleve.NewMatchQuery(keyword)
+			q.SetField(field)
+			q.SetOperator(query.MatchQueryOperatorAnd)
+			fields[i] = q
+		}
+		conjuncts = append(conjuncts, bleve.NewDisjunctionQuery(fields...))
+	}
+
+	var expressions [][]string
+	switch f := filter.(type) {
+	case nil:
+	case [][]string:
+		expressions = f
+	default:
+		return nil, fmt.Errorf("unsupported filter type %T", filter)
+	}
+
+	// 外层是且，内层是或，和 meilisearch 相同
+	for _, or := range expressions {
+		disjuncts := make([]query.Query, len(or))
+		for i, expr := range or {
+			q, err := parseFilterExpression(expr)
+			if err != nil {
+				return nil, err
+			}
+			disjuncts[i] = q
+		}
+
+		conjuncts = append(conjuncts, bleve.NewDisjunctionQuery(disjuncts...))
+	}
+
+	return bleve.NewConjunctionQuery(conjuncts...), nil
+}
+
+// facets 统计所有搜索结果中字段值的数量，只用于开发环境，所以直接读取所有结果.
+func (e *Embedded) facets(q query.Query, total uint64, fields []string) (Facets, error) {
+	result, err := e.index.Search(bleve.NewSearchRequestOptions(q, int(total), 0, false))
+	if err != nil {
+		return nil, errgo.Wrap(err, "bleve.Search")
+	}
+
+	facets := make(Facets, len(fields))
+	for _, field := range fields {
+		facets[field] = map[string]int64{}
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, hit := range result.Hits {
+		var doc map[string]any
+		if err := json.Unmarshal(e.docs[hit.ID], &doc); err != nil {
+			return nil, errgo.Wrap(err, "json.Unmarshal")
+		}
+
+		for _, field := range fields {
+			values, ok := doc[field].([]any)
+			if !ok {
+				values = []any{doc[field]}
+			}
+
+			for _, v := range values {
+				if v != nil {
+					facets[field][facetValue(v)]++
+				}
+			}
+		}
+	}
+
+	return facets, nil
+}
+
+func facetValue(v any) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// meilisearch 的 `score:desc` 转换为 bleve 的 `-score`，没有指定排序时按照相关度和 id 排序.
+func embeddedSort(sort []string) []string {
+	order := make([]string, 0, len(sort)+2)
+	for _, s := range sort {
+		field, direction, _ := strings.Cut(s, ":")
+		if direction == "desc" {
+			field = "-" + field
+		}
+		order = append(order, field)
+	}
+
+	return append(order, "-_score", "id")
+}
+
+var filterExpressionPattern = regexp.MustCompile(`^(\w+) *(>=|<=|=|>|<) *(.+)$`)
+
+// parseFilterExpression 解析 meilisearch 的 `field op value` 表达式，
+// value 可以是双引号包裹的字符串、true/false 或者数字.
+func parseFilterExpression(expr string) (query.Query, error) {
+	m := filterExpressionPattern.FindStringSubmatch(strings.TrimSpace(expr))
+	if m == nil {
+		return nil, fmt.Errorf("unsupported filter expression %q", expr)
+	}
+
+	field, op, value := m[1], m[2], strings.TrimSpace(m[3])
+
+	if strings.HasPrefix(value, `"`) {
+		if op != "=" {
+			return nil, fmt.Errorf("unsupported filter expression %q", expr)
+		}
+
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, errgo.Wrap(err, fmt.Sprintf("invalid filter value %q", expr))
+		}
+
+		q := bleve.NewTermQuery(s)
+		q.SetField(field)
+		return q, nil
+	}
+
+	if b, err := strconv.ParseBool(value); err == nil && op == "=" {
+		q := bleve.NewBoolFieldQuery(b)
+		q.SetField(field)
+		return q, nil
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported filter expression %q", expr)
+	}
+
+	var q *query.NumericRangeQuery
+	inclusive := true
+	exclusive := false
+	switch op {
+	case "=":
+		q = bleve.NewNumericRangeInclusiveQuery(&v, &v, &inclusive, &inclusive)
+	case ">":
+		q = bleve.NewNumericRangeInclusiveQuery(&v, nil, &exclusive, nil)
+	case ">=":
+		q = bleve.NewNumericRangeInclusiveQuery(&v, nil, &inclusive, nil)
+	case "<":
+		q = bleve.NewNumericRangeInclusiveQuery(nil, &v, nil, &exclusive)
+	case "<=":
+		q = bleve.NewNumericRangeInclusiveQuery(nil, &v, nil, &inclusive)
+	}
+
+	q.SetField(field)
+	return q, nil
+}
+
+// EmbeddedSet 按照 index uid 保存所有的内存索引，用于 multi-search.
+type EmbeddedSet map[string]*Embedded
+
+func (s EmbeddedSet) MultiSearchWithContext(
+	_ context.Context,
+	req *meilisearch.MultiSearchRequest,
+) (*meilisearch.MultiSearchResponse, error) {
+	resp := &meilisearch.MultiSearchResponse{Results: make([]meilisearch.SearchResponse, len(req.Queries))}
+
+	for i, q := range req.Queries {
+		e, ok := s[q.IndexUID]
+		if !ok {
+			return nil, fmt.Errorf("index %s not found", q.IndexUID)
+		}
+
+		raw, err := e.SearchRaw(q.Query, q)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(*raw, &resp.Results[i]); err != nil {
+			return nil, errgo.Wrap(err, "json.Unmarshal")
+		}
+	}
+
+	return resp, nil
+}
+
+// ErrEmbedded 是内存索引不支持的操作返回的错误，比如维护 meilisearch 索引的命令.
+var ErrEmbedded = errors.New("not supported by embedded search index")
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package searcher_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+type embeddedDoc struct {
+	ID      uint32   `json:"id"`
+	Name    string   `json:"name" searchable:"true"`
+	Aliases []string `json:"aliases,omitempty" searchable:"true"`
+	Tag     []string `json:"tag,omitempty" filterable:"true"`
+	Date    int      `json:"date,omitempty" filterable:"true" sortable:"true"`
+	Score   float64  `json:"score" filterable:"true" sortable:"true"`
+	Type    uint8    `json:"type" filterable:"true"`
+	NSFW    bool     `json:"nsfw" filterable:"true"`
+}
+
+func (d *embeddedDoc) GetID() string {
+	return strconv.FormatUint(uint64(d.ID), 10)
+}
+
+func newEmbedded(t *testing.T) *searcher.Embedded {
+	t.Helper()
+
+	e, err := searcher.NewEmbedded(reflect.TypeOf(embeddedDoc{}))
+	require.NoError(t, err)
+
+	require.NoError(t, e.SendBatch([]searcher.Document{
+		&embeddedDoc{ID: 1, Name: "新世紀エヴァンゲリオン", Aliases: []string{"新世纪福音战士"},
+			Tag: []string{"TV", "原创"}, Date: 19951004, Score: 8.8, Type: 2},
+		&embeddedDoc{ID: 2, Name: "新世紀エヴァンゲリオン劇場版", Aliases: []string{"新世纪福音战士剧场版"},
+			Tag: []string{"剧场版"}, Date: 19970719, Score: 8.2, Type: 2, NSFW: true},
+		&embeddedDoc{ID: 3, Name: "新世纪福音战士", Tag: []string{"漫画"}, Date: 19950226, Score: 7.5, Type: 1},
+	}))
+
+	return e
+}
+
+type embeddedResult struct {
+	Hits               []embeddedDoc   `json:"hits"`
+	EstimatedTotalHits int64           `json:"estimatedTotalHits"` //nolint:tagliatelle
+	FacetDistribution  searcher.Facets `json:"facetDistribution"`  //nolint:tagliatelle
+}
+
+func search(t *testing.T, e *searcher.Embedded, keyword string, req *meilisearch.SearchRequest) embeddedResult {
+	t.Helper()
+
+	raw, err := e.SearchRaw(keyword, req)
+	require.NoError(t, err)
+
+	var r embeddedResult
+	require.NoError(t, json.Unmarshal(*raw, &r))
+
+	return r
+}
+
+func ids(r embeddedResult) []uint32 {
+	ids := make([]uint32, len(r.Hits))
+	for i, h := range r.Hits {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func TestEmbedded_SearchRaw(t *testing.T) {
+	t.Parallel()
+
+	e := newEmbedded(t)
+
+	r := search(t, e, "福音战士", &meilisearch.SearchRequest{Sort: []string{"score:desc"}})
+	require.Equal(t, []uint32{1, 2, 3}, ids(r))
+	require.EqualValues(t, 3, r.EstimatedTotalHits)
+
+	r = search(t, e, "", &meilisearch.SearchRequest{
+		Filter: [][]string{{"type = 1", "type = 2"}, {"nsfw = false"}, {"date >= 19950501"}},
+	})
+	require.Equal(t, []uint32{1}, ids(r))
+
+	r = search(t, e, "", &meilisearch.SearchRequest{Filter: [][]string{{`tag = "剧场版"`}}})
+	require.Equal(t, []uint32{2}, ids(r))
+
+	r = search(t, e, "", &meilisearch.SearchRequest{Filter: [][]string{{"score >8"}}, Sort: []string{"date:asc"}})
+	require.Equal(t, []uint32{1, 2}, ids(r))
+
+	r = search(t, e, "", &meilisearch.SearchRequest{Limit: 1, Offset: 1, Facets: []string{"type", "tag"}})
+	require.Equal(t, []uint32{2}, ids(r))
+	require.Equal(t, searcher.Facets{
+		"type": {"1": 1, "2": 2},
+		"tag":  {"TV": 1, "原创": 1, "剧场版": 1, "漫画": 1},
+	}, r.FacetDistribution)
+
+	_, err := e.SearchRaw("", &meilisearch.SearchRequest{Filter: [][]string{{"type IN [1, 2]"}}})
+	require.Error(t, err)
+}
+
+func TestEmbedded_DeleteDocument(t *testing.T) {
+	t.Parallel()
+
+	e := newEmbedded(t)
+	require.NoError(t, e.DeleteDocument(context.Background(), 1))
+
+	r := search(t, e, "福音战士", &meilisearch.SearchRequest{})
+	require.ElementsMatch(t, []uint32{2, 3}, ids(r))
+}
+
+func TestEmbeddedSet_MultiSearchWithContext(t *testing.T) {
+	t.Parallel()
+
+	set := searcher.EmbeddedSet{"subjects": newEmbedded(t)}
+
+	resp, err := set.MultiSearchWithContext(context.Background(), &meilisearch.MultiSearchRequest{
+		Queries: []*meilisearch.SearchRequest{{IndexUID: "subjects", Query: "剧场版", Limit: 5}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+
+	var hits []searcher.SuggestHit
+	require.NoError(t, resp.Results[0].Hits.Decode(&hits))
+	require.Equal(t, []searcher.SuggestHit{{ID: 2, Name: "新世紀エヴァンゲリオン劇場版"}}, hits)
+}
@@ -0,0 +1,29 @@
+package searcher
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/bangumi/server/web/res"
+)
+
+// Facets 是 meilisearch 返回的 facet 统计，字段名 -> 字段值 -> 数量.
+type Facets = map[string]map[string]int64
+
+// PagedFacets 是请求了 facet 统计的搜索结果，没有请求时和 [res.Paged] 相同.
+type PagedFacets struct {
+	res.Paged
+	Facets Facets `json:"facets,omitempty"`
+}
+
+// CheckFacets 检查请求的 facet 是否都在 allowed 中。
+// 只有 filterable 的字段才能用于 facet 统计.
+func CheckFacets(facets []string, allowed ...string) error {
+	for _, f := range facets {
+		if !slices.Contains(allowed, f) {
+			return res.BadRequest(fmt.Sprintf("facet %q is not supported, should be one of %q", f, allowed))
+		}
+	}
+
+	return nil
+}
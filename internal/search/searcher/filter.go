@@ -0,0 +1,72 @@
+package searcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	wiki "github.com/bangumi/wiki-parser-go"
+
+	"github.com/bangumi/server/web/res"
+)
+
+var intFilterPattern = regexp.MustCompile(`^(?:>|<|>=|<=|=) *\d+$`)
+
+var birthYearPattern = regexp.MustCompile(`(?:^|\D)(\d{4})(?:年|-|/|\.|$)`)
+
+// AnyOf 返回 field 等于任意一个值的 filter，values 为空时返回 nil.
+func AnyOf[T comparable](field string, values []T) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	filter := make([]string, len(values))
+	for i, v := range values {
+		if s, ok := any(v).(string); ok {
+			filter[i] = field + " = " + strconv.Quote(s)
+		} else {
+			filter[i] = fmt.Sprintf("%s = %v", field, v)
+		}
+	}
+
+	return filter
+}
+
+// IntFilter 把 `>=1990` 这样的比较转换为 field 的 filter，多个条件之间是 and.
+func IntFilter(field string, values []string) ([][]string, error) {
+	filter := make([][]string, 0, len(values))
+	for _, s := range values {
+		if !intFilterPattern.MatchString(s) {
+			return nil, res.BadRequest(fmt.Sprintf(
+				`invalid %s filter: %q, should be in the format of "^(>|<|>=|<=|=) *\d+$"`, field, s))
+		}
+
+		filter = append(filter, []string{field + " " + s})
+	}
+
+	return filter, nil
+}
+
+// ExtractBirthYear 从 infobox 的生日中读取年份，没有年份时返回 0.
+func ExtractBirthYear(w wiki.Wiki) uint16 {
+	for _, field := range w.Fields {
+		if field.Key != "生日" {
+			continue
+		}
+
+		for _, value := range GetWikiValues(field) {
+			m := birthYearPattern.FindStringSubmatch(strings.TrimSpace(value))
+			if m == nil {
+				continue
+			}
+
+			year, err := strconv.ParseUint(m[1], 10, 16)
+			if err == nil {
+				return uint16(year)
+			}
+		}
+	}
+
+	return 0
+}
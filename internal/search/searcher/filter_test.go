@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package searcher_test
+
+import (
+	"testing"
+
+	wiki "github.com/bangumi/wiki-parser-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+func TestAnyOf(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, searcher.AnyOf[string]("gender", nil))
+	require.Equal(t, []string{`gender = "male"`, `gender = "female"`}, searcher.AnyOf("gender", []string{"male", "female"}))
+	require.Equal(t, []string{"blood_type = 1", "blood_type = 4"}, searcher.AnyOf("blood_type", []uint8{1, 4}))
+}
+
+func TestIntFilter(t *testing.T) {
+	t.Parallel()
+
+	filter, err := searcher.IntFilter("birth_year", []string{">=1990", "<= 1995"})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"birth_year >=1990"}, {"birth_year <= 1995"}}, filter)
+
+	_, err = searcher.IntFilter("birth_year", []string{"1990 OR nsfw = true"})
+	require.Error(t, err)
+}
+
+func TestExtractBirthYear(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]uint16{
+		"{{Infobox\n|生日= 1990年5月21日\n}}": 1990,
+		"{{Infobox\n|生日= 1992-02-03\n}}": 1992,
+		"{{Infobox\n|生日= 5月21日\n}}":      0,
+		"{{Infobox\n|性别= 女\n}}":          0,
+	}
+
+	for infobox, expected := range testCases {
+		require.Equal(t, expected, searcher.ExtractBirthYear(wiki.ParseOmitError(infobox)), infobox)
+	}
+}
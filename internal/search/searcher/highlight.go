@@ -0,0 +1,81 @@
+package searcher
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const (
+	HighlightPreTag  = "<em>"
+	HighlightPostTag = "</em>"
+
+	// meilisearch 返回的是没有转义的原文，所以先用 unicode 私有区的字符标记关键词，转义后再替换成 html 标签
+	highlightPreMark  = "\ue000"
+	highlightPostMark = "\ue001"
+
+	// summary 截取关键词附近的词数
+	cropLength = 40
+)
+
+var highlightTagReplacer = strings.NewReplacer(HighlightPreTag, "", HighlightPostTag, "")
+
+var highlightMarkReplacer = strings.NewReplacer(highlightPreMark, HighlightPreTag, highlightPostMark, HighlightPostTag)
+
+// Match 是搜索结果中匹配到关键词的一个字段.
+type Match struct {
+	// Field 是匹配到关键词的字段，数组字段（如 aliases）中每个匹配的元素都是一个单独的 Match
+	Field string `json:"field"`
+	// Snippet 是用 `<em>` 标记出关键词的字段内容，字段内容经过了 html 转义，crop 的字段只包含关键词附近的内容
+	Snippet string `json:"snippet"`
+}
+
+// Text 返回去掉高亮标记的原文.
+func (m Match) Text() string {
+	return html.UnescapeString(highlightTagReplacer.Replace(m.Snippet))
+}
+
+// Formatted 是 meilisearch 搜索结果中的 `_formatted` 字段.
+type Formatted map[string]json.RawMessage
+
+// SetHighlight 设置 meilisearch 在 `_formatted` 中返回 attributes 的高亮以及 crop 的截取结果.
+func SetHighlight(r *meilisearch.SearchRequest, attributes []string, crop ...string) {
+	r.AttributesToHighlight = attributes
+	r.HighlightPreTag = highlightPreMark
+	r.HighlightPostTag = highlightPostMark
+
+	if len(crop) != 0 {
+		r.AttributesToCrop = crop
+		r.CropLength = cropLength
+	}
+}
+
+// Matches 按 fields 的顺序返回包含高亮标记的字段.
+func (f Formatted) Matches(fields ...string) []Match {
+	var matches []Match
+
+	for _, field := range fields {
+		raw, ok := f[field]
+		if !ok {
+			continue
+		}
+
+		var values []string
+		var value string
+		if err := json.Unmarshal(raw, &value); err == nil {
+			values = []string{value}
+		} else if err := json.Unmarshal(raw, &values); err != nil {
+			continue
+		}
+
+		for _, v := range values {
+			if strings.Contains(v, highlightPreMark) {
+				matches = append(matches, Match{Field: field, Snippet: highlightMarkReplacer.Replace(html.EscapeString(v))})
+			}
+		}
+	}
+
+	return matches
+}
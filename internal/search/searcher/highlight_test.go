@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package searcher_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+func TestFormatted_Matches(t *testing.T) {
+	t.Parallel()
+
+	var f searcher.Formatted
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"id": "8",
+		"name": "コードギアス 反逆のルルーシュR2",
+		"aliases": ["\ue000叛逆\ue001的鲁路修R2", "Code Geass R2", "\ue000叛逆\ue001的勒鲁什R2"],
+		"summary": "…<b>皇帝</b>的\ue000叛逆\ue001者…"
+	}`), &f))
+
+	require.Equal(t, []searcher.Match{
+		{Field: "aliases", Snippet: "<em>叛逆</em>的鲁路修R2"},
+		{Field: "aliases", Snippet: "<em>叛逆</em>的勒鲁什R2"},
+		{Field: "summary", Snippet: "…&lt;b&gt;皇帝&lt;/b&gt;的<em>叛逆</em>者…"},
+	}, f.Matches("name", "aliases", "summary", "missing"))
+
+	require.Equal(t, "叛逆的鲁路修R2", searcher.Match{Snippet: "<em>叛逆</em>的鲁路修R2"}.Text())
+	require.Equal(t, "<b>皇帝</b>的叛逆者", searcher.Match{Snippet: "&lt;b&gt;皇帝&lt;/b&gt;的<em>叛逆</em>者"}.Text())
+}
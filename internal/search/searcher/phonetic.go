@@ -0,0 +1,188 @@
+package searcher
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+	"github.com/samber/lo"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	hiraganaFirst = 'ぁ'
+	hiraganaLast  = 'ゖ'
+	katakanaFirst = 'ァ'
+	katakanaLast  = 'ヶ'
+
+	katakanaOffset = katakanaFirst - hiraganaFirst
+)
+
+// PhoneticAliases 返回 names 的读音变体，用于没有输入法时也可以搜索到，不包含 names 本身：
+//
+//   - 含有假名的名字会生成平假名和片假名互相转换后的写法
+//   - 只由假名组成的名字会生成不带长音的平文式罗马字，如 `しんげき` -> `shingeki`，标点会被当作空格
+//   - 不含假名的中文名会生成不带声调的拼音，如 `进击的巨人` -> `jinjidejuren`
+//
+// 没有汉字的读音数据，所以 `進撃の巨人` 这样含有汉字的日文名不会生成罗马字，只有别名中有假名写法时才能用罗马字搜索到.
+// 名字会先使用 NFKC 规范化，全角的英文、数字和标点以及半角假名会转换为普通的写法.
+func PhoneticAliases(names ...string) []string {
+	var aliases []string
+	for _, name := range names {
+		name = norm.NFKC.String(name)
+		if !hasKana(name) {
+			if py := toPinyin(name); py != "" {
+				aliases = append(aliases, py)
+			}
+			continue
+		}
+
+		aliases = append(aliases, toHiragana(name), toKatakana(name))
+		if isKanaName(name) {
+			aliases = append(aliases, toRomaji(name))
+		}
+	}
+
+	return lo.Compact(lo.Without(lo.Uniq(aliases), names...))
+}
+
+func isHiragana(r rune) bool {
+	return r >= hiraganaFirst && r <= hiraganaLast
+}
+
+func isKatakana(r rune) bool {
+	return r >= katakanaFirst && r <= katakanaLast
+}
+
+func isKana(r rune) bool {
+	return isHiragana(r) || isKatakana(r) || r == 'ー' || r == '・'
+}
+
+func hasKana(s string) bool {
+	return strings.ContainsFunc(s, func(r rune) bool { return isHiragana(r) || isKatakana(r) })
+}
+
+// isKanaName 判断名字中除了空格、标点和 ASCII 字符以外是否都是假名.
+func isKanaName(s string) bool {
+	return !strings.ContainsFunc(s, func(r rune) bool {
+		return !isKana(r) && r > unicode.MaxASCII && !unicode.IsSpace(r) && !unicode.IsPunct(r)
+	})
+}
+
+func toHiragana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if isKatakana(r) {
+			return r - katakanaOffset
+		}
+		return r
+	}, s)
+}
+
+func toKatakana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if isHiragana(r) {
+			return r + katakanaOffset
+		}
+		return r
+	}, s)
+}
+
+func toPinyin(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return unicode.Is(unicode.Han, r) }) {
+		return ""
+	}
+
+	return strings.Join(pinyin.LazyPinyin(s, pinyin.NewArgs()), "")
+}
+
+//nolint:gochecknoglobals
+var romajiTable = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+	'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo", 'ゎ': "wa", 'ゕ': "ka", 'ゖ': "ke",
+}
+
+//nolint:gochecknoglobals
+var youonVowels = map[rune]string{'ゃ': "a", 'ゅ': "u", 'ょ': "o"}
+
+// toRomaji 把假名转换为平文式罗马字，长音符号会被忽略，标点和空格都转换为一个空格.
+func toRomaji(s string) string {
+	runes := []rune(toHiragana(s))
+	syllables := make([]string, 0, len(runes))
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		romaji, ok := romajiTable[r]
+		if !ok {
+			switch {
+			case r == 'ー':
+			case unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r):
+				syllables = append(syllables, " ")
+			default:
+				syllables = append(syllables, string(unicode.ToLower(r)))
+			}
+			continue
+		}
+
+		// 拗音，如 きゃ -> kya、しゅ -> shu
+		if i+1 < len(runes) && strings.HasSuffix(romaji, "i") && len(romaji) > 1 {
+			if small, ok := youonVowels[runes[i+1]]; ok {
+				stem := strings.TrimSuffix(romaji, "i")
+				if !strings.HasSuffix(stem, "h") && stem != "j" {
+					stem += "y"
+				}
+				romaji = stem + small
+				i++
+			}
+		}
+
+		// 外来语的小写元音，如 ふぁ -> fa、てぃ -> ti、うぃ -> wi
+		if i+1 < len(runes) && strings.ContainsRune("ぁぃぅぇぉ", runes[i+1]) {
+			stem := romaji[:len(romaji)-1]
+			if r == 'う' {
+				stem = "w"
+			}
+			if stem != "" {
+				romaji = stem + romajiTable[runes[i+1]]
+				i++
+			}
+		}
+
+		syllables = append(syllables, romaji)
+	}
+
+	var b strings.Builder
+	for i, syllable := range syllables {
+		if syllable != "っ" {
+			b.WriteString(syllable)
+			continue
+		}
+
+		// 促音重复下一个音节的辅音，ch 前面使用 t
+		if i+1 < len(syllables) {
+			next := syllables[i+1]
+			switch {
+			case strings.HasPrefix(next, "ch"):
+				b.WriteByte('t')
+			case next != "" && !strings.ContainsRune("aiueon ", rune(next[0])):
+				b.WriteByte(next[0])
+			}
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package searcher_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+func TestPhoneticAliases(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{"pinyin", []string{"进击的巨人"}, []string{"jinjidejuren"}},
+		{"hiragana", []string{"しんげきのきょじん"}, []string{"シンゲキノキョジン", "shingekinokyojin"}},
+		{"katakana", []string{"チェンソーマン"}, []string{"ちぇんそーまん", "chensoman"}},
+		{"sokuon", []string{"ぼっち・ざ・ろっく！"}, []string{"ぼっち・ざ・ろっく!", "ボッチ・ザ・ロック!", "botchi za rokku"}},
+		{"punctuation", []string{"けいおん!!"}, []string{"ケイオン!!", "keion"}},
+		{"halfwidth katakana", []string{"ｼﾝｹﾞｷ"}, []string{"しんげき", "シンゲキ", "shingeki"}},
+		{"foreign", []string{"ファイナルファンタジー"}, []string{"ふぁいなるふぁんたじー", "fainarufantaji"}},
+		// 没有汉字的读音，不会生成罗马字
+		{"mixed kanji and kana", []string{"進撃の巨人"}, []string{"進撃ノ巨人"}},
+		{"latin", []string{"Attack on Titan"}, []string{}},
+		{"skip names", []string{"シンゲキ", "しんげき"}, []string{"shingeki"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.expected, searcher.PhoneticAliases(tc.input...))
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package searcher
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+)
+
+const DefaultBatchSize = 500
+
+// Progress 在每一批数据写入索引后报告当前进度.
+type Progress struct {
+	// LastID 是已经写入索引的最大 id，可以作为断点继续
+	LastID uint32
+	MaxID  uint32
+}
+
+// Loader 读取 ids 对应的数据，返回需要写入索引的文档，以及需要从索引中删除的 id（已删除、被合并等）.
+type Loader func(ctx context.Context, ids []uint32) ([]Document, []uint32, error)
+
+// Reindex 按 id 顺序分批从 fromID 到 maxID 重建索引，每批写入成功后调用 onBatch.
+// 任何一批写入失败（包括 [ErrDeadLettered]）都会停止并返回错误，不会对这一批调用 onBatch，所以可以从上一批继续.
+//
+// remove 为 nil 时不会删除 Loader 返回的需要删除的 id，用于导入新的空索引。
+func Reindex(
+	ctx context.Context,
+	send func([]Document) error,
+	remove func(ctx context.Context, ids []uint32) error,
+	fromID, maxID uint32,
+	batchSize int,
+	load Loader,
+	onBatch func(Progress) error,
+) error {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	if fromID == 0 {
+		fromID = 1
+	}
+
+	for start := fromID; start <= maxID; start += uint32(batchSize) {
+		if err := ctx.Err(); err != nil {
+			return errgo.Trace(err)
+		}
+
+		end := min(start+uint32(batchSize)-1, maxID)
+
+		ids := make([]uint32, 0, end-start+1)
+		for id := start; id <= end; id++ {
+			ids = append(ids, id)
+		}
+
+		docs, removed, err := load(ctx, ids)
+		if err != nil {
+			return errgo.Wrap(err, fmt.Sprintf("load %d-%d", start, end))
+		}
+
+		if len(docs) != 0 {
+			if err := send(docs); err != nil {
+				return errgo.Wrap(err, fmt.Sprintf("send batch %d-%d", start, end))
+			}
+		}
+
+		if len(removed) != 0 && remove != nil {
+			if err := remove(ctx, removed); err != nil {
+				return errgo.Wrap(err, fmt.Sprintf("delete documents %d-%d", start, end))
+			}
+		}
+
+		if onBatch != nil {
+			if err := onBatch(Progress{LastID: end, MaxID: maxID}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// NewDeleteBatch 返回从 meilisearch 索引中批量删除 document 的函数.
+func NewDeleteBatch(index meilisearch.IndexManager) func(ctx context.Context, ids []uint32) error {
+	return func(ctx context.Context, ids []uint32) error {
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = strconv.FormatUint(uint64(id), 10)
+		}
+
+		_, err := index.DeleteDocumentsWithContext(ctx, keys, nil)
+		return errgo.Wrap(err, "meilisearch.DeleteDocuments")
+	}
+}
+
+// LogProgress 每隔 10000 个 id 打印一次进度.
+func LogProgress(log *zap.Logger) func(Progress) error {
+	var next uint32
+	return func(p Progress) error {
+		if p.LastID < next && p.LastID != p.MaxID {
+			return nil
+		}
+
+		next = p.LastID + 10000 //nolint:mnd
+
+		width := len(strconv.FormatUint(uint64(p.MaxID), 10))
+		log.Info(fmt.Sprintf("progress %*d/%d", width, p.LastID, p.MaxID))
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package searcher_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+type doc uint32
+
+func (d doc) GetID() string { return "" }
+
+func TestReindex(t *testing.T) {
+	t.Parallel()
+
+	var loaded [][]uint32
+	var sent int
+	var progress []searcher.Progress
+
+	err := searcher.Reindex(context.Background(),
+		func(docs []searcher.Document) error {
+			sent += len(docs)
+			return nil
+		},
+		nil,
+		3, 10, 3,
+		func(ctx context.Context, ids []uint32) ([]searcher.Document, []uint32, error) {
+			loaded = append(loaded, ids)
+			var docs = make([]searcher.Document, len(ids))
+			for i, id := range ids {
+				docs[i] = doc(id)
+			}
+			return docs, nil, nil
+		},
+		func(p searcher.Progress) error {
+			progress = append(progress, p)
+			return nil
+		},
+	)
+
+	require.NoError(t, err)
+	require.Equal(t, [][]uint32{{3, 4, 5}, {6, 7, 8}, {9, 10}}, loaded)
+	require.Equal(t, 8, sent)
+	require.Equal(t, []searcher.Progress{
+		{LastID: 5, MaxID: 10},
+		{LastID: 8, MaxID: 10},
+		{LastID: 10, MaxID: 10},
+	}, progress)
+}
+
+func TestReindex_canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := searcher.Reindex(ctx, nil, nil, 1, 10, 3,
+		func(ctx context.Context, ids []uint32) ([]searcher.Document, []uint32, error) {
+			t.Fatal("should not load after canceled")
+			return nil, nil, nil
+		}, nil)
+
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestReindex_deadLettered(t *testing.T) {
+	t.Parallel()
+
+	var progress []searcher.Progress
+	var batches int
+
+	err := searcher.Reindex(context.Background(),
+		func(docs []searcher.Document) error {
+			batches++
+			if batches == 2 {
+				return fmt.Errorf("%w: timeout", searcher.ErrDeadLettered)
+			}
+			return nil
+		},
+		nil,
+		1, 10, 3,
+		func(ctx context.Context, ids []uint32) ([]searcher.Document, []uint32, error) {
+			return []searcher.Document{doc(ids[0])}, nil, nil
+		},
+		func(p searcher.Progress) error {
+			progress = append(progress, p)
+			return nil
+		},
+	)
+
+	// 失败的这一批不会报告进度，断点停在上一批
+	require.ErrorIs(t, err, searcher.ErrDeadLettered)
+	require.Equal(t, 2, batches)
+	require.Equal(t, []searcher.Progress{{LastID: 3, MaxID: 10}}, progress)
+}
@@ -0,0 +1,113 @@
+package searcher
+
+import (
+	"context"
+	"reflect"
+	"slices"
+
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/samber/lo"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+)
+
+const (
+	SettingSortable   = "sortableAttributes"
+	SettingFilterable = "filterableAttributes"
+	SettingSearchable = "searchableAttributes"
+	SettingRanking    = "rankingRules"
+)
+
+// SettingDiff 是线上索引和 document 定义不一致的一项设置.
+type SettingDiff struct {
+	Name     string   `json:"name"`
+	Current  []string `json:"current"`
+	Expected []string `json:"expected"`
+}
+
+// DiffSettings 对比线上索引设置和由 document struct tag 以及 rankRule 生成的设置.
+//
+// sortable 和 filterable 不区分顺序，searchable 和排序规则的顺序会影响排序，需要完全一致。
+func DiffSettings(current *meilisearch.Settings, rt reflect.Type, rankRule *[]string) []SettingDiff {
+	var diff []SettingDiff
+
+	for _, s := range []struct {
+		name     string
+		current  []string
+		expected []string
+		ordered  bool
+	}{
+		{name: SettingSortable, current: current.SortableAttributes, expected: *GetAttributes(rt, "sortable")},
+		{name: SettingFilterable, current: current.FilterableAttributes, expected: *GetAttributes(rt, "filterable")},
+		{
+			name:     SettingSearchable,
+			current:  current.SearchableAttributes,
+			expected: *GetAttributes(rt, "searchable"),
+			ordered:  true,
+		},
+		{name: SettingRanking, current: current.RankingRules, expected: *rankRule, ordered: true},
+	} {
+		if !equalAttributes(s.current, s.expected, s.ordered) {
+			diff = append(diff, SettingDiff{Name: s.name, Current: s.current, Expected: s.expected})
+		}
+	}
+
+	return diff
+}
+
+func equalAttributes(current, expected []string, ordered bool) bool {
+	if ordered {
+		return slices.Equal(current, expected)
+	}
+
+	return slices.Equal(slices.Sorted(slices.Values(current)), slices.Sorted(slices.Values(expected)))
+}
+
+// ReconcileSettings 把线上索引的设置更新为 document 定义的设置，dryRun 时只返回差异不做修改.
+func ReconcileSettings(
+	ctx context.Context,
+	log *zap.Logger,
+	index meilisearch.IndexManager,
+	rt reflect.Type,
+	rankRule *[]string,
+	dryRun bool,
+) ([]SettingDiff, error) {
+	current, err := index.GetSettingsWithContext(ctx)
+	if err != nil {
+		return nil, errgo.Wrap(err, "get settings")
+	}
+
+	diff := DiffSettings(current, rt, rankRule)
+	for _, d := range diff {
+		log.Info("search index settings changed",
+			zap.String("setting", d.Name),
+			zap.Strings("current", d.Current),
+			zap.Strings("expected", d.Expected),
+			zap.Bool("dry_run", dryRun),
+		)
+
+		if dryRun {
+			continue
+		}
+
+		switch d.Name {
+		case SettingSortable:
+			_, err = index.UpdateSortableAttributesWithContext(ctx, &d.Expected)
+		case SettingFilterable:
+			_, err = index.UpdateFilterableAttributesWithContext(ctx, lo.ToPtr(
+				lo.Map(d.Expected, func(s string, _ int) any {
+					return s
+				})))
+		case SettingSearchable:
+			_, err = index.UpdateSearchableAttributesWithContext(ctx, &d.Expected)
+		case SettingRanking:
+			_, err = index.UpdateRankingRulesWithContext(ctx, &d.Expected)
+		}
+
+		if err != nil {
+			return diff, errgo.Wrap(err, "update "+d.Name)
+		}
+	}
+
+	return diff, nil
+}
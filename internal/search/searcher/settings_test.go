@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package searcher_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+type settingsDoc struct {
+	Name    string  `json:"name" searchable:"true"`
+	Aliases string  `json:"aliases" searchable:"true"`
+	Score   float64 `json:"score" filterable:"true" sortable:"true"`
+	Type    uint8   `json:"type" filterable:"true"`
+}
+
+func TestDiffSettings(t *testing.T) {
+	t.Parallel()
+
+	rt := reflect.TypeOf(settingsDoc{})
+	rank := &[]string{"exactness", "words", "score:desc"}
+
+	diff := searcher.DiffSettings(&meilisearch.Settings{
+		RankingRules:         []string{"exactness", "words", "score:desc"},
+		SearchableAttributes: []string{"name", "aliases"},
+		FilterableAttributes: []string{"type", "score"},
+		SortableAttributes:   []string{"score"},
+	}, rt, rank)
+	require.Empty(t, diff)
+
+	diff = searcher.DiffSettings(&meilisearch.Settings{
+		RankingRules:         []string{"exactness", "words", "score:desc"},
+		SearchableAttributes: []string{"aliases", "name"},
+		FilterableAttributes: []string{"score"},
+		SortableAttributes:   []string{"score"},
+	}, rt, rank)
+	require.Equal(t, []searcher.SettingDiff{
+		{
+			Name:     searcher.SettingFilterable,
+			Current:  []string{"score"},
+			Expected: []string{"score", "type"},
+		},
+		{
+			Name:     searcher.SettingSearchable,
+			Current:  []string{"aliases", "name"},
+			Expected: []string{"name", "aliases"},
+		},
+	}, diff)
+}
@@ -0,0 +1,22 @@
+package searcher
+
+import (
+	"github.com/meilisearch/meilisearch-go"
+)
+
+// SuggestAttributes 是输入补全需要的字段，支持补全的索引的 document 需要包含这些字段.
+var SuggestAttributes = []string{"id", "name", "name_cn", "image"}
+
+// Suggester 是支持输入补全的 [Searcher].
+type Suggester interface {
+	// SuggestRequest 返回用于 multi-search 的查询，allowNSFW 为 false 时需要排除 nsfw 的结果.
+	SuggestRequest(keyword string, limit int64, allowNSFW bool) *meilisearch.SearchRequest
+}
+
+// SuggestHit 是补全查询返回的 document.
+type SuggestHit struct {
+	Name   string `json:"name"`
+	NameCN string `json:"name_cn"`
+	Image  string `json:"image"`
+	ID     uint32 `json:"id"`
+}
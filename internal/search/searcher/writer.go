@@ -0,0 +1,309 @@
+package searcher
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/redis/rueidis"
+	"github.com/samber/lo"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+)
+
+// IndexSpec 描述索引中的 document 和从数据库读取数据的方法.
+type IndexSpec struct {
+	Type     reflect.Type
+	RankRule *[]string
+	Load     Loader
+	// MaxID 返回数据库中最大的 id，没有数据时返回 0
+	MaxID func(ctx context.Context) (uint32, error)
+}
+
+// Writer 负责写入和维护线上索引，各个索引的 canal 初始化、重建和 reindex 都由它完成.
+//
+// 修改 document 结构或者索引设置后需要增加对应索引的版本号，
+// canal 启动时发现版本不一致会在后台创建一个新的影子索引 `{uid}_v{version}` 并导入所有数据，
+// 导入完成后和线上索引交换再删除旧的索引。
+// 重建期间收到的修改会同时写入线上索引和影子索引。
+type Writer struct {
+	meili      meilisearch.ServiceManager
+	redis      rueidis.Client
+	live       meilisearch.IndexManager
+	shadow     meilisearch.IndexManager
+	deadLetter *DeadLetter
+	log        *zap.Logger
+	spec       IndexSpec
+	uid        string
+	version    int
+	mu         sync.RWMutex
+}
+
+func NewWriter(
+	meili meilisearch.ServiceManager,
+	redis rueidis.Client,
+	log *zap.Logger,
+	uid string,
+	version int,
+	spec IndexSpec,
+) *Writer {
+	return &Writer{
+		meili:      meili,
+		redis:      redis,
+		live:       meili.Index(uid),
+		deadLetter: NewDeadLetter(redis, uid),
+		log:        log,
+		spec:       spec,
+		uid:        uid,
+		version:    version,
+	}
+}
+
+// CanalInit 在 canal 启动时调用，开始重试写入失败的 document.
+// 索引为空时在后台导入所有数据，版本不一致时在后台重建影子索引，否则更新线上索引的设置.
+func (w *Writer) CanalInit(ctx context.Context, update func(ctx context.Context, id uint32) error) error {
+	go w.deadLetter.Run(context.Background(), w.log, update)
+
+	shouldCreateIndex, err := NeedFirstRun(w.meili, w.uid)
+	if err != nil {
+		return err
+	}
+	if shouldCreateIndex {
+		go w.firstRun()
+		return nil
+	}
+
+	shouldRebuild, err := w.NeedRebuild(ctx)
+	if err != nil {
+		return err
+	}
+	if shouldRebuild {
+		// 影子索引创建时会使用新的设置，线上索引保持旧的设置直到交换
+		go w.rebuild()
+		return nil
+	}
+
+	_, err = w.ReconcileSettings(ctx, false)
+	return err
+}
+
+func (w *Writer) firstRun() {
+	w.log.Info("search initialize")
+
+	ctx := context.Background()
+	if err := w.Reindex(ctx, 1, DefaultBatchSize, LogProgress(w.log)); err != nil {
+		w.log.Error("failed to run full search index", zap.Error(err))
+		return
+	}
+
+	if err := w.SaveVersion(ctx); err != nil {
+		w.log.Error("failed to save search index version", zap.Error(err))
+	}
+}
+
+func (w *Writer) rebuild() {
+	w.log.Info("search index version changed, rebuild in shadow index", zap.String("shadow", w.ShadowUID()))
+
+	if err := w.Rebuild(context.Background()); err != nil {
+		w.log.Error("failed to rebuild search index", zap.Error(err))
+		return
+	}
+
+	w.log.Info("search index rebuilt")
+}
+
+// ReconcileSettings 更新线上索引的设置，见 [ReconcileSettings].
+func (w *Writer) ReconcileSettings(ctx context.Context, dryRun bool) ([]SettingDiff, error) {
+	return ReconcileSettings(ctx, w.log, w.live, w.spec.Type, w.spec.RankRule, dryRun)
+}
+
+// Reindex 从 fromID 开始把数据库中的数据导入线上索引，索引不存在时会先创建索引.
+func (w *Writer) Reindex(ctx context.Context, fromID uint32, batchSize int, onBatch func(Progress) error) error {
+	shouldCreateIndex, err := NeedFirstRun(w.meili, w.uid)
+	if err != nil {
+		return err
+	}
+	if shouldCreateIndex {
+		if err := InitIndex(w.log, w.meili, w.uid, w.spec.Type, w.spec.RankRule); err != nil {
+			return err
+		}
+	}
+
+	maxID, err := w.spec.MaxID(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.log.Info(fmt.Sprintf("run full search index with max %s id %d", w.uid, maxID), zap.Uint32("from", fromID))
+
+	return Reindex(ctx, NewSendBatch(w.log, w.live, w.deadLetter), NewDeleteBatch(w.live),
+		fromID, maxID, batchSize, w.spec.Load, onBatch)
+}
+
+// DeadLetter 影子索引和线上索引使用同一个队列，重试时会同时写入两个索引.
+func (w *Writer) DeadLetter() *DeadLetter {
+	return w.deadLetter
+}
+
+func (w *Writer) ShadowUID() string {
+	return fmt.Sprintf("%s_v%d", w.uid, w.version)
+}
+
+func (w *Writer) indexes() []meilisearch.IndexManager {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.shadow == nil {
+		return []meilisearch.IndexManager{w.live}
+	}
+
+	return []meilisearch.IndexManager{w.live, w.shadow}
+}
+
+func (w *Writer) UpdateDocuments(ctx context.Context, doc Document) error {
+	for _, index := range w.indexes() {
+		_, err := index.UpdateDocumentsWithContext(ctx, doc, &meilisearch.DocumentOptions{PrimaryKey: lo.ToPtr("id")})
+		if err != nil {
+			return errgo.Wrap(err, "update documents")
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) DeleteDocument(ctx context.Context, id uint32) error {
+	for _, index := range w.indexes() {
+		_, err := index.DeleteDocumentWithContext(ctx, strconv.FormatUint(uint64(id), 10), nil)
+		if err != nil {
+			return errgo.Wrap(err, "delete document")
+		}
+	}
+
+	return nil
+}
+
+func versionKey(uid string) string {
+	return "chii:search:index-version:" + uid
+}
+
+// NeedRebuild 检查线上索引是否由当前版本的代码创建.
+//
+// 升级前创建的索引或者 redis 被清空后没有版本记录，这时如果线上索引中已经有数据，
+// 认为它是当前版本并记录下来，避免每次部署都重建所有索引。
+func (w *Writer) NeedRebuild(ctx context.Context) (bool, error) {
+	v, err := w.redis.Do(ctx, w.redis.B().Get().Key(versionKey(w.uid)).Build()).AsInt64()
+	if err != nil {
+		if rueidis.IsRedisNil(err) {
+			return w.adoptLiveIndex(ctx)
+		}
+
+		return false, errgo.Wrap(err, "redis get")
+	}
+
+	return v != int64(w.version), nil
+}
+
+func (w *Writer) adoptLiveIndex(ctx context.Context) (bool, error) {
+	empty, err := NeedFirstRun(w.meili, w.uid)
+	if err != nil {
+		return false, err
+	}
+
+	if empty {
+		return true, nil
+	}
+
+	return false, w.SaveVersion(ctx)
+}
+
+// SaveVersion 记录线上索引的版本.
+func (w *Writer) SaveVersion(ctx context.Context) error {
+	err := w.redis.Do(ctx, w.redis.B().Set().Key(versionKey(w.uid)).Value(strconv.Itoa(w.version)).Build()).Error()
+	return errgo.Wrap(err, "redis set")
+}
+
+// Rebuild 在影子索引中导入所有数据，然后和线上索引交换.
+func (w *Writer) Rebuild(ctx context.Context) error {
+	shadowUID := w.ShadowUID()
+	log := w.log.With(zap.String("shadow", shadowUID))
+
+	// 上一次重建可能中途退出，留下了不完整的影子索引
+	if err := w.dropIndex(ctx, shadowUID); err != nil {
+		return err
+	}
+
+	maxID, err := w.spec.MaxID(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info("create shadow index")
+	if err := InitIndex(log, w.meili, shadowUID, w.spec.Type, w.spec.RankRule); err != nil {
+		return err
+	}
+
+	shadow := w.meili.Index(shadowUID)
+
+	w.mu.Lock()
+	w.shadow = shadow
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.shadow = nil
+		w.mu.Unlock()
+	}()
+
+	err = Reindex(ctx, NewSendBatch(log, shadow, w.deadLetter), NewDeleteBatch(shadow),
+		1, maxID, DefaultBatchSize, w.spec.Load, LogProgress(log))
+	if err != nil {
+		return err
+	}
+
+	log.Info("swap shadow index with live index")
+	task, err := w.meili.SwapIndexesWithContext(ctx, []*meilisearch.SwapIndexesParams{
+		{Indexes: []string{w.uid, shadowUID}},
+	})
+	if err != nil {
+		return errgo.Wrap(err, "swap indexes")
+	}
+
+	if err := w.wait(ctx, task); err != nil {
+		return errgo.Wrap(err, "swap indexes")
+	}
+
+	if err := w.SaveVersion(ctx); err != nil {
+		return err
+	}
+
+	// 交换后影子索引中是旧的数据
+	return w.dropIndex(ctx, shadowUID)
+}
+
+func (w *Writer) dropIndex(ctx context.Context, uid string) error {
+	task, err := w.meili.DeleteIndexWithContext(ctx, uid)
+	if err != nil {
+		return errgo.Wrap(err, fmt.Sprintf("delete index %s", uid))
+	}
+
+	// 索引不存在时任务会失败，可以忽略
+	_, err = w.meili.WaitForTaskWithContext(ctx, task.TaskUID, time.Second)
+	return errgo.Wrap(err, fmt.Sprintf("delete index %s", uid))
+}
+
+func (w *Writer) wait(ctx context.Context, info *meilisearch.TaskInfo) error {
+	task, err := w.meili.WaitForTaskWithContext(ctx, info.TaskUID, time.Second)
+	if err != nil {
+		return errgo.Trace(err)
+	}
+
+	if task.Status != meilisearch.TaskStatusSucceeded {
+		return fmt.Errorf("task %d %s: %s", task.UID, task.Status, task.Error.Message)
+	}
+
+	return nil
+}
@@ -2,13 +2,15 @@ package subject
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
-	"strconv"
 
 	"github.com/meilisearch/meilisearch-go"
+	"github.com/redis/rueidis"
 	"github.com/trim21/errgo"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	"github.com/bangumi/server/config"
 	"github.com/bangumi/server/dal/query"
@@ -25,75 +27,120 @@ func New(
 	cfg config.AppConfig,
 	meili meilisearch.ServiceManager,
 	repo subject.Repo,
+	redis rueidis.Client,
 	log *zap.Logger,
 	query *query.Query,
 ) (searcher.Searcher, error) {
 	if repo == nil {
 		return nil, fmt.Errorf("nil subjectRepo")
 	}
+
 	c := &client{
-		meili: meili,
 		repo:  repo,
 		index: meili.Index(idx),
+		redis: redis,
 		log:   log.Named("search").With(zap.String("index", idx)),
 		q:     query,
 	}
 
+	c.writer = searcher.NewWriter(meili, redis, c.log, idx, indexVersion, searcher.IndexSpec{
+		Type:     reflect.TypeOf(document{}),
+		RankRule: rankRule(),
+		Load:     c.load,
+		MaxID:    c.maxID,
+	})
+	c.docs = c.writer
+
 	if cfg.AppType != config.AppTypeCanal {
 		return c, nil
 	}
 
-	return c, c.canalInit(cfg)
+	if err := searcher.ValidateConfigs(cfg); err != nil {
+		return nil, errgo.Wrap(err, "validate search config")
+	}
+
+	return c, c.writer.CanalInit(context.Background(), c.OnUpdate)
 }
 
 type client struct {
 	repo  subject.Repo
-	index meilisearch.IndexManager
+	index searcher.SearchIndex
+	docs  searcher.DocumentWriter
+	// redis 用于读取 [ComputePageRank] 保存的分数，为 nil 时所有条目的分数为 0
+	redis rueidis.Client
+
+	// writer 只在使用 meilisearch 时存在，用于维护索引
+	writer *searcher.Writer
 
-	meili meilisearch.ServiceManager
-	log   *zap.Logger
-	q     *query.Query
+	log *zap.Logger
+	q   *query.Query
 }
 
-func (c *client) canalInit(cfg config.AppConfig) error {
-	if err := searcher.ValidateConfigs(cfg); err != nil {
-		return errgo.Wrap(err, "validate search config")
+func (c *client) ReconcileSettings(ctx context.Context, dryRun bool) ([]searcher.SettingDiff, error) {
+	if c.writer == nil {
+		return nil, searcher.ErrEmbedded
 	}
-	shouldCreateIndex, err := searcher.NeedFirstRun(c.meili, idx)
-	if err != nil {
-		return err
-	}
-	if shouldCreateIndex {
-		go c.firstRun()
+
+	return c.writer.ReconcileSettings(ctx, dryRun)
+}
+
+func (c *client) DeadLetter() *searcher.DeadLetter {
+	if c.writer == nil {
+		return nil
 	}
-	return nil
+
+	return c.writer.DeadLetter()
 }
 
-//nolint:funlen
-func (c *client) firstRun() {
-	c.log.Info("search initialize")
-	rt := reflect.TypeOf(document{})
-	searcher.InitIndex(c.log, c.meili, idx, rt, rankRule())
+func (c *client) Reindex(
+	ctx context.Context,
+	fromID model.SubjectID,
+	batchSize int,
+	onBatch func(searcher.Progress) error,
+) error {
+	if c.writer == nil {
+		return searcher.ErrEmbedded
+	}
 
-	ctx := context.Background()
+	return c.writer.Reindex(ctx, fromID, batchSize, onBatch)
+}
 
+// maxID 返回数据库中最大的 id，没有数据时返回 0.
+func (c *client) maxID(ctx context.Context) (model.SubjectID, error) {
 	maxItem, err := c.q.Subject.WithContext(ctx).Limit(1).Order(c.q.Subject.ID.Desc()).Take()
 	if err != nil {
-		c.log.Fatal("failed to get current max id", zap.Error(err))
-		return
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+
+		return 0, errgo.Wrap(err, "failed to get current max id")
 	}
 
-	c.log.Info(fmt.Sprintf("run full search index with max %s id %d", idx, maxItem.ID))
+	return maxItem.ID, nil
+}
 
-	width := len(strconv.Itoa(int(maxItem.ID)))
-	for i := model.SubjectID(1); i <= maxItem.ID; i++ {
-		if i%10000 == 0 {
-			c.log.Info(fmt.Sprintf("progress %*d/%d", width, i, maxItem.ID))
-		}
+func (c *client) load(ctx context.Context, ids []model.SubjectID) ([]searcher.Document, []model.SubjectID, error) {
+	subjects, err := c.repo.GetByIDs(ctx, ids, subject.Filter{})
+	if err != nil {
+		return nil, nil, errgo.Wrap(err, "subjectRepo.GetByIDs")
+	}
+
+	pageRanks, err := c.pageRanks(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		err := c.OnUpdate(ctx, i)
-		if err != nil {
-			c.log.Error("error when updating", zap.Error(err))
+	var docs = make([]searcher.Document, 0, len(subjects))
+	var removed []model.SubjectID
+	for _, id := range ids {
+		s, ok := subjects[id]
+		if !ok || s.Redirect != 0 || s.Ban != 0 {
+			removed = append(removed, id)
+			continue
 		}
+
+		docs = append(docs, extract(&s, pageRanks[id]))
 	}
+
+	return docs, removed, nil
 }
@@ -24,6 +24,9 @@ import (
 	"github.com/bangumi/server/internal/search/searcher"
 )
 
+// indexVersion 修改 document 结构或者索引设置后需要增加，canal 启动时会在后台重建索引.
+const indexVersion = 8
+
 // 最终 meilisearch 索引的文档.
 // 使用 `filterable:"true"`， `sortable:"true"`
 // 两种 tag 来设置是否可以被索引和排序.
@@ -34,7 +37,12 @@ type document struct {
 	MetaTags    []string        `json:"meta_tag" filterable:"true"`
 	Name        string          `json:"name" searchable:"true"`
 	Aliases     []string        `json:"aliases,omitempty" searchable:"true"`
+	Phonetic    []string        `json:"phonetic,omitempty" searchable:"true"` // 假名、罗马字和拼音，排在名字之后
+	Summary     string          `json:"summary,omitempty" searchable:"true"`  // 排在最后，在 attribute 规则中优先级最低
+	NameCN      string          `json:"name_cn,omitempty"`                    // name_cn 和 image 只用于输入补全
+	Image       string          `json:"image,omitempty"`
 	Date        int             `json:"date,omitempty" filterable:"true" sortable:"true"`
+	Year        int             `json:"year,omitempty" filterable:"true"`
 	Score       float64         `json:"score" filterable:"true" sortable:"true"`
 	RatingCount uint32          `json:"rating_count" filterable:"true" sortable:"true"`
 	PageRank    float64         `json:"page_rank" sortable:"true"`
@@ -58,6 +66,8 @@ func rankRule() *[]string {
 		"proximity",
 		"attribute",
 		"sort",
+		// 关联越多的条目越优先，冷门的续作和衍生作品也能排在同系列其他条目附近，见 [ComputePageRank]
+		"page_rank:desc",
 		// id 在前的优先展示，主要是为了系列作品能有个很好的顺序
 		"id:asc",
 		// 以下酌情，我选择优先展示排行榜排名更高、评分更高的条目，且尽量优先展示 sfw 内容
@@ -71,7 +81,7 @@ func heat(s *model.Subject) uint32 {
 	return s.OnHold + s.Doing + s.Dropped + s.Wish + s.Collect
 }
 
-func extract(s *model.Subject) searcher.Document {
+func extract(s *model.Subject, pageRank float64) searcher.Document {
 	tags := s.Tags
 
 	w := wiki.ParseOmitError(s.Infobox)
@@ -83,18 +93,26 @@ func extract(s *model.Subject) searcher.Document {
 		tagNames[i] = tag.Name
 	}
 
+	date := parseDateVal(s.Date)
+	aliases := extractAliases(s, w)
+
 	return &document{
 		ID:          s.ID,
 		Name:        s.Name,
-		Aliases:     extractAliases(s, w),
+		Aliases:     aliases,
+		Phonetic:    searcher.PhoneticAliases(append([]string{s.Name}, aliases...)...),
+		Summary:     s.Summary,
+		NameCN:      s.NameCN,
+		Image:       s.Image,
 		MetaTags:    strings.Split(s.MetaTags, " "),
 		Tag:         tagNames,
 		NSFW:        s.NSFW,
 		Type:        s.TypeID,
-		Date:        parseDateVal(s.Date),
+		Date:        date,
+		Year:        date / 10000, //nolint:mnd
 		Platform:    s.PlatformID,
 		RatingCount: s.Rating.Total,
-		PageRank:    float64(s.Rating.Total),
+		PageRank:    pageRank,
 		Rank:        s.Rating.Rank,
 		Heat:        heat(s),
 		Score:       score,
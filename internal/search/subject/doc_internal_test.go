@@ -18,6 +18,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/model"
 )
 
 func Test_parseDateVal(t *testing.T) {
@@ -28,3 +30,13 @@ func Test_parseDateVal(t *testing.T) {
 	require.Equal(t, 21080620, parseDateVal("2108-06-20"))
 	require.Equal(t, 0, parseDateVal("2108-06-0"))
 }
+
+func Test_extractYear(t *testing.T) {
+	t.Parallel()
+
+	doc := extract(&model.Subject{Date: "2008-01-20"}, 0).(*document) //nolint:forcetypeassert
+	require.Equal(t, 2008, doc.Year)
+
+	doc = extract(&model.Subject{}, 0).(*document) //nolint:forcetypeassert
+	require.Zero(t, doc.Year)
+}
@@ -0,0 +1,45 @@
+package subject
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/redis/rueidis"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/dal/query"
+	"github.com/bangumi/server/internal/search/searcher"
+	"github.com/bangumi/server/internal/subject"
+)
+
+// NewEmbedded 创建使用内存索引的 searcher，创建时会从数据库导入所有数据，见 [searcher.Embedded].
+func NewEmbedded(
+	set searcher.EmbeddedSet,
+	repo subject.Repo,
+	redis rueidis.Client,
+	log *zap.Logger,
+	query *query.Query,
+) (searcher.Searcher, error) {
+	e, err := searcher.NewEmbedded(reflect.TypeOf(document{}))
+	if err != nil {
+		return nil, err
+	}
+	set[idx] = e
+
+	c := &client{
+		repo:  repo,
+		index: e,
+		docs:  e,
+		redis: redis,
+		log:   log.Named("search").With(zap.String("index", idx), zap.String("backend", "embedded")),
+		q:     query,
+	}
+
+	ctx := context.Background()
+	maxID, err := c.maxID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, e.Load(ctx, maxID, c.load)
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package subject
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/null"
+	"github.com/bangumi/server/internal/search/searcher"
+	"github.com/bangumi/server/internal/subject"
+)
+
+// internal/mocks 依赖 search 包，这里不能使用 mocks.SubjectRepo.
+type memoryRepo struct {
+	subject.Repo
+	subjects map[model.SubjectID]model.Subject
+}
+
+func (r memoryRepo) Get(_ context.Context, id model.SubjectID, _ subject.Filter) (model.Subject, error) {
+	return r.subjects[id], nil
+}
+
+func TestEmbedded_doSearch(t *testing.T) {
+	t.Parallel()
+
+	subjects := map[model.SubjectID]model.Subject{
+		1: {ID: 1, Name: "新世紀エヴァンゲリオン", NameCN: "新世纪福音战士", TypeID: model.SubjectTypeAnime,
+			Date: "1995-10-04", Rating: model.Rating{Rank: 100, Score: 8.8, Total: 1000},
+			Tags: []model.Tag{{Name: "TV"}}},
+		2: {ID: 2, Name: "新世紀エヴァンゲリオン劇場版 Air/まごころを、君に", NameCN: "新世纪福音战士剧场版",
+			TypeID: model.SubjectTypeAnime, Date: "1997-07-19", NSFW: true,
+			Rating: model.Rating{Rank: 50, Score: 8.9, Total: 800}},
+		3: {ID: 3, Name: "新世紀エヴァンゲリオン", NameCN: "新世纪福音战士", TypeID: model.SubjectTypeBook,
+			Date: "1995-02-26", Rating: model.Rating{Rank: 3000, Score: 7.5, Total: 100}},
+	}
+
+	repo := memoryRepo{subjects: subjects}
+
+	e, err := searcher.NewEmbedded(reflect.TypeOf(document{}))
+	require.NoError(t, err)
+	c := &client{repo: repo, index: e, docs: e}
+
+	for id := range subjects {
+		require.NoError(t, c.OnAdded(context.Background(), id))
+	}
+
+	search := func(keyword string, filter ReqFilter, sort string) []model.SubjectID {
+		meiliFilter, err := filterToMeiliFilter(filter)
+		require.NoError(t, err)
+
+		r, err := c.doSearch(keyword, meiliFilter, sort, nil, false, 10, 0)
+		require.NoError(t, err)
+
+		var hits []hit
+		require.NoError(t, json.Unmarshal(r.Hits, &hits))
+
+		ids := make([]model.SubjectID, len(hits))
+		for i, h := range hits {
+			ids[i] = h.ID
+		}
+		return ids
+	}
+
+	require.Equal(t, []model.SubjectID{2, 1, 3}, search("福音战士", ReqFilter{}, "rank"))
+	require.Equal(t, []model.SubjectID{1}, search("福音战士", ReqFilter{
+		Type: []model.SubjectType{model.SubjectTypeAnime},
+		NSFW: null.Bool{Set: true, Value: false},
+	}, "match"))
+	require.Equal(t, []model.SubjectID{2, 1}, search("", ReqFilter{
+		AirDate: []string{">=1995-06-01"},
+		Score:   []string{">=8"},
+	}, "score"))
+	require.Equal(t, []model.SubjectID{1}, search("", ReqFilter{Tag: []string{"TV"}}, ""))
+	require.Equal(t, []model.SubjectID{1, 3}, search("xinshijifuyinzhanshi", ReqFilter{}, "rank"))
+
+	require.NoError(t, c.OnDelete(context.Background(), 1))
+	require.Equal(t, []model.SubjectID{3}, search("", ReqFilter{Rank: []string{">1000"}}, "rank"))
+}
@@ -3,10 +3,7 @@ package subject
 import (
 	"context"
 	"errors"
-	"strconv"
 
-	"github.com/meilisearch/meilisearch-go"
-	"github.com/samber/lo"
 	"github.com/trim21/errgo"
 
 	"github.com/bangumi/server/domain/gerr"
@@ -27,10 +24,14 @@ func (c *client) OnAdded(ctx context.Context, id model.SubjectID) error {
 		return c.OnDelete(ctx, id)
 	}
 
-	extracted := extract(&s)
+	pageRanks, err := c.pageRanks(ctx, []model.SubjectID{id})
+	if err != nil {
+		return err
+	}
+
+	extracted := extract(&s, pageRanks[id])
 
-	_, err = c.index.UpdateDocumentsWithContext(ctx, extracted, &meilisearch.DocumentOptions{PrimaryKey: lo.ToPtr("id")})
-	return err
+	return c.docs.UpdateDocuments(ctx, extracted)
 }
 
 func (c *client) OnUpdate(ctx context.Context, id model.SubjectID) error {
@@ -46,15 +47,16 @@ func (c *client) OnUpdate(ctx context.Context, id model.SubjectID) error {
 		return c.OnDelete(ctx, id)
 	}
 
-	extracted := extract(&s)
+	pageRanks, err := c.pageRanks(ctx, []model.SubjectID{id})
+	if err != nil {
+		return err
+	}
 
-	_, err = c.index.UpdateDocumentsWithContext(ctx, extracted, &meilisearch.DocumentOptions{PrimaryKey: lo.ToPtr("id")})
+	extracted := extract(&s, pageRanks[id])
 
-	return err
+	return c.docs.UpdateDocuments(ctx, extracted)
 }
 
 func (c *client) OnDelete(ctx context.Context, id model.SubjectID) error {
-	_, err := c.index.DeleteDocumentWithContext(ctx, strconv.FormatUint(uint64(id), 10), nil)
-
-	return errgo.Wrap(err, "search")
+	return errgo.Wrap(c.docs.DeleteDocument(ctx, id), "search")
 }
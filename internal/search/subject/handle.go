@@ -33,6 +33,7 @@ import (
 	"github.com/bangumi/server/internal/pkg/compat"
 	"github.com/bangumi/server/internal/pkg/generic/slice"
 	"github.com/bangumi/server/internal/pkg/null"
+	"github.com/bangumi/server/internal/search/searcher"
 	"github.com/bangumi/server/internal/subject"
 	"github.com/bangumi/server/internal/tag"
 	"github.com/bangumi/server/web/accessor"
@@ -47,8 +48,15 @@ type Req struct {
 	Keyword string    `json:"keyword"`
 	Sort    string    `json:"sort"`
 	Filter  ReqFilter `json:"filter"`
+	Facets  []string  `json:"facets"`
+
+	// Highlight 为 true 时返回匹配到关键词的字段
+	Highlight bool `json:"highlight"`
 }
 
+// 允许统计 facet 的字段，需要是 document 中 filterable 的字段.
+var allowedFacets = []string{"type", "tag", "meta_tag", "year"}
+
 type ReqFilter struct { //nolint:musttag
 	Type        []model.SubjectType `json:"type"`         // or
 	Tag         []string            `json:"tag"`          // and
@@ -63,7 +71,8 @@ type ReqFilter struct { //nolint:musttag
 }
 
 type hit struct {
-	ID model.SubjectID `json:"id"`
+	Formatted searcher.Formatted `json:"_formatted"` //nolint:tagliatelle
+	ID        model.SubjectID    `json:"id"`
 }
 
 type ResponseSubject struct {
@@ -88,6 +97,7 @@ type ResponseSubject struct {
 	NSFW          bool                      `json:"nsfw"`
 	TypeID        model.SubjectType         `json:"type"`
 	Redirect      model.SubjectID           `json:"-"`
+	Highlight     []searcher.Match          `json:"highlight,omitempty"`
 }
 
 //nolint:funlen
@@ -112,7 +122,11 @@ func (c *client) Handle(ctx *echo.Context) error {
 		return err
 	}
 
-	result, err := c.doSearch(r.Keyword, meiliFilter, r.Sort, q.Limit, q.Offset)
+	if err = searcher.CheckFacets(r.Facets, allowedFacets...); err != nil {
+		return err
+	}
+
+	result, err := c.doSearch(r.Keyword, meiliFilter, r.Sort, r.Facets, r.Highlight, q.Limit, q.Offset)
 	if err != nil {
 		return errgo.Wrap(err, "search")
 	}
@@ -129,8 +143,8 @@ func (c *client) Handle(ctx *echo.Context) error {
 	}
 
 	var data = make([]ResponseSubject, 0, len(subjects))
-	for _, id := range ids {
-		s, ok := subjects[id]
+	for _, h := range hits {
+		s, ok := subjects[h.ID]
 		if !ok {
 			continue
 		}
@@ -143,17 +157,37 @@ func (c *client) Handle(ctx *echo.Context) error {
 			metaTags = append(metaTags, tag.Tag{Name: t, Count: 1})
 		}
 
-		data = append(data, toResponseSubject(s, metaTags))
+		subject := toResponseSubject(s, metaTags)
+		if r.Highlight {
+			subject.Highlight = highlight(h.Formatted, s)
+		}
+
+		data = append(data, subject)
 	}
 
-	return ctx.JSON(http.StatusOK, res.Paged{
-		Data:   data,
-		Total:  result.EstimatedTotalHits,
-		Limit:  q.Limit,
-		Offset: q.Offset,
+	return ctx.JSON(http.StatusOK, searcher.PagedFacets{
+		Paged: res.Paged{
+			Data:   data,
+			Total:  result.EstimatedTotalHits,
+			Limit:  q.Limit,
+			Offset: q.Offset,
+		},
+		Facets: result.FacetDistribution,
 	})
 }
 
+// highlight 返回匹配到关键词的字段，中文名在索引中是 aliases 的一部分，需要单独区分出来.
+func highlight(f searcher.Formatted, s model.Subject) []searcher.Match {
+	matches := f.Matches("name", "aliases", "summary")
+	for i, m := range matches {
+		if m.Field == "aliases" && s.NameCN != "" && m.Text() == s.NameCN {
+			matches[i].Field = "name_cn"
+		}
+	}
+
+	return matches
+}
+
 var intFilterPattern = regexp.MustCompile(`^(?:>|<|>=|<=|=) *\d+$`)
 var floatFilterPattern = regexp.MustCompile(`^(?:>|<|>=|<=|=) *\d+(?:\.\d+)?$`)
 
@@ -161,6 +195,8 @@ func (c *client) doSearch(
 	words string,
 	filter [][]string,
 	sort string,
+	facets []string,
+	highlight bool,
 	limit, offset int,
 ) (*meiliSearchResponse, error) {
 	if limit == 0 {
@@ -182,12 +218,19 @@ func (c *client) doSearch(
 		return nil, res.BadRequest("sort not supported")
 	}
 
-	raw, err := c.index.SearchRaw(words, &meilisearch.SearchRequest{
+	searchReq := &meilisearch.SearchRequest{
 		Offset: int64(offset),
 		Limit:  int64(limit),
 		Filter: filter,
 		Sort:   sortOpt,
-	})
+		Facets: facets,
+	}
+
+	if highlight {
+		searcher.SetHighlight(searchReq, []string{"name", "aliases", "summary"}, "summary")
+	}
+
+	raw, err := c.index.SearchRaw(words, searchReq)
 	if err != nil {
 		return nil, errgo.Wrap(err, "meilisearch search")
 	}
@@ -203,6 +246,7 @@ func (c *client) doSearch(
 type meiliSearchResponse struct {
 	Hits               json.RawMessage `json:"hits"`
 	EstimatedTotalHits int64           `json:"estimatedTotalHits"` //nolint:tagliatelle
+	FacetDistribution  searcher.Facets `json:"facetDistribution"`  //nolint:tagliatelle
 }
 
 func filterToMeiliFilter(req ReqFilter) ([][]string, error) {
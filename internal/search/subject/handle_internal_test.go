@@ -15,11 +15,14 @@
 package subject
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/bangumi/server/internal/model"
 	"github.com/bangumi/server/internal/pkg/null"
+	"github.com/bangumi/server/internal/search/searcher"
 )
 
 func Test_ReqFilterToMeiliFilter(t *testing.T) {
@@ -40,3 +43,17 @@ func Test_ReqFilterToMeiliFilter(t *testing.T) {
 		{`rating_count >=100`},
 	}, actual)
 }
+
+func Test_highlight(t *testing.T) {
+	t.Parallel()
+
+	f := searcher.Formatted{
+		"name":    json.RawMessage(`"コードギアス 反逆のルルーシュR2"`),
+		"aliases": json.RawMessage(`["\ue000叛逆\ue001的鲁路修R2", "\ue000叛逆\ue001的勒鲁什R2"]`),
+	}
+
+	require.Equal(t, []searcher.Match{
+		{Field: "name_cn", Snippet: "<em>叛逆</em>的鲁路修R2"},
+		{Field: "aliases", Snippet: "<em>叛逆</em>的勒鲁什R2"},
+	}, highlight(f, model.Subject{NameCN: "叛逆的鲁路修R2"}))
+}
@@ -0,0 +1,279 @@
+package subject
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/redis/rueidis"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/bangumi/server/dal/query"
+	"github.com/bangumi/server/internal/model"
+)
+
+const (
+	pageRankKey = "chii:search:subject:page-rank"
+
+	pageRankDamping       = 0.85
+	pageRankMaxIterations = 100
+	pageRankTolerance     = 1e-9
+
+	// 读取数据库时每次查询的 subject id 范围
+	pageRankBatchSize = 10000
+	// 写入 redis 时每次 HSET 的字段数量
+	pageRankSaveBatch = 1000
+)
+
+// node 的高 32 位是节点类型，低 32 位是 id.
+type node = uint64
+
+const (
+	nodeSubject node = iota << 32
+	nodePerson
+	nodeCharacter
+)
+
+// graph 是以条目、人物和角色为节点的有向图.
+// 条目关联是有向边，人物和角色参与条目则是双向的边，所以同一个系列的条目会通过关联和共同的 staff 、角色互相传递分数.
+// 重复的边不会去重，相当于边的权重.
+type graph struct {
+	index map[node]int32
+	nodes []node
+	out   [][]int32
+}
+
+func newGraph() *graph {
+	return &graph{index: make(map[node]int32)}
+}
+
+func (g *graph) id(n node) int32 {
+	if i, ok := g.index[n]; ok {
+		return i
+	}
+
+	i := int32(len(g.nodes))
+	g.index[n] = i
+	g.nodes = append(g.nodes, n)
+	g.out = append(g.out, nil)
+
+	return i
+}
+
+func (g *graph) addEdge(from, to node) {
+	f, t := g.id(from), g.id(to)
+	g.out[f] = append(g.out[f], t)
+}
+
+func (g *graph) addLink(a, b node) {
+	g.addEdge(a, b)
+	g.addEdge(b, a)
+}
+
+// pageRank 使用幂迭代计算每个节点的分数，所有节点的分数之和为 1.
+// 没有出边的节点的分数会平均分给所有节点.
+func (g *graph) pageRank() []float64 {
+	n := len(g.nodes)
+	if n == 0 {
+		return nil
+	}
+
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+
+	for range pageRankMaxIterations {
+		var dangling float64
+		for v, out := range g.out {
+			if len(out) == 0 {
+				dangling += rank[v]
+			}
+		}
+
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+
+		for v, out := range g.out {
+			if len(out) == 0 {
+				continue
+			}
+
+			share := pageRankDamping * rank[v] / float64(len(out))
+			for _, w := range out {
+				next[w] += share
+			}
+		}
+
+		var diff float64
+		for i := range rank {
+			diff += math.Abs(next[i] - rank[i])
+		}
+
+		rank, next = next, rank
+		if diff < pageRankTolerance {
+			break
+		}
+	}
+
+	return rank
+}
+
+// subjectScores 返回所有条目节点的分数，乘以节点数量使平均值为 1，避免分数过小.
+func (g *graph) subjectScores() map[model.SubjectID]float64 {
+	rank := g.pageRank()
+	scale := float64(len(rank))
+
+	scores := make(map[model.SubjectID]float64)
+	for i, n := range g.nodes {
+		if n&^math.MaxUint32 == nodeSubject {
+			scores[model.SubjectID(n)] = rank[i] * scale
+		}
+	}
+
+	return scores
+}
+
+// ComputePageRank 从条目关联、人物参与的条目和角色出演的条目构建图，
+// 计算每个条目的 PageRank 并保存在 redis 中，重建索引时会写入 document 的 page_rank 字段.
+func ComputePageRank(ctx context.Context, q *query.Query, redis rueidis.Client, log *zap.Logger) (int, error) {
+	maxItem, err := q.Subject.WithContext(ctx).Limit(1).Order(q.Subject.ID.Desc()).Take()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+
+		return 0, errgo.Wrap(err, "failed to get current max id")
+	}
+
+	g := newGraph()
+	for from := model.SubjectID(1); from <= maxItem.ID; from += pageRankBatchSize {
+		to := from + pageRankBatchSize - 1
+		if err := loadGraph(ctx, q, g, from, to); err != nil {
+			return 0, err
+		}
+
+		log.Debug("load subject graph", zap.Uint32("to", to), zap.Int("nodes", len(g.nodes)))
+	}
+
+	log.Info("compute page rank", zap.Int("nodes", len(g.nodes)))
+	scores := g.subjectScores()
+
+	return len(scores), savePageRank(ctx, redis, scores)
+}
+
+func loadGraph(ctx context.Context, q *query.Query, g *graph, from, to model.SubjectID) error {
+	var ids []model.SubjectID
+	err := q.Subject.WithContext(ctx).Where(q.Subject.ID.Between(from, to)).Pluck(q.Subject.ID, &ids)
+	if err != nil {
+		return errgo.Wrap(err, "load subjects")
+	}
+
+	// 没有任何关联的条目也需要作为节点，才能得到分数
+	for _, id := range ids {
+		g.id(nodeSubject | node(id))
+	}
+
+	relations, err := q.SubjectRelation.WithContext(ctx).
+		Select(q.SubjectRelation.SubjectID, q.SubjectRelation.RelatedSubjectID).
+		Where(q.SubjectRelation.SubjectID.Between(from, to)).Find()
+	if err != nil {
+		return errgo.Wrap(err, "load subject relations")
+	}
+
+	for _, r := range relations {
+		g.addEdge(nodeSubject|node(r.SubjectID), nodeSubject|node(r.RelatedSubjectID))
+	}
+
+	persons, err := q.PersonSubjects.WithContext(ctx).
+		Select(q.PersonSubjects.PersonID, q.PersonSubjects.SubjectID).
+		Where(q.PersonSubjects.SubjectID.Between(from, to)).Find()
+	if err != nil {
+		return errgo.Wrap(err, "load subject staff")
+	}
+
+	for _, p := range persons {
+		g.addLink(nodeSubject|node(p.SubjectID), nodePerson|node(p.PersonID))
+	}
+
+	characters, err := q.CharacterSubjects.WithContext(ctx).
+		Select(q.CharacterSubjects.CharacterID, q.CharacterSubjects.SubjectID).
+		Where(q.CharacterSubjects.SubjectID.Between(from, to)).Find()
+	if err != nil {
+		return errgo.Wrap(err, "load subject characters")
+	}
+
+	for _, c := range characters {
+		g.addLink(nodeSubject|node(c.SubjectID), nodeCharacter|node(c.CharacterID))
+	}
+
+	return nil
+}
+
+// savePageRank 先写入临时的 key 再替换，避免重建索引时读到一半的结果.
+func savePageRank(ctx context.Context, redis rueidis.Client, scores map[model.SubjectID]float64) error {
+	tmp := pageRankKey + ":tmp"
+	if err := redis.Do(ctx, redis.B().Del().Key(tmp).Build()).Error(); err != nil {
+		return errgo.Wrap(err, "redis del")
+	}
+
+	if len(scores) == 0 {
+		return errgo.Wrap(redis.Do(ctx, redis.B().Del().Key(pageRankKey).Build()).Error(), "redis del")
+	}
+
+	cmd := redis.B().Hset().Key(tmp).FieldValue()
+	var n int
+	for id, score := range scores {
+		cmd = cmd.FieldValue(strconv.FormatUint(uint64(id), 10), strconv.FormatFloat(score, 'g', -1, 64))
+		n++
+
+		if n%pageRankSaveBatch == 0 || n == len(scores) {
+			if err := redis.Do(ctx, cmd.Build()).Error(); err != nil {
+				return errgo.Wrap(err, "redis hset")
+			}
+
+			cmd = redis.B().Hset().Key(tmp).FieldValue()
+		}
+	}
+
+	return errgo.Wrap(redis.Do(ctx, redis.B().Rename().Key(tmp).Newkey(pageRankKey).Build()).Error(), "redis rename")
+}
+
+// pageRanks 读取条目的 PageRank，没有计算过的条目不会出现在结果中.
+func (c *client) pageRanks(ctx context.Context, ids []model.SubjectID) (map[model.SubjectID]float64, error) {
+	scores := make(map[model.SubjectID]float64, len(ids))
+	if c.redis == nil || len(ids) == 0 {
+		return scores, nil
+	}
+
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	values, err := c.redis.Do(ctx, c.redis.B().Hmget().Key(pageRankKey).Field(fields...).Build()).ToArray()
+	if err != nil {
+		return nil, errgo.Wrap(err, "redis hmget")
+	}
+
+	for i, v := range values {
+		score, err := v.AsFloat64()
+		if err != nil {
+			if rueidis.IsRedisNil(err) {
+				continue
+			}
+
+			return nil, errgo.Wrap(err, "parse page rank")
+		}
+
+		scores[ids[i]] = score
+	}
+
+	return scores, nil
+}
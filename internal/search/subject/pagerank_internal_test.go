@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package subject
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph_pageRank(t *testing.T) {
+	t.Parallel()
+
+	g := newGraph()
+	g.addEdge(nodeSubject|1, nodeSubject|2)
+	g.addEdge(nodeSubject|2, nodeSubject|1)
+	g.addEdge(nodeSubject|3, nodeSubject|1)
+
+	rank := g.pageRank()
+	require.Len(t, rank, 3)
+
+	var sum float64
+	for _, r := range rank {
+		sum += r
+	}
+	require.InDelta(t, 1, sum, 1e-6)
+
+	require.Greater(t, rank[g.index[nodeSubject|1]], rank[g.index[nodeSubject|2]])
+	require.Greater(t, rank[g.index[nodeSubject|2]], rank[g.index[nodeSubject|3]])
+}
+
+func TestGraph_subjectScores(t *testing.T) {
+	t.Parallel()
+
+	g := newGraph()
+	// 1 和 2 有相同的 staff，3 没有任何关联
+	g.addLink(nodeSubject|1, nodePerson|1)
+	g.addLink(nodeSubject|2, nodePerson|1)
+	g.addLink(nodeSubject|2, nodeCharacter|1)
+	g.id(nodeSubject | 3)
+
+	scores := g.subjectScores()
+	require.Len(t, scores, 3)
+	require.Greater(t, scores[2], scores[1])
+	require.Greater(t, scores[1], scores[3])
+}
+
+func TestGraph_pageRank_empty(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, newGraph().subjectScores())
+}
@@ -0,0 +1,24 @@
+package subject
+
+import (
+	"github.com/meilisearch/meilisearch-go"
+
+	"github.com/bangumi/server/internal/search/searcher"
+)
+
+var _ searcher.Suggester = (*client)(nil)
+
+func (c *client) SuggestRequest(keyword string, limit int64, allowNSFW bool) *meilisearch.SearchRequest {
+	r := &meilisearch.SearchRequest{
+		IndexUID:             idx,
+		Query:                keyword,
+		Limit:                limit,
+		AttributesToRetrieve: searcher.SuggestAttributes,
+	}
+
+	if !allowNSFW {
+		r.Filter = [][]string{{"nsfw = false"}}
+	}
+
+	return r
+}
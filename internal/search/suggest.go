@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v5"
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/config"
+	"github.com/bangumi/server/internal/search/searcher"
+	"github.com/bangumi/server/web/accessor"
+	"github.com/bangumi/server/web/res"
+)
+
+const (
+	suggestLimit          = 5
+	suggestMaxQueryLength = 50
+
+	// 只缓存较短的前缀，这部分查询会被大量用户重复请求
+	suggestCacheMaxLength = 8
+	suggestCacheTTL       = time.Minute * 5
+)
+
+// 没有指定 types 时的默认顺序.
+var suggestTargets = []SearchTarget{SearchTargetSubject, SearchTargetCharacter, SearchTargetPerson}
+
+type Suggestion struct {
+	Type   SearchTarget `json:"type"`
+	Name   string       `json:"name"`
+	NameCN string       `json:"name_cn"`
+	Image  string       `json:"image"`
+	ID     uint32       `json:"id"`
+}
+
+// Suggest 用一次 multi-search 同时查询多个索引，返回输入补全需要的少量字段.
+func (s *Search) Suggest(c *echo.Context) error {
+	keyword := strings.TrimSpace(c.QueryParam("q"))
+	if keyword == "" {
+		return res.BadRequest("query parameter 'q' is required")
+	}
+
+	if utf8.RuneCountInString(keyword) > suggestMaxQueryLength {
+		return res.BadRequest(fmt.Sprintf("query parameter 'q' is too long, max length is %d", suggestMaxQueryLength))
+	}
+
+	targets, err := parseSuggestTargets(c.QueryParam("types"))
+	if err != nil {
+		return err
+	}
+
+	allowNSFW := accessor.GetFromCtx(c).AllowNSFW()
+	ctx := c.Request().Context()
+
+	cacheable := utf8.RuneCountInString(keyword) <= suggestCacheMaxLength
+	cacheKey := suggestCacheKey(keyword, targets, allowNSFW)
+
+	if cacheable {
+		var cached []Suggestion
+		ok, err := s.cache.Get(ctx, cacheKey, &cached)
+		if err != nil {
+			return errgo.Wrap(err, "cache.Get")
+		}
+
+		if ok {
+			return c.JSON(http.StatusOK, cached)
+		}
+	}
+
+	data, err := s.suggest(ctx, keyword, targets, allowNSFW)
+	if err != nil {
+		// 补全只是辅助功能，超时返回空结果，让客户端继续输入
+		if errors.Is(err, context.DeadlineExceeded) {
+			s.log.Warn("search suggest timeout", zap.String("q", keyword))
+			return c.JSON(http.StatusOK, []Suggestion{})
+		}
+
+		return err
+	}
+
+	if cacheable {
+		if err := s.cache.Set(ctx, cacheKey, data, suggestCacheTTL); err != nil {
+			s.log.Error("failed to cache search suggestion", zap.Error(err))
+		}
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (s *Search) suggest(
+	ctx context.Context,
+	keyword string,
+	targets []SearchTarget,
+	allowNSFW bool,
+) ([]Suggestion, error) {
+	queries := make([]*meilisearch.SearchRequest, len(targets))
+	for i, target := range targets {
+		// suggestTargets 中的 searcher 都实现了 Suggester
+		suggester := s.searchers[target].(searcher.Suggester) //nolint:forcetypeassert
+		queries[i] = suggester.SuggestRequest(keyword, suggestLimit, allowNSFW)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.suggestTimeout)
+	defer cancel()
+
+	resp, err := s.meili.MultiSearchWithContext(ctx, &meilisearch.MultiSearchRequest{Queries: queries})
+	if err != nil {
+		return nil, errgo.Wrap(err, "meilisearch multi-search")
+	}
+
+	data := make([]Suggestion, 0, len(targets)*suggestLimit)
+	for i, result := range resp.Results {
+		var hits []searcher.SuggestHit
+		if err := result.Hits.Decode(&hits); err != nil {
+			return nil, errgo.Wrap(err, "decode hits")
+		}
+
+		target := targets[i]
+		for _, h := range hits {
+			data = append(data, Suggestion{
+				Type:   target,
+				ID:     h.ID,
+				Name:   h.Name,
+				NameCN: h.NameCN,
+				Image:  suggestImage(target, h.Image),
+			})
+		}
+	}
+
+	return data, nil
+}
+
+func suggestImage(target SearchTarget, image string) string {
+	if target == SearchTargetSubject {
+		return res.SubjectImage(image).Small
+	}
+
+	return res.PersonImage(image).Small
+}
+
+// parseSuggestTargets 解析逗号分隔的 types，去重并按 [suggestTargets] 的顺序排列，方便作为缓存的 key.
+func parseSuggestTargets(raw string) ([]SearchTarget, error) {
+	if raw == "" {
+		return suggestTargets, nil
+	}
+
+	var targets []SearchTarget
+	for t := range strings.SplitSeq(raw, ",") {
+		target := SearchTarget(strings.TrimSpace(t))
+		if !slices.Contains(suggestTargets, target) {
+			return nil, res.BadRequest(fmt.Sprintf("type %q is not supported, should be one of %q", t, suggestTargets))
+		}
+
+		targets = append(targets, target)
+	}
+
+	return slices.DeleteFunc(slices.Clone(suggestTargets), func(t SearchTarget) bool {
+		return !slices.Contains(targets, t)
+	}), nil
+}
+
+func suggestCacheKey(keyword string, targets []SearchTarget, allowNSFW bool) string {
+	types := make([]string, len(targets))
+	for i, t := range targets {
+		types[i] = string(t)
+	}
+
+	return fmt.Sprintf(config.RedisKeyPrefix+"search:suggest:%t:%s:%s",
+		allowNSFW, strings.Join(types, ","), strings.ToLower(keyword))
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/config"
+)
+
+func Test_parseSuggestTargets(t *testing.T) {
+	t.Parallel()
+
+	targets, err := parseSuggestTargets("")
+	require.NoError(t, err)
+	require.Equal(t, suggestTargets, targets)
+
+	targets, err = parseSuggestTargets("person, subject,person")
+	require.NoError(t, err)
+	require.Equal(t, []SearchTarget{SearchTargetSubject, SearchTargetPerson}, targets)
+
+	_, err = parseSuggestTargets("subject,index")
+	require.Error(t, err)
+}
+
+func Test_suggestCacheKey(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, config.RedisKeyPrefix+"search:suggest:false:subject,person:fate",
+		suggestCacheKey("Fate", []SearchTarget{SearchTargetSubject, SearchTargetPerson}, false))
+}
@@ -39,11 +39,65 @@ type cacheRepo struct {
 	log   *zap.Logger
 }
 
+// canal 会在条目修改后删除缓存，见 [InvalidateCache] 和 [InvalidateBrowseCache].
 const (
 	browseCacheTTLFirst = 24 * time.Hour
 	browseCacheTTLOther = time.Hour
+
+	// browseVersionTTL 需要比浏览缓存的 TTL 长，版本过期时旧版本的浏览缓存也已经过期了
+	browseVersionTTL = 7 * 24 * time.Hour
+
+	// subjectCacheTTL 条目修改后由 canal 删除缓存，过期只是在 canal 没有运行时的兜底
+	subjectCacheTTL = time.Hour
 )
 
+// browseTypes 是所有可以浏览的条目类型.
+var browseTypes = []model.SubjectType{ //nolint:gochecknoglobals
+	model.SubjectTypeBook,
+	model.SubjectTypeAnime,
+	model.SubjectTypeMusic,
+	model.SubjectTypeGame,
+	model.SubjectTypeReal,
+}
+
+// InvalidateCache 删除条目详情的缓存.
+func InvalidateCache(ctx context.Context, c cache.RedisCache, ids ...model.SubjectID) error {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = cachekey.Subject(id)
+	}
+
+	return errgo.Wrap(c.Del(ctx, keys...), "cache.Del")
+}
+
+// InvalidateBrowseCache 更新浏览缓存的版本，使这些类型的所有浏览缓存失效，没有指定类型时更新所有类型.
+// 不指定类型的浏览页面（类型为 0）包含所有类型的条目，每次都会失效.
+func InvalidateBrowseCache(ctx context.Context, c cache.RedisCache, types ...model.SubjectType) error {
+	if len(types) == 0 {
+		types = browseTypes
+	}
+
+	types = append([]model.SubjectType{0}, types...)
+
+	version := time.Now().UnixNano()
+	for _, t := range types {
+		if err := c.Set(ctx, cachekey.SubjectBrowseVersion(t), version, browseVersionTTL); err != nil {
+			return errgo.Wrap(err, "cache.Set")
+		}
+	}
+
+	return nil
+}
+
+func (r cacheRepo) browseVersion(ctx context.Context, t model.SubjectType) (int64, error) {
+	var version int64
+	if _, err := r.cache.Get(ctx, cachekey.SubjectBrowseVersion(t), &version); err != nil {
+		return 0, errgo.Wrap(err, "cache.Get")
+	}
+
+	return version, nil
+}
+
 func (r cacheRepo) Get(ctx context.Context, id model.SubjectID, filter Filter) (model.Subject, error) {
 	var key = cachekey.Subject(id)
 
@@ -63,7 +117,7 @@ func (r cacheRepo) Get(ctx context.Context, id model.SubjectID, filter Filter) (
 		return s, err
 	}
 
-	if e := r.cache.Set(ctx, key, s, time.Minute); e != nil {
+	if e := r.cache.Set(ctx, key, s, subjectCacheTTL); e != nil {
 		r.log.Error("can't set response to cache", zap.Error(e))
 	}
 
@@ -81,7 +135,11 @@ func (r cacheRepo) Count(ctx context.Context, filter BrowseFilter) (int64, error
 	if err != nil {
 		return 0, err
 	}
-	key := cachekey.SubjectBrowseCount(hash)
+	version, err := r.browseVersion(ctx, filter.Type)
+	if err != nil {
+		return 0, err
+	}
+	key := cachekey.SubjectBrowseCount(version, hash)
 
 	var s int64
 	ok, err := r.cache.Get(ctx, key, &s)
@@ -110,7 +168,11 @@ func (r cacheRepo) Browse(
 	if err != nil {
 		return nil, err
 	}
-	key := cachekey.SubjectBrowse(hash, limit, offset)
+	version, err := r.browseVersion(ctx, filter.Type)
+	if err != nil {
+		return nil, err
+	}
+	key := cachekey.SubjectBrowse(version, hash, limit, offset)
 
 	var subjects []model.Subject
 	ok, err := r.cache.Get(ctx, key, &subjects)
@@ -69,6 +69,13 @@ func init() {
 	prometheus.MustRegister(TotalCount)
 }
 
+// InvalidateCache 删除条目标签的缓存，canal 在标签修改后调用.
+func InvalidateCache(ctx context.Context, c cache.RedisCache, ids ...model.SubjectID) error {
+	return errgo.Wrap(c.Del(ctx, lo.Map(ids, func(id model.SubjectID, _ int) string {
+		return cachekey.SubjectMetaTag(id)
+	})...), "cache.Del")
+}
+
 // also need to change version in [cachekey.SubjectMetaTag] if schema is changed.
 
 func (r cacheRepo) Get(ctx context.Context, id model.SubjectID, typeID model.SubjectType) ([]Tag, error) {
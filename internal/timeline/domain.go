@@ -16,6 +16,7 @@ package timeline
 
 import (
 	"context"
+	"time"
 
 	"github.com/bangumi/server/internal/auth"
 	"github.com/bangumi/server/internal/collections/domain/collection"
@@ -50,3 +51,97 @@ type Service interface {
 		volsUpdate uint32,
 	) error
 }
+
+// Category 是时间线的分类，对应 `chii_timeline.tml_cat`.
+type Category uint16
+
+const (
+	CategorySubject  Category = 3 // 收藏条目
+	CategoryProgress Category = 4 // 章节和条目进度
+	CategoryIndex    Category = 7 // 目录
+	CategoryMono     Category = 8 // 收藏角色和人物
+)
+
+// ProgressTypeSubject 是批量更新条目进度的 [CategoryProgress] 时间线，其他类型是单个章节的收藏状态.
+const ProgressTypeSubject = 0
+
+type Repo interface {
+	// List 返回 userIDs 发布的时间线，按照 ID 倒序.
+	List(ctx context.Context, userIDs []model.UserID, filter Filter) ([]TimeLine, error)
+}
+
+type Filter struct {
+	// Categories 为空时不过滤分类
+	Categories []Category
+	// Until 不为 0 时只返回 ID 小于 Until 的时间线
+	Until uint32
+	Limit int
+}
+
+type TimeLine struct {
+	CreatedAt time.Time
+	Memo      Memo
+	ID        uint32
+	UserID    model.UserID
+	Replies   uint32
+	Type      uint16
+	Category  Category
+	Source    uint8
+	Batch     bool
+}
+
+// Memo 是时间线的内容，只有对应分类的字段不为空.
+type Memo struct {
+	ProgressSubject *ProgressSubjectMemo
+	ProgressEpisode *ProgressEpisodeMemo
+	Subject         []SubjectMemo
+	Index           []IndexMemo
+	Mono            []MonoMemo
+}
+
+type SubjectMemo struct {
+	Comment   string
+	ID        model.SubjectID
+	CollectID uint64
+	Type      model.SubjectType
+	Collect   collection.SubjectCollection
+	Rate      uint8
+}
+
+type ProgressSubjectMemo struct {
+	Name       string
+	ID         model.SubjectID
+	Eps        uint32
+	Volumes    uint32
+	EpsUpdate  uint32
+	VolsUpdate uint32
+	Type       model.SubjectType
+}
+
+type ProgressEpisodeMemo struct {
+	SubjectName string
+	EpisodeName string
+	Sort        float32
+	SubjectID   model.SubjectID
+	EpisodeID   model.EpisodeID
+	SubjectType model.SubjectType
+	Status      collection.EpisodeCollection
+}
+
+type IndexMemo struct {
+	Title       string
+	Description string
+	ID          model.IndexID
+}
+
+type MonoMemo struct {
+	Name string
+	ID   uint32
+	// Type 是 [MonoTypeCharacter] 或者 [MonoTypePerson]
+	Type uint8
+}
+
+const (
+	MonoTypeCharacter = 1
+	MonoTypePerson    = 2
+)
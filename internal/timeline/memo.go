@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package timeline
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/internal/collections/domain/collection"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/serialize"
+)
+
+// memo 是 tml_memo 中的一条记录，旧数据中的数字可能被保存为字符串，所以不能直接解码到 struct.
+type memo map[string]any
+
+func (m memo) getString(key string) string {
+	v, _ := m[key].(string)
+	return v
+}
+
+func (m memo) getFloat(key string) float64 {
+	switch v := m[key].(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+func (m memo) getUint32(key string) uint32 {
+	f := m.getFloat(key)
+	if f < 0 {
+		return 0
+	}
+
+	return uint32(f)
+}
+
+// decodeMemo 解码 php serialize 或者 json 格式的 tml_memo.
+// 批量的时间线是以条目、角色等的 ID 为 key 的数组，按照 ID 排序.
+func decodeMemo(raw []byte, batch bool) ([]memo, error) {
+	if !batch {
+		var m memo
+		if err := serialize.Decode(raw, &m); err != nil {
+			return nil, errgo.Wrap(err, "serialize.Decode")
+		}
+
+		if m == nil {
+			return nil, nil
+		}
+
+		return []memo{m}, nil
+	}
+
+	var m map[uint32]memo
+	if err := serialize.Decode(raw, &m); err != nil {
+		return nil, errgo.Wrap(err, "serialize.Decode")
+	}
+
+	keys := make([]uint32, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	memos := make([]memo, len(keys))
+	for i, k := range keys {
+		memos[i] = m[k]
+	}
+
+	return memos, nil
+}
+
+// subjectCollectionType 把条目收藏时间线的 tml_type 转换为收藏类型.
+// 1-4 为想看，5-8 为看过，9-12 为在看，分别对应书籍、动画、音乐和游戏的不同说法.
+func subjectCollectionType(t uint16) collection.SubjectCollection {
+	switch {
+	case t >= 1 && t <= 4:
+		return collection.SubjectCollectionWish
+	case t >= 5 && t <= 8:
+		return collection.SubjectCollectionDone
+	case t >= 9 && t <= 12:
+		return collection.SubjectCollectionDoing
+	case t == 13:
+		return collection.SubjectCollectionOnHold
+	case t == 14:
+		return collection.SubjectCollectionDropped
+	default:
+		return 0
+	}
+}
+
+func parseMemo(cat Category, t uint16, memos []memo) Memo {
+	var r Memo
+
+	switch cat {
+	case CategorySubject:
+		r.Subject = make([]SubjectMemo, len(memos))
+		for i, m := range memos {
+			r.Subject[i] = SubjectMemo{
+				ID:        m.getUint32("subject_id"),
+				Type:      model.SubjectType(m.getUint32("subject_type_id")),
+				CollectID: uint64(m.getFloat("collect_id")),
+				Collect:   subjectCollectionType(t),
+				Rate:      uint8(m.getUint32("collect_rate")),
+				Comment:   m.getString("collect_comment"),
+			}
+		}
+	case CategoryProgress:
+		if len(memos) == 0 {
+			return r
+		}
+
+		m := memos[0]
+		if t == ProgressTypeSubject {
+			r.ProgressSubject = &ProgressSubjectMemo{
+				ID:         m.getUint32("subject_id"),
+				Type:       model.SubjectType(m.getUint32("subject_type_id")),
+				Name:       m.getString("subject_name"),
+				Eps:        m.getUint32("eps_total"),
+				Volumes:    m.getUint32("vols_total"),
+				EpsUpdate:  m.getUint32("eps_update"),
+				VolsUpdate: m.getUint32("vols_update"),
+			}
+		} else {
+			r.ProgressEpisode = &ProgressEpisodeMemo{
+				SubjectID:   m.getUint32("subject_id"),
+				SubjectType: model.SubjectType(m.getUint32("subject_type_id")),
+				SubjectName: m.getString("subject_name"),
+				EpisodeID:   m.getUint32("ep_id"),
+				EpisodeName: m.getString("ep_name"),
+				Sort:        float32(m.getFloat("ep_sort")),
+				Status:      collection.EpisodeCollection(t),
+			}
+		}
+	case CategoryIndex:
+		r.Index = make([]IndexMemo, len(memos))
+		for i, m := range memos {
+			r.Index[i] = IndexMemo{
+				ID:          m.getUint32("idx_id"),
+				Title:       m.getString("idx_title"),
+				Description: m.getString("idx_desc"),
+			}
+		}
+	case CategoryMono:
+		r.Mono = make([]MonoMemo, len(memos))
+		for i, m := range memos {
+			r.Mono[i] = MonoMemo{
+				ID:   m.getUint32("id"),
+				Type: uint8(m.getUint32("cat")),
+				Name: m.getString("name"),
+			}
+		}
+	}
+
+	return r
+}
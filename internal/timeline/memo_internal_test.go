@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package timeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/collections/domain/collection"
+)
+
+func TestDecodeMemo_php(t *testing.T) {
+	t.Parallel()
+
+	memos, err := decodeMemo([]byte(
+		`a:4:{s:5:"ep_id";s:4:"1027";s:7:"ep_name";s:3:"ep1";s:7:"ep_sort";i:1;s:10:"subject_id";s:2:"12";}`,
+	), false)
+	require.NoError(t, err)
+
+	m := parseMemo(CategoryProgress, uint16(collection.EpisodeCollectionDone), memos)
+	require.Nil(t, m.ProgressSubject)
+	require.Equal(t, &ProgressEpisodeMemo{
+		SubjectID:   12,
+		EpisodeID:   1027,
+		EpisodeName: "ep1",
+		Sort:        1,
+		Status:      collection.EpisodeCollectionDone,
+	}, m.ProgressEpisode)
+}
+
+func TestDecodeMemo_batch(t *testing.T) {
+	t.Parallel()
+
+	memos, err := decodeMemo([]byte(
+		`a:2:{i:9;a:2:{s:3:"cat";i:2;s:2:"id";s:1:"9";}i:3;a:2:{s:3:"cat";s:1:"1";s:2:"id";i:3;}}`,
+	), true)
+	require.NoError(t, err)
+
+	m := parseMemo(CategoryMono, 0, memos)
+	require.Equal(t, []MonoMemo{
+		{ID: 3, Type: MonoTypeCharacter},
+		{ID: 9, Type: MonoTypePerson},
+	}, m.Mono)
+}
+
+func TestDecodeMemo_json(t *testing.T) {
+	t.Parallel()
+
+	memos, err := decodeMemo([]byte(`{"subject_id":"5","collect_rate":8,"collect_comment":"c"}`), false)
+	require.NoError(t, err)
+
+	m := parseMemo(CategorySubject, 6, memos)
+	require.Equal(t, []SubjectMemo{{
+		ID:      5,
+		Collect: collection.SubjectCollectionDone,
+		Rate:    8,
+		Comment: "c",
+	}}, m.Subject)
+}
+
+func TestDecodeMemo_empty(t *testing.T) {
+	t.Parallel()
+
+	memos, err := decodeMemo(nil, false)
+	require.NoError(t, err)
+	require.Empty(t, memos)
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package timeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/dal/dao"
+	"github.com/bangumi/server/dal/query"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/generic/slice"
+)
+
+func NewMysqlRepo(q *query.Query, log *zap.Logger) (Repo, error) {
+	return mysqlRepo{q: q, log: log.Named("timeline.mysqlRepo")}, nil
+}
+
+type mysqlRepo struct {
+	q   *query.Query
+	log *zap.Logger
+}
+
+func (m mysqlRepo) List(ctx context.Context, userIDs []model.UserID, filter Filter) ([]TimeLine, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	q := m.q.TimeLine.WithContext(ctx).Where(m.q.TimeLine.UserID.In(userIDs...))
+
+	if len(filter.Categories) != 0 {
+		q = q.Where(m.q.TimeLine.Cat.In(slice.Map(filter.Categories, func(c Category) uint16 {
+			return uint16(c)
+		})...))
+	}
+
+	if filter.Until != 0 {
+		q = q.Where(m.q.TimeLine.ID.Lt(filter.Until))
+	}
+
+	rows, err := q.Order(m.q.TimeLine.ID.Desc()).Limit(filter.Limit).Find()
+	if err != nil {
+		return nil, errgo.Wrap(err, "dal")
+	}
+
+	return slice.Map(rows, m.convert), nil
+}
+
+func (m mysqlRepo) convert(t *dao.TimeLine) TimeLine {
+	batch := t.Batch != 0
+
+	memos, err := decodeMemo(t.Memo, batch)
+	if err != nil {
+		// 无法解析的内容不影响其他时间线
+		m.log.Warn("failed to decode timeline memo", zap.Uint32("id", t.ID), zap.Error(err))
+	}
+
+	return TimeLine{
+		ID:        t.ID,
+		UserID:    t.UserID,
+		Category:  Category(t.Cat),
+		Type:      t.Type,
+		Batch:     batch,
+		Source:    t.Source,
+		Replies:   t.Replies,
+		CreatedAt: time.Unix(int64(t.CreatedTime), 0),
+		Memo:      parseMemo(Category(t.Cat), t.Type, memos),
+	}
+}
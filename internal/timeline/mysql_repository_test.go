@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package timeline_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/bangumi/server/dal/query"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/test"
+	"github.com/bangumi/server/internal/timeline"
+)
+
+func TestMysqlRepo_List(t *testing.T) {
+	test.RequireEnv(t, test.EnvMysql)
+	t.Parallel()
+
+	repo, err := timeline.NewMysqlRepo(query.Use(test.GetGorm(t)), zap.NewNop())
+	require.NoError(t, err)
+
+	const uid model.UserID = 1
+
+	data, err := repo.List(context.Background(), []model.UserID{uid}, timeline.Filter{
+		Categories: []timeline.Category{timeline.CategorySubject, timeline.CategoryProgress},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(data), 10)
+
+	for i, tl := range data {
+		require.Equal(t, uid, tl.UserID)
+		require.Contains(t, []timeline.Category{timeline.CategorySubject, timeline.CategoryProgress}, tl.Category)
+		if i > 0 {
+			require.Less(t, tl.ID, data[i-1].ID)
+		}
+	}
+}
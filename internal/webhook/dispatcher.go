@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/rueidis"
+	"github.com/trim21/errgo"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/bangumi/server/domain/gerr"
+)
+
+const (
+	HeaderEvent     = "X-Bangumi-Event"
+	HeaderDelivery  = "X-Bangumi-Delivery"
+	HeaderSignature = "X-Bangumi-Signature-256"
+
+	// 等待发送的请求，score 是发送时间的毫秒时间戳
+	keyQueue = keyPrefix + "queue"
+
+	maxAttempts        = 6
+	retryBackoff       = 30 * time.Second
+	maxRetryBackoff    = time.Hour
+	pollInterval       = time.Second
+	pollBatch          = 100
+	deliverConcurrency = 8
+
+	// 新注册的 webhook 最多在这个时间之后开始接收事件，删除的 webhook 在发送时会被跳过
+	subscriptionTTL = 10 * time.Second
+)
+
+// Sign 返回请求体的 HMAC-SHA256 签名，放在 [HeaderSignature] 中.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type job struct {
+	Event     Event  `json:"event"`
+	WebhookID string `json:"webhook_id"`
+	Attempt   int    `json:"attempt"`
+}
+
+// Dispatcher 把事件写入 redis 中的队列，再由 [Dispatcher.Run] 发送，发送失败时按照指数退避重试.
+// 队列保存在 redis 中，进程重启不会丢失请求，多个进程同时运行时每个请求只会被发送一次.
+type Dispatcher struct {
+	repo   Repo
+	redis  rueidis.Client
+	client *http.Client
+	log    *zap.Logger
+
+	mu sync.Mutex
+	// subscriptions 是每种事件的 webhook id，每 [subscriptionTTL] 从数据库重新读取
+	subscriptions map[string][]string
+	expires       time.Time
+}
+
+func NewDispatcher(repo Repo, redis rueidis.Client, log *zap.Logger) *Dispatcher {
+	return &Dispatcher{repo: repo, redis: redis, client: newHTTPClient(), log: log.Named("webhook")}
+}
+
+// Publish 把事件发送给所有订阅了这个事件的 webhook.
+func (d *Dispatcher) Publish(ctx context.Context, e Event) error {
+	webhooks, err := d.subscribers(ctx, e.Type)
+	if err != nil {
+		return err
+	}
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	if e.ID == "" {
+		e.ID = uuid.NewString()
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	for _, id := range webhooks {
+		if err := d.enqueue(ctx, job{Event: e, WebhookID: id, Attempt: 1}, e.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subscribers 返回订阅了 event 的 webhook id.
+func (d *Dispatcher) subscribers(ctx context.Context, event string) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if time.Now().After(d.expires) {
+		webhooks, err := d.repo.ListAll(ctx)
+		if err != nil {
+			return nil, errgo.Wrap(err, "webhook.ListAll")
+		}
+
+		d.subscriptions = indexByEvent(webhooks)
+		d.expires = time.Now().Add(subscriptionTTL)
+	}
+
+	return d.subscriptions[event], nil
+}
+
+func indexByEvent(webhooks []Webhook) map[string][]string {
+	subscriptions := make(map[string][]string, len(EventTypes))
+	for _, w := range webhooks {
+		for _, event := range EventTypes {
+			if w.Accept(event) {
+				subscriptions[event] = append(subscriptions[event], w.ID)
+			}
+		}
+	}
+
+	return subscriptions
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, j job, at time.Time) error {
+	member, err := json.Marshal(j)
+	if err != nil {
+		return errgo.Wrap(err, "json.Marshal")
+	}
+
+	err = d.redis.Do(ctx, d.redis.B().Zadd().Key(keyQueue).ScoreMember().
+		ScoreMember(float64(at.UnixMilli()), string(member)).Build()).Error()
+
+	return errgo.Wrap(err, "redis zadd")
+}
+
+// Run 定期发送队列中已经到时间的请求，直到 ctx 被取消.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+
+		if err := d.poll(ctx); err != nil && ctx.Err() == nil {
+			d.log.Error("failed to poll webhook queue", zap.Error(err))
+		}
+	}
+}
+
+func (d *Dispatcher) poll(ctx context.Context) error {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	members, err := d.redis.Do(ctx, d.redis.B().Zrange().Key(keyQueue).Min("-inf").Max(now).Byscore().
+		Limit(0, pollBatch).Build()).AsStrSlice()
+	if err != nil {
+		return errgo.Wrap(err, "redis zrange")
+	}
+
+	var g errgroup.Group
+	g.SetLimit(deliverConcurrency)
+	for _, member := range members {
+		g.Go(func() error {
+			// 只有成功删除的进程负责发送
+			n, err := d.redis.Do(ctx, d.redis.B().Zrem().Key(keyQueue).Member(member).Build()).AsInt64()
+			if err != nil {
+				return errgo.Wrap(err, "redis zrem")
+			}
+
+			if n == 1 {
+				d.deliver(ctx, member)
+			}
+
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, member string) {
+	var j job
+	if err := json.Unmarshal([]byte(member), &j); err != nil {
+		d.log.Error("invalid webhook job", zap.String("job", member), zap.Error(err))
+		return
+	}
+
+	w, err := d.repo.Get(ctx, j.WebhookID)
+	if err != nil {
+		if !errors.Is(err, gerr.ErrNotFound) {
+			d.log.Error("failed to get webhook", zap.String("webhook_id", j.WebhookID), zap.Error(err))
+		}
+		// webhook 已经被删除
+		return
+	}
+
+	delivery := d.send(ctx, w, j)
+	if err := d.repo.AddDelivery(ctx, w.ID, delivery); err != nil {
+		d.log.Error("failed to save webhook delivery", zap.String("webhook_id", w.ID), zap.Error(err))
+	}
+
+	if delivery.Success || j.Attempt >= maxAttempts {
+		return
+	}
+
+	next := backoff(j.Attempt)
+	j.Attempt++
+	if err := d.enqueue(ctx, j, time.Now().Add(next)); err != nil {
+		d.log.Error("failed to retry webhook", zap.String("webhook_id", w.ID), zap.Error(err))
+	}
+}
+
+// backoff 返回第 attempt 次失败后等待的时间.
+func backoff(attempt int) time.Duration {
+	d := retryBackoff
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, maxRetryBackoff)
+}
+
+func (d *Dispatcher) send(ctx context.Context, w Webhook, j job) Delivery {
+	delivery := Delivery{
+		CreatedAt: time.Now(),
+		EventID:   j.Event.ID,
+		Event:     j.Event.Type,
+		Attempt:   j.Attempt,
+	}
+
+	body, err := json.Marshal(j.Event)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bangumi-webhook")
+	req.Header.Set(HeaderEvent, j.Event.Type)
+	req.Header.Set(HeaderDelivery, j.Event.ID)
+	req.Header.Set(HeaderSignature, Sign(w.Secret, body))
+
+	start := time.Now()
+	res, err := d.client.Do(req)
+	delivery.Duration = time.Since(start)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	delivery.StatusCode = res.StatusCode
+	delivery.Success = res.StatusCode >= 200 && res.StatusCode < 300
+	if !delivery.Success {
+		delivery.Error = fmt.Sprintf("unexpected status code %d", res.StatusCode)
+	}
+
+	return delivery
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	// echo -n 'hello' | openssl dgst -sha256 -hmac 'secret'
+	require.Equal(t,
+		"sha256=88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b",
+		Sign("secret", []byte("hello")))
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, retryBackoff, backoff(1))
+	require.Equal(t, retryBackoff*2, backoff(2))
+	require.Equal(t, retryBackoff*16, backoff(5))
+	require.Equal(t, maxRetryBackoff, backoff(100))
+}
+
+func TestDispatcher_send(t *testing.T) {
+	t.Parallel()
+
+	e := Event{ID: "event", Type: EventSubjectUpdated, EntityID: 8, Changed: []string{"name"}}
+
+	var status = http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		require.Equal(t, EventSubjectUpdated, r.Header.Get(HeaderEvent))
+		require.Equal(t, "event", r.Header.Get(HeaderDelivery))
+		require.Equal(t, Sign("secret", body), r.Header.Get(HeaderSignature))
+
+		var received Event
+		require.NoError(t, json.Unmarshal(body, &received))
+		require.Equal(t, e.Changed, received.Changed)
+
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	// 测试服务器在本机，不能使用限制了内网地址的 client
+	d := &Dispatcher{client: srv.Client(), log: zap.NewNop()}
+	w := Webhook{ID: "hook", URL: srv.URL, Secret: "secret"}
+
+	delivery := d.send(context.Background(), w, job{Event: e, WebhookID: w.ID, Attempt: 2})
+	require.True(t, delivery.Success, delivery.Error)
+	require.Equal(t, http.StatusOK, delivery.StatusCode)
+	require.Equal(t, 2, delivery.Attempt)
+
+	status = http.StatusInternalServerError
+	delivery = d.send(context.Background(), w, job{Event: e, WebhookID: w.ID, Attempt: 3})
+	require.False(t, delivery.Success)
+	require.Equal(t, http.StatusInternalServerError, delivery.StatusCode)
+}
+
+func TestHTTPClient_privateAddress(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	d := &Dispatcher{client: newHTTPClient(), log: zap.NewNop()}
+	delivery := d.send(context.Background(), Webhook{URL: srv.URL}, job{Event: Event{CreatedAt: time.Now()}})
+	require.False(t, delivery.Success)
+	require.Contains(t, delivery.Error, errPrivateAddress.Error())
+}
+
+type listAllRepo struct {
+	Repo
+	webhooks []Webhook
+	calls    int
+}
+
+func (r *listAllRepo) ListAll(context.Context) ([]Webhook, error) {
+	r.calls++
+	return r.webhooks, nil
+}
+
+func TestDispatcher_subscribers(t *testing.T) {
+	t.Parallel()
+
+	repo := &listAllRepo{webhooks: []Webhook{
+		{ID: "all"},
+		{ID: "subject", Events: []string{EventSubjectUpdated, EventSubjectDeleted}},
+	}}
+	d := &Dispatcher{repo: repo, log: zap.NewNop()}
+
+	ids, err := d.subscribers(context.Background(), EventSubjectUpdated)
+	require.NoError(t, err)
+	require.Equal(t, []string{"all", "subject"}, ids)
+
+	ids, err = d.subscribers(context.Background(), EventPersonCreated)
+	require.NoError(t, err)
+	require.Equal(t, []string{"all"}, ids)
+
+	// 缓存过期之前不会重新读取数据库
+	require.Equal(t, 1, repo.calls)
+
+	d.expires = time.Now().Add(-time.Second)
+	_, err = d.subscribers(context.Background(), EventPersonCreated)
+	require.NoError(t, err)
+	require.Equal(t, 2, repo.calls)
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+// Package webhook 把 wiki 的修改推送给第三方注册的 http 地址，事件由 canal 根据 binlog 生成.
+package webhook
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/bangumi/server/internal/model"
+)
+
+// 每个 access token 最多可以注册的 webhook 数量.
+const MaxPerToken = 10
+
+// 支持的事件类型，格式为 `{entity}.{action}`.
+const (
+	EventSubjectCreated   = "subject.created"
+	EventSubjectUpdated   = "subject.updated"
+	EventSubjectDeleted   = "subject.deleted"
+	EventEpisodeCreated   = "episode.created"
+	EventEpisodeUpdated   = "episode.updated"
+	EventEpisodeDeleted   = "episode.deleted"
+	EventCharacterCreated = "character.created"
+	EventCharacterUpdated = "character.updated"
+	EventCharacterDeleted = "character.deleted"
+	EventPersonCreated    = "person.created"
+	EventPersonUpdated    = "person.updated"
+	EventPersonDeleted    = "person.deleted"
+)
+
+// EventTypes 是所有支持的事件类型.
+var EventTypes = []string{ //nolint:gochecknoglobals
+	EventSubjectCreated, EventSubjectUpdated, EventSubjectDeleted,
+	EventEpisodeCreated, EventEpisodeUpdated, EventEpisodeDeleted,
+	EventCharacterCreated, EventCharacterUpdated, EventCharacterDeleted,
+	EventPersonCreated, EventPersonUpdated, EventPersonDeleted,
+}
+
+// Webhook 属于创建它的 access token，只能通过同一个 token 查看和删除.
+type Webhook struct {
+	CreatedAt time.Time    `json:"created_at"`
+	ID        string       `json:"id"`
+	URL       string       `json:"url"`
+	Secret    string       `json:"secret"`
+	Events    []string     `json:"events"` // 为空时接收所有事件
+	TokenID   uint32       `json:"token_id"`
+	UserID    model.UserID `json:"user_id"`
+}
+
+func (w Webhook) Accept(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// Event 是发送给 webhook 的请求体.
+type Event struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	EntityID  uint32    `json:"entity_id"`
+	// Changed 是修改的字段，只有 `*.updated` 事件有
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Delivery 是一次请求的记录.
+type Delivery struct {
+	CreatedAt  time.Time     `json:"created_at"`
+	EventID    string        `json:"event_id"`
+	Event      string        `json:"event"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Attempt    int           `json:"attempt"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Success    bool          `json:"success"`
+}
+
+type Repo interface {
+	Create(ctx context.Context, w Webhook) error
+	// Get return [gerr.ErrNotFound] if webhook doesn't exist.
+	Get(ctx context.Context, id string) (Webhook, error)
+	ListByToken(ctx context.Context, tokenID uint32) ([]Webhook, error)
+	ListAll(ctx context.Context) ([]Webhook, error)
+	Delete(ctx context.Context, w Webhook) error
+
+	AddDelivery(ctx context.Context, webhookID string, d Delivery) error
+	// ListDeliveries 返回最近的请求记录，新的在前.
+	ListDeliveries(ctx context.Context, webhookID string) ([]Delivery, error)
+}
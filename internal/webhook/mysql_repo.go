@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/rueidis"
+	"github.com/trim21/errgo"
+
+	"github.com/bangumi/server/domain/gerr"
+	"github.com/bangumi/server/internal/model"
+)
+
+// NewMysqlRepo 把 webhook 保存在 `chii_webhooks` 表中，请求记录只是用来排查问题的，保存在 redis 中.
+func NewMysqlRepo(db *sqlx.DB, redis rueidis.Client) Repo {
+	return mysqlRepo{db: db, redisDeliveries: redisDeliveries{redis: redis}}
+}
+
+type mysqlRepo struct {
+	db *sqlx.DB
+	redisDeliveries
+}
+
+type webhookRow struct {
+	ID      string `db:"hook_id"`
+	URL     string `db:"hook_url"`
+	Secret  string `db:"hook_secret"`
+	Events  string `db:"hook_events"` // 逗号分隔，为空时接收所有事件
+	TokenID uint32 `db:"hook_token_id"`
+	UserID  uint32 `db:"hook_uid"`
+	Created int64  `db:"hook_dateline"`
+}
+
+const selectWebhook = `select hook_id, hook_url, hook_secret, hook_events, hook_token_id, hook_uid, hook_dateline
+	from chii_webhooks`
+
+func (r webhookRow) webhook() Webhook {
+	w := Webhook{
+		CreatedAt: time.Unix(r.Created, 0),
+		ID:        r.ID,
+		URL:       r.URL,
+		Secret:    r.Secret,
+		TokenID:   r.TokenID,
+		UserID:    model.UserID(r.UserID),
+	}
+
+	if r.Events != "" {
+		w.Events = strings.Split(r.Events, ",")
+	}
+
+	return w
+}
+
+func (r mysqlRepo) Create(ctx context.Context, w Webhook) error {
+	_, err := r.db.ExecContext(ctx, `insert into chii_webhooks
+		(hook_id, hook_url, hook_secret, hook_events, hook_token_id, hook_uid, hook_dateline)
+		values (?, ?, ?, ?, ?, ?, ?)`,
+		w.ID, w.URL, w.Secret, strings.Join(w.Events, ","), w.TokenID, w.UserID, w.CreatedAt.Unix())
+
+	return errgo.Wrap(err, "insert chii_webhooks")
+}
+
+func (r mysqlRepo) Get(ctx context.Context, id string) (Webhook, error) {
+	var row webhookRow
+	if err := r.db.GetContext(ctx, &row, selectWebhook+` where hook_id = ? limit 1`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Webhook{}, gerr.ErrNotFound
+		}
+
+		return Webhook{}, errgo.Wrap(err, "select chii_webhooks")
+	}
+
+	return row.webhook(), nil
+}
+
+func (r mysqlRepo) ListByToken(ctx context.Context, tokenID uint32) ([]Webhook, error) {
+	return r.list(ctx, selectWebhook+` where hook_token_id = ? order by hook_dateline`, tokenID)
+}
+
+func (r mysqlRepo) ListAll(ctx context.Context) ([]Webhook, error) {
+	return r.list(ctx, selectWebhook)
+}
+
+func (r mysqlRepo) list(ctx context.Context, query string, args ...any) ([]Webhook, error) {
+	var rows []webhookRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, errgo.Wrap(err, "select chii_webhooks")
+	}
+
+	webhooks := make([]Webhook, len(rows))
+	for i, row := range rows {
+		webhooks[i] = row.webhook()
+	}
+
+	return webhooks, nil
+}
+
+func (r mysqlRepo) Delete(ctx context.Context, w Webhook) error {
+	if _, err := r.db.ExecContext(ctx, `delete from chii_webhooks where hook_id = ?`, w.ID); err != nil {
+		return errgo.Wrap(err, "delete chii_webhooks")
+	}
+
+	return r.deleteDeliveries(ctx, w.ID)
+}
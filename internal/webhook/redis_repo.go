@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/rueidis"
+	"github.com/trim21/errgo"
+)
+
+const (
+	// 队列和请求记录不能使用 `config.RedisKeyPrefix`，它会随着版本变化
+	keyPrefix = "chii:webhook:"
+
+	// 每个 webhook 保存的请求记录数量
+	maxDeliveries = 50
+)
+
+func keyDeliveries(webhookID string) string {
+	return keyPrefix + "deliveries:" + webhookID
+}
+
+// redisDeliveries 在 redis 中保存每个 webhook 最近的请求记录.
+type redisDeliveries struct {
+	redis rueidis.Client
+}
+
+func (r redisDeliveries) AddDelivery(ctx context.Context, webhookID string, d Delivery) error {
+	value, err := json.Marshal(d)
+	if err != nil {
+		return errgo.Wrap(err, "json.Marshal")
+	}
+
+	key := keyDeliveries(webhookID)
+	for _, resp := range r.redis.DoMulti(ctx,
+		r.redis.B().Lpush().Key(key).Element(string(value)).Build(),
+		r.redis.B().Ltrim().Key(key).Start(0).Stop(maxDeliveries-1).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			return errgo.Wrap(err, "redis")
+		}
+	}
+
+	return nil
+}
+
+func (r redisDeliveries) ListDeliveries(ctx context.Context, webhookID string) ([]Delivery, error) {
+	values, err := r.redis.Do(ctx, r.redis.B().Lrange().Key(keyDeliveries(webhookID)).Start(0).Stop(-1).Build()).
+		AsStrSlice()
+	if err != nil {
+		return nil, errgo.Wrap(err, "redis lrange")
+	}
+
+	deliveries := make([]Delivery, len(values))
+	for i, v := range values {
+		if err := json.Unmarshal([]byte(v), &deliveries[i]); err != nil {
+			return nil, errgo.Wrap(err, "json.Unmarshal")
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (r redisDeliveries) deleteDeliveries(ctx context.Context, webhookID string) error {
+	err := r.redis.Do(ctx, r.redis.B().Del().Key(keyDeliveries(webhookID)).Build()).Error()
+
+	return errgo.Wrap(err, "redis del")
+}
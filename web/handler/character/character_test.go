@@ -52,7 +52,7 @@ func TestCharacter_Get_redirect(t *testing.T) {
 
 	app := test.GetWebApp(t, test.Mock{CharacterRepo: m})
 
-	resp := htest.New(t, app).Get("/v0/characters/7").ExpectCode(http.StatusFound)
+	resp := htest.New(t, app).Get("/v0/characters/7").ExpectCode(http.StatusMovedPermanently)
 
 	require.Equal(t, "/v0/characters/8", resp.Header.Get("Location"))
 }
@@ -47,7 +47,7 @@ func (h Character) Get(c *echo.Context) error {
 	}
 
 	if r.Redirect != 0 {
-		return c.Redirect(http.StatusFound, "/v0/characters/"+strconv.FormatUint(uint64(r.Redirect), 10))
+		return c.Redirect(http.StatusMovedPermanently, "/v0/characters/"+strconv.FormatUint(uint64(r.Redirect), 10))
 	}
 
 	if !auth.AllowReadCharacter(u.Auth, r) {
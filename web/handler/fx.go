@@ -22,7 +22,9 @@ import (
 	"github.com/bangumi/server/web/handler/index"
 	"github.com/bangumi/server/web/handler/person"
 	"github.com/bangumi/server/web/handler/subject"
+	"github.com/bangumi/server/web/handler/timeline"
 	"github.com/bangumi/server/web/handler/user"
+	"github.com/bangumi/server/web/handler/webhook"
 )
 
 var Module = fx.Module("handler",
@@ -34,5 +36,7 @@ var Module = fx.Module("handler",
 		subject.New,
 		character.New,
 		index.New,
+		webhook.New,
+		timeline.New,
 	),
 )
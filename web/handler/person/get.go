@@ -46,7 +46,7 @@ func (h Person) Get(c *echo.Context) error {
 	res.SetCacheControl(c, res.CacheControlParams{Public: true, MaxAge: time.Hour})
 
 	if r.Redirect != 0 {
-		return c.Redirect(http.StatusFound, "/v0/persons/"+strconv.FormatUint(uint64(r.Redirect), 10))
+		return c.Redirect(http.StatusMovedPermanently, "/v0/persons/"+strconv.FormatUint(uint64(r.Redirect), 10))
 	}
 
 	return c.JSON(http.StatusOK, res.ConvertModelPerson(r))
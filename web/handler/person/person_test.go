@@ -43,13 +43,14 @@ func TestPerson_Get(t *testing.T) {
 func TestPerson_Get_Redirect(t *testing.T) {
 	t.Parallel()
 	m := mocks.NewPersonRepo(t)
-	m.EXPECT().Get(mock.Anything, model.PersonID(7)).Return(model.Person{ID: 7}, nil)
+	m.EXPECT().Get(mock.Anything, model.PersonID(7)).Return(model.Person{ID: 7, Redirect: 8}, nil)
 
 	app := test.GetWebApp(t, test.Mock{PersonRepo: m})
 
 	resp := htest.New(t, app).Get("/v0/persons/7")
 
-	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	require.Equal(t, "/v0/persons/8", resp.Header.Get("Location"))
 }
 
 func TestPerson_GetImage(t *testing.T) {
@@ -31,3 +31,11 @@ func (h Handler) SearchCharacters(c *echo.Context) error {
 func (h Handler) SearchPersons(c *echo.Context) error {
 	return h.search.Handle(c, search.SearchTargetPerson) //nolint:wrapcheck
 }
+
+func (h Handler) SearchIndices(c *echo.Context) error {
+	return h.search.Handle(c, search.SearchTargetIndex) //nolint:wrapcheck
+}
+
+func (h Handler) SearchSuggest(c *echo.Context) error {
+	return h.search.Suggest(c) //nolint:wrapcheck
+}
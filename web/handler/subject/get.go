@@ -54,7 +54,7 @@ func (h Subject) Get(c *echo.Context) error {
 	}
 
 	if s.Redirect != 0 {
-		return c.Redirect(http.StatusFound, fmt.Sprintf("/v0/subjects/%d", s.Redirect))
+		return c.Redirect(http.StatusMovedPermanently, fmt.Sprintf("/v0/subjects/%d", s.Redirect))
 	}
 
 	totalEpisode, err := h.episode.Count(c.Request().Context(), id, episode.Filter{})
@@ -87,7 +87,7 @@ func TestSubject_Get_Redirect(t *testing.T) {
 
 	resp := htest.New(t, app).Get("/v0/subjects/8")
 
-	require.Equal(t, http.StatusFound, resp.StatusCode, "302 for redirect repository")
+	require.Equal(t, http.StatusMovedPermanently, resp.StatusCode, "301 for merged subject")
 	require.Equal(t, "/v0/subjects/2", resp.Header.Get("location"))
 }
 
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package timeline
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+
+	"github.com/bangumi/server/domain/gerr"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/subject"
+	"github.com/bangumi/server/internal/timeline"
+	"github.com/bangumi/server/web/accessor"
+	"github.com/bangumi/server/web/mw"
+	"github.com/bangumi/server/web/req"
+	"github.com/bangumi/server/web/res"
+)
+
+const defaultLimit = 20
+const maxLimit = 50
+
+func (h Timeline) Routes(g *echo.Group) {
+	g.GET("/users/:username/timeline", h.ListUser)
+	g.GET("/timeline", h.List, mw.NeedLogin)
+}
+
+// ListUser 返回一个用户的时间线.
+func (h Timeline) ListUser(c *echo.Context) error {
+	username := c.Param("username")
+	if username == "" {
+		return res.BadRequest("missing require parameters `username`")
+	}
+	if len(username) >= 32 {
+		return res.BadRequest("username is too long")
+	}
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return err
+	}
+
+	u, err := h.user.GetByName(c.Request().Context(), username)
+	if err != nil {
+		if errors.Is(err, gerr.ErrNotFound) {
+			return res.NotFound("can't find user with username " + strconv.Quote(username))
+		}
+
+		return res.InternalError(c, err, "failed to get user")
+	}
+
+	return h.list(c, []model.UserID{u.ID}, filter)
+}
+
+// List 返回当前用户和好友的时间线.
+func (h Timeline) List(c *echo.Context) error {
+	if scope := c.QueryParam("scope"); scope != "" && scope != "friends" {
+		return res.BadRequest("unknown scope " + strconv.Quote(scope))
+	}
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return err
+	}
+
+	a := accessor.GetFromCtx(c)
+
+	friends, err := h.user.GetFriends(c.Request().Context(), a.ID)
+	if err != nil {
+		return res.InternalError(c, err, "failed to get friends")
+	}
+
+	return h.list(c, append(slices.Collect(maps.Keys(friends)), a.ID), filter)
+}
+
+func (h Timeline) list(c *echo.Context, userIDs []model.UserID, filter timeline.Filter) error {
+	ctx := c.Request().Context()
+
+	data, err := h.timeline.List(ctx, userIDs, filter)
+	if err != nil {
+		return res.InternalError(c, err, "failed to list timeline")
+	}
+
+	// 下一页从数据库返回的最后一条开始，和是否隐藏了 nsfw 条目无关
+	var next *uint32
+	if len(data) == filter.Limit {
+		next = &data[len(data)-1].ID
+	}
+
+	if !accessor.GetFromCtx(c).AllowNSFW() {
+		data, err = h.hideNSFW(ctx, data)
+		if err != nil {
+			return res.InternalError(c, err, "failed to get subjects")
+		}
+	}
+
+	ids := make([]model.UserID, 0, len(data))
+	for _, t := range data {
+		ids = append(ids, t.UserID)
+	}
+	slices.Sort(ids)
+
+	users, err := h.user.GetByIDs(ctx, slices.Compact(ids))
+	if err != nil {
+		return res.InternalError(c, err, "failed to get users")
+	}
+
+	page := res.TimelinePage{Data: make([]res.Timeline, len(data)), Next: next}
+	for i, t := range data {
+		page.Data[i] = res.ConvertTimeline(t, res.ConvertModelUser(users[t.UserID]))
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// hideNSFW 去掉时间线中的 nsfw 条目，只包含 nsfw 条目的时间线会被整条去掉，所以一页可能少于 limit 条.
+func (h Timeline) hideNSFW(ctx context.Context, data []timeline.TimeLine) ([]timeline.TimeLine, error) {
+	var ids []model.SubjectID
+	for _, t := range data {
+		for _, s := range t.Memo.Subject {
+			ids = append(ids, s.ID)
+		}
+
+		if p := t.Memo.ProgressSubject; p != nil {
+			ids = append(ids, p.ID)
+		}
+
+		if p := t.Memo.ProgressEpisode; p != nil {
+			ids = append(ids, p.SubjectID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return data, nil
+	}
+
+	slices.Sort(ids)
+	subjects, err := h.subject.GetByIDs(ctx, slices.Compact(ids), subject.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	nsfw := func(id model.SubjectID) bool {
+		return subjects[id].NSFW
+	}
+
+	result := make([]timeline.TimeLine, 0, len(data))
+	for _, t := range data {
+		if (t.Memo.ProgressSubject != nil && nsfw(t.Memo.ProgressSubject.ID)) ||
+			(t.Memo.ProgressEpisode != nil && nsfw(t.Memo.ProgressEpisode.SubjectID)) {
+			continue
+		}
+
+		if len(t.Memo.Subject) != 0 {
+			t.Memo.Subject = slices.DeleteFunc(slices.Clone(t.Memo.Subject), func(s timeline.SubjectMemo) bool {
+				return nsfw(s.ID)
+			})
+
+			if len(t.Memo.Subject) == 0 {
+				continue
+			}
+		}
+
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+// parseFilter 解析 `type`、`until` 和 `limit` 参数，`type` 可以是逗号分隔的多个分类.
+// 时间线只能使用 `until` 翻页，不支持 `offset`.
+func parseFilter(c *echo.Context) (timeline.Filter, error) {
+	if c.QueryParam("offset") != "" {
+		return timeline.Filter{}, res.BadRequest("timeline doesn't support `offset`, use `until` instead")
+	}
+
+	page, err := req.GetPageQuerySoftLimit(c, defaultLimit, maxLimit)
+	if err != nil {
+		return timeline.Filter{}, err
+	}
+
+	filter := timeline.Filter{Limit: page.Limit}
+
+	if raw := c.QueryParam("until"); raw != "" {
+		until, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil || until == 0 {
+			return timeline.Filter{}, res.BadRequest("can't parse query args until as id: " + strconv.Quote(raw))
+		}
+
+		filter.Until = uint32(until)
+	}
+
+	raw := c.QueryParam("type")
+	if raw == "" {
+		// 不返回 api 不支持的分类
+		filter.Categories = slices.Sorted(maps.Values(res.TimelineCategories))
+		return filter, nil
+	}
+
+	for _, s := range strings.Split(raw, ",") {
+		category, ok := res.TimelineCategories[s]
+		if !ok {
+			return timeline.Filter{}, res.BadRequest("unknown timeline type " + strconv.Quote(s))
+		}
+
+		filter.Categories = append(filter.Categories, category)
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package timeline_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/trim21/htest"
+
+	"github.com/bangumi/server/internal/auth"
+	"github.com/bangumi/server/internal/mocks"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/pkg/test"
+	"github.com/bangumi/server/internal/subject"
+	"github.com/bangumi/server/internal/timeline"
+	"github.com/bangumi/server/internal/user"
+	"github.com/bangumi/server/web/res"
+)
+
+func TestTimeline_ListUser(t *testing.T) {
+	t.Parallel()
+
+	const uid model.UserID = 5
+
+	u := mocks.NewUserRepo(t)
+	u.EXPECT().GetByName(mock.Anything, "sai").Return(user.User{ID: uid, UserName: "sai"}, nil)
+	u.EXPECT().GetByIDs(mock.Anything, []model.UserID{uid}).
+		Return(map[model.UserID]user.User{uid: {ID: uid, UserName: "sai"}}, nil)
+
+	m := mocks.NewTimelineRepo(t)
+	m.EXPECT().List(mock.Anything, []model.UserID{uid}, timeline.Filter{
+		Categories: []timeline.Category{timeline.CategoryProgress},
+		Until:      100,
+		Limit:      1,
+	}).Return([]timeline.TimeLine{{
+		ID:        99,
+		UserID:    uid,
+		Category:  timeline.CategoryProgress,
+		Type:      timeline.ProgressTypeSubject,
+		CreatedAt: time.Unix(1e9, 0),
+		Memo: timeline.Memo{ProgressSubject: &timeline.ProgressSubjectMemo{
+			ID: 8, Name: "name", Eps: 12, EpsUpdate: 3,
+		}},
+	}}, nil)
+
+	s := mocks.NewSubjectRepo(t)
+	s.EXPECT().GetByIDs(mock.Anything, []model.SubjectID{8}, subject.Filter{}).
+		Return(map[model.SubjectID]model.Subject{8: {ID: 8}}, nil)
+
+	app := test.GetWebApp(t, test.Mock{UserRepo: u, TimelineRepo: m, SubjectRepo: s})
+
+	var r res.TimelinePage
+	htest.New(t, app).
+		Query("type", "progress").
+		Query("until", "100").
+		Query("limit", "1").
+		Get("/v0/users/sai/timeline").
+		ExpectCode(http.StatusOK).
+		JSON(&r)
+
+	require.Len(t, r.Data, 1)
+	require.NotNil(t, r.Next)
+	require.EqualValues(t, 99, *r.Next)
+	require.Equal(t, "progress", r.Data[0].Category)
+	require.Equal(t, "sai", r.Data[0].User.Username)
+	require.NotNil(t, r.Data[0].ProgressSubject)
+	require.EqualValues(t, 8, r.Data[0].ProgressSubject.Subject.ID)
+	require.EqualValues(t, 3, r.Data[0].ProgressSubject.Collect.EpsUpdate)
+}
+
+func TestTimeline_ListUser_nsfw(t *testing.T) {
+	t.Parallel()
+
+	const uid model.UserID = 5
+
+	u := mocks.NewUserRepo(t)
+	u.EXPECT().GetByName(mock.Anything, "sai").Return(user.User{ID: uid, UserName: "sai"}, nil)
+	u.EXPECT().GetByIDs(mock.Anything, []model.UserID{uid}).
+		Return(map[model.UserID]user.User{uid: {ID: uid, UserName: "sai"}}, nil)
+
+	m := mocks.NewTimelineRepo(t)
+	m.EXPECT().List(mock.Anything, []model.UserID{uid}, mock.Anything).Return([]timeline.TimeLine{
+		{
+			ID: 3, UserID: uid, Category: timeline.CategorySubject, Batch: true,
+			Memo: timeline.Memo{Subject: []timeline.SubjectMemo{{ID: 1}, {ID: 2}}},
+		},
+		{
+			ID: 2, UserID: uid, Category: timeline.CategoryProgress,
+			Memo: timeline.Memo{ProgressSubject: &timeline.ProgressSubjectMemo{ID: 2, Name: "nsfw"}},
+		},
+		{
+			ID: 1, UserID: uid, Category: timeline.CategoryMono,
+			Memo: timeline.Memo{Mono: []timeline.MonoMemo{{ID: 1, Name: "mono", Type: timeline.MonoTypeCharacter}}},
+		},
+	}, nil)
+
+	s := mocks.NewSubjectRepo(t)
+	s.EXPECT().GetByIDs(mock.Anything, []model.SubjectID{1, 2}, subject.Filter{}).
+		Return(map[model.SubjectID]model.Subject{1: {ID: 1}, 2: {ID: 2, NSFW: true}}, nil)
+
+	app := test.GetWebApp(t, test.Mock{UserRepo: u, TimelineRepo: m, SubjectRepo: s})
+
+	var r res.TimelinePage
+	htest.New(t, app).
+		Query("limit", "3").
+		Get("/v0/users/sai/timeline").
+		ExpectCode(http.StatusOK).
+		JSON(&r)
+
+	// 未登录时不返回 nsfw 条目，下一页仍然从数据库返回的最后一条开始
+	require.Len(t, r.Data, 2)
+	require.Len(t, r.Data[0].Subject, 1)
+	require.EqualValues(t, 1, r.Data[0].Subject[0].Subject.ID)
+	require.Equal(t, "mono", r.Data[1].Category)
+	require.NotNil(t, r.Next)
+	require.EqualValues(t, 1, *r.Next)
+}
+
+func TestTimeline_ListUser_offset(t *testing.T) {
+	t.Parallel()
+
+	app := test.GetWebApp(t, test.Mock{})
+
+	htest.New(t, app).
+		Query("offset", "20").
+		Get("/v0/users/sai/timeline").
+		ExpectCode(http.StatusBadRequest)
+}
+
+func TestTimeline_ListUser_badType(t *testing.T) {
+	t.Parallel()
+
+	app := test.GetWebApp(t, test.Mock{})
+
+	htest.New(t, app).
+		Query("type", "blog").
+		Get("/v0/users/sai/timeline").
+		ExpectCode(http.StatusBadRequest)
+}
+
+func TestTimeline_List(t *testing.T) {
+	t.Parallel()
+
+	a := mocks.NewAuthService(t)
+	a.EXPECT().GetByToken(mock.Anything, mock.Anything).Return(auth.Auth{ID: 1}, nil)
+
+	u := mocks.NewUserRepo(t)
+	u.EXPECT().GetFriends(mock.Anything, model.UserID(1)).
+		Return(map[model.UserID]user.FriendItem{2: {}}, nil)
+	u.EXPECT().GetByIDs(mock.Anything, mock.Anything).Return(map[model.UserID]user.User{}, nil)
+
+	m := mocks.NewTimelineRepo(t)
+	m.EXPECT().List(mock.Anything, []model.UserID{2, 1}, mock.Anything).Return(nil, nil)
+
+	app := test.GetWebApp(t, test.Mock{AuthService: a, UserRepo: u, TimelineRepo: m})
+
+	var r res.TimelinePage
+	htest.New(t, app).
+		Header(echo.HeaderAuthorization, "Bearer t").
+		Query("scope", "friends").
+		Get("/v0/timeline").
+		ExpectCode(http.StatusOK).
+		JSON(&r)
+
+	require.Empty(t, r.Data)
+	require.Nil(t, r.Next)
+}
+
+func TestTimeline_List_needLogin(t *testing.T) {
+	t.Parallel()
+
+	app := test.GetWebApp(t, test.Mock{})
+
+	htest.New(t, app).Get("/v0/timeline").ExpectCode(http.StatusUnauthorized)
+}
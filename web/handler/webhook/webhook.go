@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v5"
+
+	"github.com/bangumi/server/domain/gerr"
+	"github.com/bangumi/server/internal/pkg/random"
+	"github.com/bangumi/server/internal/webhook"
+	"github.com/bangumi/server/web/accessor"
+	"github.com/bangumi/server/web/mw"
+	"github.com/bangumi/server/web/req"
+	"github.com/bangumi/server/web/res"
+)
+
+const secretLength = 32
+
+var errNeedAccessToken = res.Forbidden("webhooks can only be managed with an access token")
+
+func (h Webhook) Routes(g *echo.Group) {
+	g.POST("/webhooks", h.Create, req.JSON, mw.NeedLogin, needAccessToken)
+	g.GET("/webhooks", h.List, mw.NeedLogin, needAccessToken)
+	g.DELETE("/webhooks/:id", h.Delete, mw.NeedLogin, needAccessToken)
+	g.GET("/webhooks/:id/deliveries", h.ListDeliveries, mw.NeedLogin, needAccessToken)
+}
+
+// webhook 属于 access token，cookie 登录的请求不能管理 webhook.
+func needAccessToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		if accessor.GetFromCtx(c).TokenID == 0 {
+			return errNeedAccessToken
+		}
+
+		return next(c)
+	}
+}
+
+func (h Webhook) Create(c *echo.Context) error {
+	var r req.WebhookCreate
+	if err := c.Echo().JSONSerializer.Deserialize(c, &r); err != nil {
+		return res.JSONError(c, err)
+	}
+
+	if err := webhook.ValidateURL(r.URL); err != nil {
+		return res.BadRequest(err.Error())
+	}
+
+	for _, event := range r.Events {
+		if !slices.Contains(webhook.EventTypes, event) {
+			return res.BadRequest("unknown event type " + event)
+		}
+	}
+
+	a := accessor.GetFromCtx(c)
+	ctx := c.Request().Context()
+
+	webhooks, err := h.repo.ListByToken(ctx, a.TokenID)
+	if err != nil {
+		return res.InternalError(c, err, "failed to list webhooks")
+	}
+
+	if len(webhooks) >= webhook.MaxPerToken {
+		return res.BadRequest("too many webhooks for current access token")
+	}
+
+	w := webhook.Webhook{
+		CreatedAt: time.Now(),
+		ID:        uuid.NewString(),
+		URL:       r.URL,
+		Secret:    random.Base62String(secretLength),
+		Events:    slices.Compact(slices.Sorted(slices.Values(r.Events))),
+		TokenID:   a.TokenID,
+		UserID:    a.ID,
+	}
+
+	if err := h.repo.Create(ctx, w); err != nil {
+		return res.InternalError(c, err, "failed to create webhook")
+	}
+
+	data := res.ConvertWebhook(w)
+	data.Secret = w.Secret
+
+	return c.JSON(http.StatusCreated, data)
+}
+
+func (h Webhook) List(c *echo.Context) error {
+	webhooks, err := h.repo.ListByToken(c.Request().Context(), accessor.GetFromCtx(c).TokenID)
+	if err != nil {
+		return res.InternalError(c, err, "failed to list webhooks")
+	}
+
+	data := make([]res.Webhook, len(webhooks))
+	for i, w := range webhooks {
+		data[i] = res.ConvertWebhook(w)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h Webhook) Delete(c *echo.Context) error {
+	w, err := h.get(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.repo.Delete(c.Request().Context(), w); err != nil {
+		return res.InternalError(c, err, "failed to delete webhook")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h Webhook) ListDeliveries(c *echo.Context) error {
+	w, err := h.get(c)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := h.repo.ListDeliveries(c.Request().Context(), w.ID)
+	if err != nil {
+		return res.InternalError(c, err, "failed to list webhook deliveries")
+	}
+
+	data := make([]res.WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		data[i] = res.ConvertWebhookDelivery(d)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+// get 返回当前 access token 的 webhook，其他 token 的 webhook 当作不存在.
+func (h Webhook) get(c *echo.Context) (webhook.Webhook, error) {
+	w, err := h.repo.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gerr.ErrNotFound) {
+			return webhook.Webhook{}, res.NotFound("webhook not found")
+		}
+
+		return webhook.Webhook{}, res.InternalError(c, err, "failed to get webhook")
+	}
+
+	if w.TokenID != accessor.GetFromCtx(c).TokenID {
+		return webhook.Webhook{}, res.NotFound("webhook not found")
+	}
+
+	return w, nil
+}
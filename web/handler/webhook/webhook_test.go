@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package webhook_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/trim21/htest"
+
+	"github.com/bangumi/server/internal/auth"
+	"github.com/bangumi/server/internal/mocks"
+	"github.com/bangumi/server/internal/pkg/test"
+	"github.com/bangumi/server/internal/webhook"
+	"github.com/bangumi/server/web/res"
+)
+
+const tokenID uint32 = 3
+
+func authWithToken(t *testing.T, tokenID uint32) *mocks.AuthService {
+	t.Helper()
+	a := mocks.NewAuthService(t)
+	a.EXPECT().GetByToken(mock.Anything, mock.Anything).Return(auth.Auth{ID: 1, TokenID: tokenID}, nil)
+
+	return a
+}
+
+func TestWebhook_Create(t *testing.T) {
+	t.Parallel()
+
+	var created webhook.Webhook
+	m := mocks.NewWebhookRepo(t)
+	m.EXPECT().ListByToken(mock.Anything, tokenID).Return(nil, nil)
+	m.EXPECT().Create(mock.Anything, mock.Anything).Run(func(_ context.Context, w webhook.Webhook) {
+		created = w
+	}).Return(nil)
+
+	app := test.GetWebApp(t, test.Mock{AuthService: authWithToken(t, tokenID), WebhookRepo: m})
+
+	var r res.Webhook
+	htest.New(t, app).
+		Header(echo.HeaderAuthorization, "Bearer t").
+		BodyJSON(map[string]any{
+			"url":    "https://example.com/hook",
+			"events": []string{webhook.EventSubjectUpdated, webhook.EventEpisodeUpdated},
+		}).
+		Post("/v0/webhooks").
+		ExpectCode(http.StatusCreated).
+		JSON(&r)
+
+	require.Equal(t, tokenID, created.TokenID)
+	require.Equal(t, created.ID, r.ID)
+	require.Equal(t, created.Secret, r.Secret)
+	require.NotEmpty(t, r.Secret)
+	require.Equal(t, []string{webhook.EventEpisodeUpdated, webhook.EventSubjectUpdated}, r.Events)
+}
+
+func TestWebhook_Create_invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]map[string]any{
+		"scheme":  {"url": "ftp://example.com/hook"},
+		"private": {"url": "http://127.0.0.1:8080/hook"},
+		"event":   {"url": "https://example.com/hook", "events": []string{"subject.unknown"}},
+	}
+
+	for name, body := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			app := test.GetWebApp(t, test.Mock{AuthService: authWithToken(t, tokenID)})
+
+			htest.New(t, app).
+				Header(echo.HeaderAuthorization, "Bearer t").
+				BodyJSON(body).
+				Post("/v0/webhooks").
+				ExpectCode(http.StatusBadRequest)
+		})
+	}
+}
+
+func TestWebhook_List_needAccessToken(t *testing.T) {
+	t.Parallel()
+
+	app := test.GetWebApp(t, test.Mock{AuthService: authWithToken(t, 0)})
+
+	htest.New(t, app).
+		Header(echo.HeaderAuthorization, "Bearer t").
+		Get("/v0/webhooks").
+		ExpectCode(http.StatusForbidden)
+}
+
+func TestWebhook_Delete_otherToken(t *testing.T) {
+	t.Parallel()
+
+	m := mocks.NewWebhookRepo(t)
+	m.EXPECT().Get(mock.Anything, "hook").Return(webhook.Webhook{ID: "hook", TokenID: tokenID + 1}, nil)
+
+	app := test.GetWebApp(t, test.Mock{AuthService: authWithToken(t, tokenID), WebhookRepo: m})
+
+	htest.New(t, app).
+		Header(echo.HeaderAuthorization, "Bearer t").
+		Delete("/v0/webhooks/hook").
+		ExpectCode(http.StatusNotFound)
+}
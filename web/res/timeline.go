@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package res
+
+import (
+	"time"
+
+	"github.com/bangumi/server/internal/collections/domain/collection"
+	"github.com/bangumi/server/internal/model"
+	"github.com/bangumi/server/internal/timeline"
+)
+
+// TimelineCategories 是 api 支持的时间线分类.
+var TimelineCategories = map[string]timeline.Category{ //nolint:gochecknoglobals
+	"subject":  timeline.CategorySubject,
+	"progress": timeline.CategoryProgress,
+	"index":    timeline.CategoryIndex,
+	"mono":     timeline.CategoryMono,
+}
+
+type TimelinePage struct {
+	Data []Timeline `json:"data"`
+	// Next 是下一页的 `until` 参数，没有更多数据时为 null
+	Next *uint32 `json:"next"`
+}
+
+// Timeline 中 `subject`、`progress_subject` 和 `progress_episode` 的内容和 [timeline] 发送的
+// subject、progressSubject、progressEpisode 消息相同（去掉了 uid、createdAt 和 source），另外加上了名字等数据库中保存的字段.
+type Timeline struct {
+	CreatedAt       time.Time                `json:"created_at"`
+	ProgressSubject *TimelineProgressSubject `json:"progress_subject,omitempty"`
+	ProgressEpisode *TimelineProgressEpisode `json:"progress_episode,omitempty"`
+	Category        string                   `json:"category"`
+	Subject         []TimelineSubject        `json:"subject,omitempty"`
+	Index           []TimelineIndex          `json:"index,omitempty"`
+	Mono            []TimelineMono           `json:"mono,omitempty"`
+	User            User                     `json:"user"`
+	ID              uint32                   `json:"id"`
+	Replies         uint32                   `json:"replies"`
+	Type            uint16                   `json:"type"`
+	Source          uint8                    `json:"source"`
+	Batch           bool                     `json:"batch"`
+}
+
+type TimelineSubjectInfo struct {
+	Name string            `json:"name,omitempty"`
+	ID   model.SubjectID   `json:"id"`
+	Type model.SubjectType `json:"type"`
+}
+
+type TimelineSubject struct {
+	Collect TimelineSubjectCollect `json:"collect"`
+	Subject TimelineSubjectInfo    `json:"subject"`
+}
+
+type TimelineSubjectCollect struct {
+	Comment string                       `json:"comment"`
+	ID      uint64                       `json:"id"`
+	Type    collection.SubjectCollection `json:"type"`
+	Rate    uint8                        `json:"rate"`
+}
+
+type TimelineProgressSubject struct {
+	Subject TimelineProgressSubjectInfo `json:"subject"`
+	Collect TimelineProgressCollect     `json:"collect"`
+}
+
+type TimelineProgressSubjectInfo struct {
+	Name    string            `json:"name"`
+	ID      model.SubjectID   `json:"id"`
+	Eps     uint32            `json:"eps"`
+	Volumes uint32            `json:"volumes"`
+	Type    model.SubjectType `json:"type"`
+}
+
+// TimelineProgressCollect 使用和消息相同的字段名.
+type TimelineProgressCollect struct {
+	EpsUpdate  uint32 `json:"epsUpdate"`  //nolint:tagliatelle
+	VolsUpdate uint32 `json:"volsUpdate"` //nolint:tagliatelle
+}
+
+type TimelineProgressEpisode struct {
+	Subject TimelineSubjectInfo         `json:"subject"`
+	Episode TimelineProgressEpisodeInfo `json:"episode"`
+}
+
+type TimelineProgressEpisodeInfo struct {
+	Name   string                       `json:"name"`
+	Sort   float32                      `json:"sort"`
+	ID     model.EpisodeID              `json:"id"`
+	Status collection.EpisodeCollection `json:"status"`
+}
+
+type TimelineIndex struct {
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	ID          model.IndexID `json:"id"`
+}
+
+type TimelineMono struct {
+	Name string `json:"name"`
+	// Type 是 `character` 或者 `person`
+	Type string `json:"type"`
+	ID   uint32 `json:"id"`
+}
+
+func timelineCategoryString(c timeline.Category) string {
+	for s, v := range TimelineCategories {
+		if v == c {
+			return s
+		}
+	}
+
+	return ""
+}
+
+func ConvertTimeline(t timeline.TimeLine, u User) Timeline {
+	r := Timeline{
+		ID:        t.ID,
+		User:      u,
+		Category:  timelineCategoryString(t.Category),
+		Type:      t.Type,
+		Batch:     t.Batch,
+		Source:    t.Source,
+		Replies:   t.Replies,
+		CreatedAt: t.CreatedAt,
+	}
+
+	for _, s := range t.Memo.Subject {
+		r.Subject = append(r.Subject, TimelineSubject{
+			Subject: TimelineSubjectInfo{ID: s.ID, Type: s.Type},
+			Collect: TimelineSubjectCollect{ID: s.CollectID, Type: s.Collect, Rate: s.Rate, Comment: s.Comment},
+		})
+	}
+
+	if p := t.Memo.ProgressSubject; p != nil {
+		r.ProgressSubject = &TimelineProgressSubject{
+			Subject: TimelineProgressSubjectInfo{ID: p.ID, Type: p.Type, Name: p.Name, Eps: p.Eps, Volumes: p.Volumes},
+			Collect: TimelineProgressCollect{EpsUpdate: p.EpsUpdate, VolsUpdate: p.VolsUpdate},
+		}
+	}
+
+	if p := t.Memo.ProgressEpisode; p != nil {
+		r.ProgressEpisode = &TimelineProgressEpisode{
+			Subject: TimelineSubjectInfo{ID: p.SubjectID, Type: p.SubjectType, Name: p.SubjectName},
+			Episode: TimelineProgressEpisodeInfo{ID: p.EpisodeID, Name: p.EpisodeName, Sort: p.Sort, Status: p.Status},
+		}
+	}
+
+	for _, i := range t.Memo.Index {
+		r.Index = append(r.Index, TimelineIndex{ID: i.ID, Title: i.Title, Description: i.Description})
+	}
+
+	for _, m := range t.Memo.Mono {
+		mono := TimelineMono{ID: m.ID, Name: m.Name}
+		switch m.Type {
+		case timeline.MonoTypeCharacter:
+			mono.Type = "character"
+		case timeline.MonoTypePerson:
+			mono.Type = "person"
+		}
+		r.Mono = append(r.Mono, mono)
+	}
+
+	return r
+}
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package res_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bangumi/server/internal/timeline"
+	"github.com/bangumi/server/web/res"
+)
+
+// 和时间线消息 progressSubject 的结构相同.
+func TestConvertTimeline_progressSubject(t *testing.T) {
+	t.Parallel()
+
+	r := res.ConvertTimeline(timeline.TimeLine{
+		Category: timeline.CategoryProgress,
+		Memo: timeline.Memo{ProgressSubject: &timeline.ProgressSubjectMemo{
+			ID: 8, Type: 2, Eps: 12, Volumes: 0, EpsUpdate: 3, VolsUpdate: 0,
+		}},
+	}, res.User{})
+
+	raw, err := json.Marshal(r.ProgressSubject)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"subject": {"id": 8, "type": 2, "name": "", "eps": 12, "volumes": 0},
+		"collect": {"epsUpdate": 3, "volsUpdate": 0}
+	}`, string(raw))
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: AGPL-3.0-only
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+// See the GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>
+
+package res
+
+import (
+	"time"
+
+	"github.com/bangumi/server/internal/webhook"
+)
+
+type Webhook struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	// 只在创建时返回
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+}
+
+func ConvertWebhook(w webhook.Webhook) Webhook {
+	events := w.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	return Webhook{
+		CreatedAt: w.CreatedAt,
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    events,
+	}
+}
+
+type WebhookDelivery struct {
+	CreatedAt  time.Time `json:"created_at"`
+	EventID    string    `json:"event_id"`
+	Event      string    `json:"event"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Success    bool      `json:"success"`
+}
+
+func ConvertWebhookDelivery(d webhook.Delivery) WebhookDelivery {
+	return WebhookDelivery{
+		CreatedAt:  d.CreatedAt,
+		EventID:    d.EventID,
+		Event:      d.Event,
+		Error:      d.Error,
+		DurationMS: d.Duration.Milliseconds(),
+		Attempt:    d.Attempt,
+		StatusCode: d.StatusCode,
+		Success:    d.Success,
+	}
+}
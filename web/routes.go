@@ -24,7 +24,9 @@ import (
 	"github.com/bangumi/server/web/handler/index"
 	"github.com/bangumi/server/web/handler/person"
 	"github.com/bangumi/server/web/handler/subject"
+	"github.com/bangumi/server/web/handler/timeline"
 	"github.com/bangumi/server/web/handler/user"
+	"github.com/bangumi/server/web/handler/webhook"
 	"github.com/bangumi/server/web/mw"
 	"github.com/bangumi/server/web/mw/ua"
 	"github.com/bangumi/server/web/req"
@@ -43,6 +45,8 @@ func AddRouters(
 	characterHandler character.Character,
 	subjectHandler subject.Subject,
 	indexHandler index.Handler,
+	webhookHandler webhook.Webhook,
+	timelineHandler timeline.Timeline,
 ) {
 	app.GET("/", indexPage())
 
@@ -55,6 +59,8 @@ func AddRouters(
 	v0.POST("/search/subjects", h.SearchSubjects)
 	v0.POST("/search/characters", h.SearchCharacters)
 	v0.POST("/search/persons", h.SearchPersons)
+	v0.POST("/search/indices", h.SearchIndices)
+	v0.GET("/search/suggest", h.SearchSuggest)
 
 	subjectHandler.Routes(v0)
 
@@ -129,6 +135,10 @@ func AddRouters(
 
 	v0.GET("/revisions/episodes/:id", h.GetEpisodeRevision)
 	v0.GET("/revisions/episodes", h.ListEpisodeRevision)
+
+	webhookHandler.Routes(v0)
+	timelineHandler.Routes(v0)
+
 	v0.Any("/*", globalNotFoundHandler)
 
 	// default 404 Handler, all router should be added before this router